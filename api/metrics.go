@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+
+	"balance/internal/metrics"
+	"balance/internal/models"
+	"balance/internal/repository"
+)
+
+// debtStatuses - статусы долгов в метриках. Статусы без долгов отдаются с нулем
+var debtStatuses = []string{
+	models.DebtStatusProposed, models.DebtStatusActive, models.DebtStatusDisputed,
+	models.DebtStatusSettled, models.DebtStatusCancelled,
+}
+
+// businessMetrics отдает показатели приложения: пользователей, группы, долги и
+// очереди. Считаются запросом к базе при каждом сборе метрик
+func businessMetrics(statsRepo *repository.StatsRepository) metrics.Collector {
+	return metrics.CollectorFunc(func(ctx context.Context, e *metrics.Encoder) error {
+		stats, err := statsRepo.Get(ctx)
+		if err != nil {
+			return err
+		}
+
+		e.Gauge("balance_users", "Registered users, without guests.", float64(stats.Users))
+		e.Gauge("balance_guests", "Guest users.", float64(stats.Guests))
+		e.Gauge("balance_groups", "Groups.", float64(stats.Groups))
+
+		e.Family("balance_debts", "Debts by status.", "gauge")
+		for _, status := range debtStatuses {
+			e.Sample("balance_debts", float64(stats.Debts[status]), "status", status)
+		}
+		e.Family("balance_debts_amount", "Total amount of debts by status.", "gauge")
+		for _, status := range debtStatuses {
+			e.Sample("balance_debts_amount", stats.DebtAmounts[status], "status", status)
+		}
+		e.Gauge("balance_debts_outstanding_amount", "Total amount of active and disputed debts.",
+			stats.DebtAmounts[models.DebtStatusActive]+stats.DebtAmounts[models.DebtStatusDisputed])
+
+		e.Gauge("balance_recurring_series_active", "Active recurring debt series.", float64(stats.ActiveSeries))
+		e.Gauge("balance_notifications_pending", "Notifications waiting to be sent.", float64(stats.PendingNotifications))
+		e.Gauge("balance_webhook_deliveries_pending", "Webhook deliveries waiting to be sent.", float64(stats.PendingDeliveries))
+		return nil
+	})
+}
@@ -0,0 +1,296 @@
+package api
+
+import (
+	"net/http"
+
+	"balance/internal/auth"
+	"balance/internal/models"
+	"balance/internal/openapi"
+	"balance/internal/service"
+)
+
+// apiInfo - заголовок документа OpenAPI
+var apiInfo = openapi.Info{
+	Title:   "Balance API",
+	Version: "1.0.0",
+	Description: "Учет долгов между пользователями и в группах. " +
+		"Ответы JSON имеют вид {\"status\": \"success\", \"data\": ...} или {\"status\": \"error\", \"error\": \"...\"}.",
+}
+
+// message - тело ответов, которые возвращают только текст
+type message map[string]string
+
+// adminSecurity - маршруты /admin требуют ADMIN_TOKEN вместо сессии
+var adminSecurity = []openapi.SecurityRequirement{{"adminToken": {}}}
+
+// userSecurity - маршруты, которым обязательно нужна сессия или API ключ
+var userSecurity = []openapi.SecurityRequirement{{"bearerAuth": {}}}
+
+// apiSpec описывает маршруты /api/v1. Новый маршрут в NewRouter нужно описать здесь,
+// иначе при запуске сервер предупредит о расхождении
+func apiSpec() *openapi.Document {
+	var (
+		groupID    = openapi.Query("group_id", "integer", "Только для группы")
+		userID     = openapi.Query("user_id", "integer", "Только для пользователя")
+		debtStatus = openapi.Query("status", "string", "Статус долга",
+			"proposed", "active", "settled", "cancelled", "disputed")
+		limit  = openapi.Query("limit", "integer", "Размер страницы, от 1 до 1000. Без limit возвращается весь список")
+		offset = openapi.Query("offset", "integer", "Сколько записей пропустить")
+	)
+
+	routes := []openapi.Route{
+		// Пользователи
+		{Method: "GET", Path: "/users", ID: "getUsers", Tag: "Users", Summary: "Получить всех пользователей",
+			Query: []openapi.Parameter{limit, offset}, Data: []*models.User{}},
+		{Method: "POST", Path: "/users", ID: "createUser", Tag: "Users", Summary: "Создать пользователя",
+			Body: models.CreateUserRequest{}, Status: http.StatusCreated, Data: models.User{}},
+		{Method: "GET", Path: "/users/", ID: "getUser", Tag: "Users", Summary: "Получить пользователя по ID",
+			Query: []openapi.Parameter{openapi.RequiredQuery("id", "integer", "ID пользователя")}, Data: models.User{}},
+		{Method: "PUT", Path: "/users/", ID: "updateUser", Tag: "Users", Summary: "Обновить пользователя",
+			Query: []openapi.Parameter{openapi.RequiredQuery("id", "integer", "ID пользователя")},
+			Body:  models.UpdateUserRequest{}, Data: models.User{}},
+		{Method: "DELETE", Path: "/users/", ID: "deleteUser", Tag: "Users", Summary: "Удалить пользователя",
+			Query: []openapi.Parameter{openapi.RequiredQuery("id", "integer", "ID пользователя")}, Data: message{}},
+		{Method: "POST", Path: "/users/{id}/verify-email", ID: "sendVerification", Tag: "Users",
+			Summary: "Повторно отправить письмо с подтверждением email", Data: message{}},
+		{Method: "GET", Path: "/users/{id}/debts/overdue", ID: "getOverdueDebts", Tag: "Debts",
+			Summary: "Просроченные долги пользователя", Data: []*models.Debt{}},
+		{Method: "GET", Path: "/users/{id}/balances", ID: "getBalances", Tag: "Balances",
+			Summary: "Балансы пользователя со всеми, с кем у него есть активные долги", Data: []*models.UserBalance{}},
+		{Method: "GET", Path: "/users/{id}/balances/{otherId}", ID: "getBalance", Tag: "Balances",
+			Summary: "Баланс пользователя с другим пользователем", Data: models.UserBalance{}},
+		{Method: "GET", Path: "/users/{id}/friends", ID: "getFriends", Tag: "Friends",
+			Summary: "Друзья пользователя и запросы дружбы",
+			Query:   []openapi.Parameter{openapi.Query("status", "string", "Статус дружбы", "pending", "accepted")},
+			Data:    []*models.Friendship{}},
+		{Method: "POST", Path: "/users/{id}/friends", ID: "addFriend", Tag: "Friends",
+			Summary: "Отправить запрос дружбы или принять встречный",
+			Body:    models.AddFriendRequest{}, Status: http.StatusCreated, Data: models.Friendship{}},
+		{Method: "POST", Path: "/users/{id}/friends/{friendId}/accept", ID: "acceptFriend", Tag: "Friends",
+			Summary: "Принять запрос дружбы", Data: models.Friendship{}},
+		{Method: "DELETE", Path: "/users/{id}/friends/{friendId}", ID: "removeFriend", Tag: "Friends",
+			Summary: "Удалить друга, отклонить или отозвать запрос", Data: message{}},
+		{Method: "POST", Path: "/users/{id}/claims", ID: "createGuestClaim", Tag: "Guests",
+			Summary: "Пригласить человека забрать гостя",
+			Body:    models.CreateGuestClaimRequest{}, Status: http.StatusCreated, Data: models.GuestClaim{}},
+		{Method: "GET", Path: "/users/{id}/notification-preferences", ID: "getNotificationPreferences",
+			Tag: "Notifications", Summary: "Настройки уведомлений", Data: []models.NotificationPreference{}},
+		{Method: "PUT", Path: "/users/{id}/notification-preferences", ID: "updateNotificationPreferences",
+			Tag: "Notifications", Summary: "Изменить настройки уведомлений",
+			Body: models.UpdateNotificationPreferencesRequest{}, Data: []models.NotificationPreference{}},
+
+		// Вход и сессии
+		{Method: "POST", Path: "/auth/login", ID: "login", Tag: "Auth", Summary: "Войти по email и паролю",
+			Body: models.LoginRequest{}, Data: models.LoginResult{}},
+		{Method: "POST", Path: "/auth/login/2fa", ID: "completeTwoFactorLogin", Tag: "Auth",
+			Summary: "Завершить вход кодом второго фактора",
+			Body:    models.TwoFactorLoginRequest{}, Data: models.LoginResult{}},
+		{Method: "POST", Path: "/auth/oidc/login", ID: "startOIDCLogin", Tag: "Auth",
+			Summary: "Начать вход через провайдера OpenID Connect", Data: models.OIDCLogin{}},
+		{Method: "POST", Path: "/auth/oidc/callback", ID: "finishOIDCLogin", Tag: "Auth",
+			Summary: "Завершить вход через провайдера", Body: models.OIDCCallbackRequest{}, Data: models.LoginResult{}},
+		{Method: "GET", Path: "/auth/me", ID: "getMe", Tag: "Auth", Summary: "Пользователь текущей сессии",
+			Data: models.User{}},
+		{Method: "POST", Path: "/auth/logout", ID: "logout", Tag: "Auth", Summary: "Завершить текущую сессию",
+			Data: message{}},
+
+		// Двухфакторная аутентификация
+		{Method: "GET", Path: "/auth/2fa", ID: "getTwoFactorStatus", Tag: "Two-factor",
+			Summary: "Включена ли 2FA и сколько осталось кодов восстановления", Data: models.TwoFactorStatus{}},
+		{Method: "POST", Path: "/auth/2fa/enroll", ID: "enrollTwoFactor", Tag: "Two-factor",
+			Summary: "Выпустить секрет TOTP", Data: models.TwoFactorEnrollment{}},
+		{Method: "POST", Path: "/auth/2fa/confirm", ID: "confirmTwoFactor", Tag: "Two-factor",
+			Summary: "Включить 2FA кодом из приложения",
+			Body:    models.TwoFactorCodeRequest{}, Data: models.TwoFactorRecoveryCodes{}},
+		{Method: "POST", Path: "/auth/2fa/disable", ID: "disableTwoFactor", Tag: "Two-factor",
+			Summary: "Выключить 2FA", Body: models.DisableTwoFactorRequest{}, Data: message{}},
+		{Method: "POST", Path: "/auth/2fa/recovery-codes", ID: "regenerateRecoveryCodes", Tag: "Two-factor",
+			Summary: "Заменить коды восстановления",
+			Body:    models.TwoFactorCodeRequest{}, Data: models.TwoFactorRecoveryCodes{}},
+
+		// Персональные API ключи
+		{Method: "GET", Path: "/auth/api-keys", ID: "getAPIKeys", Tag: "API keys",
+			Summary: "Ключи текущего пользователя", Data: []*models.APIKey{}},
+		{Method: "POST", Path: "/auth/api-keys", ID: "createAPIKey", Tag: "API keys",
+			Summary: "Выпустить ключ. Ключ возвращается только в этом ответе",
+			Body:    models.CreateAPIKeyRequest{}, Status: http.StatusCreated, Data: models.APIKey{}},
+		{Method: "DELETE", Path: "/auth/api-keys/{id}", ID: "revokeAPIKey", Tag: "API keys",
+			Summary: "Отозвать ключ", Data: models.APIKey{}},
+
+		// Подтверждение email и сброс пароля
+		{Method: "POST", Path: "/auth/verify-email", ID: "verifyEmail", Tag: "Auth", Summary: "Подтвердить email",
+			Body: models.TokenRequest{}, Data: models.User{}},
+		{Method: "POST", Path: "/auth/password-reset", ID: "requestPasswordReset", Tag: "Auth",
+			Summary: "Запросить сброс пароля", Body: models.PasswordResetRequest{}, Data: message{}},
+		{Method: "POST", Path: "/auth/password-reset/confirm", ID: "confirmPasswordReset", Tag: "Auth",
+			Summary: "Задать новый пароль по ссылке из письма", Body: models.PasswordResetConfirmRequest{}, Data: message{}},
+
+		// Группы
+		{Method: "GET", Path: "/groups", ID: "getGroups", Tag: "Groups", Summary: "Получить группы",
+			Query: []openapi.Parameter{openapi.Query("user_id", "integer", "Только группы пользователя"), limit, offset},
+			Data:  []*models.Group{}},
+		{Method: "POST", Path: "/groups", ID: "createGroup", Tag: "Groups", Summary: "Создать группу",
+			Body: models.CreateGroupRequest{}, Status: http.StatusCreated, Data: models.Group{}},
+		{Method: "GET", Path: "/groups/{id}", ID: "getGroup", Tag: "Groups", Summary: "Получить группу с участниками",
+			Data: models.Group{}},
+		{Method: "POST", Path: "/groups/{id}/members", ID: "addGroupMember", Tag: "Groups",
+			Summary: "Добавить участника", Body: models.AddMemberRequest{}, Status: http.StatusCreated,
+			Data: models.GroupMember{}},
+		{Method: "DELETE", Path: "/groups/{id}/members/{userId}", ID: "removeGroupMember", Tag: "Groups",
+			Summary: "Удалить участника", Data: message{}},
+		{Method: "POST", Path: "/groups/{id}/guests", ID: "addGuest", Tag: "Guests",
+			Summary: "Добавить в группу гостя без аккаунта",
+			Body:    models.CreateGuestRequest{}, Status: http.StatusCreated, Data: models.User{}},
+		{Method: "GET", Path: "/groups/{id}/invites", ID: "getInvites", Tag: "Invites",
+			Summary: "Приглашения в группу", Data: []*models.GroupInvite{}},
+		{Method: "POST", Path: "/groups/{id}/invites", ID: "createInvite", Tag: "Invites",
+			Summary: "Пригласить в группу", Body: models.CreateInviteRequest{}, Status: http.StatusCreated,
+			Data: models.GroupInvite{}},
+		{Method: "POST", Path: "/groups/{id}/invites/{inviteId}/revoke", ID: "revokeInvite", Tag: "Invites",
+			Summary: "Отозвать приглашение", Body: models.RevokeInviteRequest{}, Data: models.GroupInvite{}},
+		{Method: "POST", Path: "/invites/accept", ID: "acceptInvite", Tag: "Invites",
+			Summary: "Принять приглашение", Body: models.InviteTokenRequest{}, Status: http.StatusCreated,
+			Data: models.GroupMember{}},
+		{Method: "POST", Path: "/invites/decline", ID: "declineInvite", Tag: "Invites",
+			Summary: "Отклонить приглашение", Body: models.InviteTokenRequest{}, Data: models.GroupInvite{}},
+		{Method: "GET", Path: "/groups/{id}/export.csv", ID: "exportGroup", Tag: "Import and export",
+			Summary: "Выгрузить долги группы в CSV", ContentTypes: []string{"text/csv"}},
+		{Method: "POST", Path: "/groups/{id}/import", ID: "importGroup", Tag: "Import and export",
+			Summary: "Импортировать долги группы из CSV. Если в файле есть ошибки, ответ 422 с отчетом по строкам",
+			Query: []openapi.Parameter{
+				openapi.Query("user_id", "integer", "Кто импортирует, по умолчанию пользователь запроса"),
+				openapi.Query("dry_run", "boolean", "Только проверить файл"),
+			},
+			BodyType: "text/csv", Data: models.ImportResult{}},
+		{Method: "GET", Path: "/groups/{id}/statement", ID: "getStatement", Tag: "Groups",
+			Summary: "Выписка по группе",
+			Query: []openapi.Parameter{
+				openapi.Query("format", "string", "Формат выписки", "html", "pdf"),
+				openapi.Query("user_id", "integer", "Только долги пользователя"),
+				openapi.Query("from", "string", "Начало периода: дата или дата и время RFC 3339"),
+				openapi.Query("to", "string", "Конец периода: дата (включительно) или дата и время RFC 3339"),
+			},
+			ContentTypes: []string{"text/html", "application/pdf"}},
+		{Method: "POST", Path: "/import/splitwise", ID: "importSplitwise", Tag: "Import and export",
+			Summary: "Создать группу из экспорта Splitwise. Если в данных есть ошибки, ответ 422 с отчетом",
+			Body:    models.SplitwiseImportRequest{}, Status: http.StatusCreated, Data: models.SplitwiseImportResult{}},
+		{Method: "POST", Path: "/guests/claim", ID: "claimGuest", Tag: "Guests",
+			Summary: "Забрать гостя в свой аккаунт", Body: models.ClaimGuestRequest{}, Data: models.GuestClaimResult{}},
+
+		// Долги
+		{Method: "GET", Path: "/debts", ID: "getDebts", Tag: "Debts", Summary: "Получить долги",
+			Query: []openapi.Parameter{debtStatus, groupID, userID, limit, offset}, Data: []*models.Debt{}},
+		{Method: "POST", Path: "/debts", ID: "createDebt", Tag: "Debts", Summary: "Создать долг",
+			Body: models.CreateDebtRequest{}, Status: http.StatusCreated, Data: models.Debt{}},
+		{Method: "GET", Path: "/debts/{id}", ID: "getDebt", Tag: "Debts", Summary: "Получить долг",
+			Data: models.Debt{}},
+	}
+
+	for _, action := range []string{
+		service.DebtActionConfirm,
+		service.DebtActionReject,
+		service.DebtActionDispute,
+		service.DebtActionSettle,
+		service.DebtActionCancel,
+	} {
+		routes = append(routes, openapi.Route{
+			Method: "POST", Path: "/debts/{id}/" + action, ID: action + "Debt", Tag: "Debts",
+			Summary: "Перевести долг в другой статус: " + action,
+			Body:    models.DebtActionRequest{}, Data: models.Debt{},
+		})
+	}
+
+	routes = append(routes,
+		// Повторяющиеся долги и расходы
+		openapi.Route{Method: "GET", Path: "/recurring", ID: "getRecurringSeries", Tag: "Recurring",
+			Summary: "Получить повторяющиеся долги",
+			Query:   []openapi.Parameter{groupID, userID}, Data: []*models.RecurringSeries{}},
+		openapi.Route{Method: "POST", Path: "/recurring", ID: "createRecurringSeries", Tag: "Recurring",
+			Summary: "Создать повторяющийся долг или расход",
+			Body:    models.CreateRecurringSeriesRequest{}, Status: http.StatusCreated, Data: models.RecurringSeries{}},
+		openapi.Route{Method: "GET", Path: "/recurring/{id}", ID: "getRecurringSeriesByID", Tag: "Recurring",
+			Summary: "Получить повторяющийся долг", Data: models.RecurringSeries{}},
+		openapi.Route{Method: "PUT", Path: "/recurring/{id}", ID: "updateRecurringSeries", Tag: "Recurring",
+			Summary: "Изменить повторяющийся долг",
+			Body:    models.UpdateRecurringSeriesRequest{}, Data: models.RecurringSeries{}},
+	)
+	for _, action := range []string{"pause", "resume", "end"} {
+		routes = append(routes, openapi.Route{
+			Method: "POST", Path: "/recurring/{id}/" + action, ID: action + "RecurringSeries", Tag: "Recurring",
+			Summary: "Изменить статус повторяющегося долга: " + action,
+			Body:    models.SeriesActionRequest{}, Data: models.RecurringSeries{},
+		})
+	}
+
+	routes = append(routes,
+		// Вебхуки
+		openapi.Route{Method: "GET", Path: "/webhooks", ID: "getWebhooks", Tag: "Webhooks",
+			Summary: "Получить вебхуки группы или пользователя",
+			Query:   []openapi.Parameter{groupID, userID}, Data: []*models.Webhook{}},
+		openapi.Route{Method: "POST", Path: "/webhooks", ID: "createWebhook", Tag: "Webhooks",
+			Summary: "Создать вебхук", Body: models.CreateWebhookRequest{}, Status: http.StatusCreated,
+			Data: models.Webhook{}},
+		openapi.Route{Method: "GET", Path: "/webhooks/{id}", ID: "getWebhook", Tag: "Webhooks",
+			Summary: "Получить вебхук", Data: models.Webhook{}},
+		openapi.Route{Method: "DELETE", Path: "/webhooks/{id}", ID: "deleteWebhook", Tag: "Webhooks",
+			Summary: "Удалить вебхук", Data: message{}},
+		openapi.Route{Method: "GET", Path: "/webhooks/{id}/deliveries", ID: "getWebhookDeliveries", Tag: "Webhooks",
+			Summary: "Журнал доставок вебхука", Data: []*models.WebhookDelivery{}},
+		openapi.Route{Method: "POST", Path: "/webhooks/{id}/deliveries/{deliveryId}/redeliver", ID: "redeliverWebhook",
+			Tag: "Webhooks", Summary: "Повторить доставку", Status: http.StatusCreated, Data: models.WebhookDelivery{}},
+
+		// Администрирование
+		openapi.Route{Method: "POST", Path: "/admin/users/{id}/merge", ID: "mergeUsers", Tag: "Admin",
+			Summary: "Слить пользователя source_user_id в пользователя id",
+			Body:    models.MergeUsersRequest{}, Data: models.UserMerge{}, Security: adminSecurity},
+		openapi.Route{Method: "GET", Path: "/admin/merges", ID: "getMerges", Tag: "Admin",
+			Summary: "Журнал слияний пользователей",
+			Query:   []openapi.Parameter{openapi.Query("user_id", "integer", "Только слияния с участием пользователя")},
+			Data:    []*models.UserMerge{}, Security: adminSecurity},
+	)
+
+	// Без входа доступны только маршруты, для которых auth.RequiresUser это разрешает
+	for i, rt := range routes {
+		if rt.Security == nil && auth.RequiresUser(rt.Method, rt.Path) {
+			routes[i].Security = userSecurity
+		}
+	}
+
+	doc := openapi.Build(apiInfo, "/api/v1", routes)
+	doc.Components.SecuritySchemes = map[string]*openapi.SecurityScheme{
+		"bearerAuth": {
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "Токен сессии из /auth/login или персональный API ключ bal_...",
+		},
+		"adminToken": {
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "ADMIN_TOKEN сервера",
+		},
+	}
+	// Чтение и часть маршрутов /auth доступны без сессии
+	doc.Security = []openapi.SecurityRequirement{{"bearerAuth": {}}, {}}
+
+	return doc
+}
+
+// routeMux - ServeMux, который запоминает шаблоны маршрутов, чтобы сверить их с apiSpec
+type routeMux struct {
+	*http.ServeMux
+	patterns []string
+}
+
+func newRouteMux() *routeMux {
+	return &routeMux{ServeMux: http.NewServeMux()}
+}
+
+func (m *routeMux) Handle(pattern string, handler http.Handler) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.Handle(pattern, handler)
+}
+
+func (m *routeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.HandleFunc(pattern, handler)
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"balance/internal/config"
+	"balance/internal/health"
+	"balance/internal/logging"
+	"balance/internal/metrics"
+)
+
+// Описание API должно совпадать с маршрутами, зарегистрированными в NewRouter
+func TestAPISpecMatchesRoutes(t *testing.T) {
+	_, patterns := newRouter(nil, config.New(), logging.New(io.Discard, "text", "error"),
+		metrics.NewRegistry(), health.New(time.Second))
+	if len(patterns) == 0 {
+		t.Fatal("no routes registered")
+	}
+
+	missing, extra := apiSpec().Diff("/api/v1", patterns)
+	for _, pattern := range missing {
+		t.Errorf("route %q is missing from the OpenAPI spec", pattern)
+	}
+	for _, operation := range extra {
+		t.Errorf("OpenAPI spec describes unregistered route %q", operation)
+	}
+}
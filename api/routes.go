@@ -1,30 +1,138 @@
 package api
 
 import (
+	"crypto/subtle"
+	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"balance/internal/auth"
+	"balance/internal/config"
+	"balance/internal/cors"
 	"balance/internal/handlers"
+	"balance/internal/health"
+	"balance/internal/logging"
+	"balance/internal/metrics"
+	"balance/internal/models"
+	"balance/internal/notification"
+	"balance/internal/oidc"
+	"balance/internal/openapi"
+	"balance/internal/ratelimit"
 	"balance/internal/repository"
 	"balance/internal/service"
+	"balance/internal/tracing"
+	"balance/internal/webhook"
+	"balance/pkg/utils"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func NewRouter(db *pgx.Conn) http.Handler {
+func NewRouter(
+	db *pgxpool.Pool,
+	cfg *config.Config,
+	logger *slog.Logger,
+	registry *metrics.Registry,
+	checker *health.Checker,
+) http.Handler {
+	handler, _ := newRouter(db, cfg, logger, registry, checker)
+	return handler
+}
+
+// newRouter собирает обработчик и возвращает шаблоны маршрутов /api/v1 без префикса.
+// По ним тесты сверяют описание OpenAPI с маршрутами
+func newRouter(
+	db *pgxpool.Pool,
+	cfg *config.Config,
+	logger *slog.Logger,
+	registry *metrics.Registry,
+	checker *health.Checker,
+) (http.Handler, []string) {
 	// Создаем репозитории
 	userRepo := repository.NewUserRepository(db)
+	debtRepo := repository.NewDebtRepository(db)
+	recurringRepo := repository.NewRecurringRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	friendshipRepo := repository.NewFriendshipRepository(db)
+	guestRepo := repository.NewGuestRepository(db)
+	mergeRepo := repository.NewMergeRepository(db)
+	inviteRepo := repository.NewInviteRepository(db)
+	userTokenRepo := repository.NewUserTokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
 
 	// Создаем сервисы
-	userService := service.NewUserService(userRepo)
+	var oidcProvider *oidc.Provider
+	if cfg.OIDC.Issuer != "" {
+		oidcProvider = oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+		}, nil)
+	}
+	twoFactorService := service.NewTwoFactorService(twoFactorRepo, userRepo, cfg.Auth.TOTPIssuer)
+	authService := service.NewAuthService(
+		userRepo, sessionRepo, identityRepo, twoFactorRepo, twoFactorService,
+		oidcProvider, cfg.Auth.SessionTTL,
+	)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo)
+	mailer := notification.NewMailer(cfg.SMTP)
+	userService := service.NewUserService(
+		userRepo, userTokenRepo, authService,
+		mailer, cfg.Server.PublicURL, cfg.Auth.VerifyTTL, cfg.Auth.ResetTTL,
+	)
+	debtService := service.NewDebtService(debtRepo, friendshipRepo, groupRepo, userRepo)
+	recurringService := service.NewRecurringService(recurringRepo, friendshipRepo)
+	groupService := service.NewGroupService(groupRepo)
+	notificationService := service.NewNotificationService(
+		outboxRepo, notificationRepo, userRepo,
+		cfg.Notifications.MaxAttempts, notification.NewNotifiers(cfg.SMTP)...,
+	)
+	statementService := service.NewStatementService(debtRepo, groupRepo)
+	balanceService := service.NewBalanceService(debtRepo, userRepo)
+	friendshipService := service.NewFriendshipService(friendshipRepo, userRepo)
+	guestService := service.NewGuestService(
+		guestRepo, groupRepo, userRepo,
+		mailer, cfg.Server.PublicURL, cfg.Guests.ClaimTTL,
+	)
+	inviteService := service.NewInviteService(
+		inviteRepo, groupRepo, userRepo,
+		mailer, cfg.Server.PublicURL, cfg.Invites.TTL,
+	)
+	ledgerService := service.NewLedgerService(debtRepo, groupRepo, userRepo, inviteService)
+	webhookService := service.NewWebhookService(webhookRepo, groupRepo, webhook.NewSender(nil), cfg.Webhooks.MaxAttempts)
 
 	// Создаем обработчики
 	userHandler := handlers.NewUserHandler(userService)
+	debtHandler := handlers.NewDebtHandler(debtService)
+	recurringHandler := handlers.NewRecurringHandler(recurringService)
+	groupHandler := handlers.NewGroupHandler(groupService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+	statementHandler := handlers.NewStatementHandler(statementService)
+	balanceHandler := handlers.NewBalanceHandler(balanceService)
+	friendshipHandler := handlers.NewFriendshipHandler(friendshipService)
+	guestHandler := handlers.NewGuestHandler(guestService)
+	inviteHandler := handlers.NewInviteHandler(inviteService)
+	adminHandler := handlers.NewAdminHandler(service.NewMergeService(mergeRepo))
+	authHandler := handlers.NewAuthHandler(authService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
 
 	// Создаем мультиплексор
 	mux := http.NewServeMux()
 
 	// API v1 маршруты
-	apiV1 := http.NewServeMux()
+	apiV1 := newRouteMux()
 
 	// Пользователи
 	apiV1.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
@@ -51,8 +159,144 @@ func NewRouter(db *pgx.Conn) http.Handler {
 		}
 	})
 
-	// Добавляем CORS middleware
-	mux.Handle("/api/v1/", corsMiddleware(apiV1))
+	apiV1.HandleFunc("POST /users/{id}/verify-email", userHandler.SendVerification)
+	apiV1.HandleFunc("GET /users/{id}/debts/overdue", debtHandler.GetOverdueDebts)
+	apiV1.HandleFunc("GET /users/{id}/balances", balanceHandler.GetBalances)
+	apiV1.HandleFunc("GET /users/{id}/balances/{otherId}", balanceHandler.GetBalance)
+	apiV1.HandleFunc("GET /users/{id}/friends", friendshipHandler.GetFriends)
+	apiV1.HandleFunc("POST /users/{id}/friends", friendshipHandler.AddFriend)
+	apiV1.HandleFunc("POST /users/{id}/friends/{friendId}/accept", friendshipHandler.AcceptFriend)
+	apiV1.HandleFunc("DELETE /users/{id}/friends/{friendId}", friendshipHandler.RemoveFriend)
+	apiV1.HandleFunc("POST /users/{id}/claims", guestHandler.CreateClaim)
+	apiV1.HandleFunc("GET /users/{id}/notification-preferences", notificationHandler.GetPreferences)
+	apiV1.HandleFunc("PUT /users/{id}/notification-preferences", notificationHandler.UpdatePreferences)
+
+	// Вход и сессии
+	apiV1.HandleFunc("POST /auth/login", userHandler.Login)
+	apiV1.HandleFunc("POST /auth/login/2fa", authHandler.CompleteTwoFactorLogin)
+	apiV1.HandleFunc("POST /auth/oidc/login", authHandler.StartOIDCLogin)
+	apiV1.HandleFunc("POST /auth/oidc/callback", authHandler.FinishOIDCLogin)
+	apiV1.HandleFunc("GET /auth/me", authHandler.Me)
+	apiV1.HandleFunc("POST /auth/logout", authHandler.Logout)
+
+	// Двухфакторная аутентификация пользователя текущей сессии
+	apiV1.HandleFunc("GET /auth/2fa", twoFactorHandler.GetStatus)
+	apiV1.HandleFunc("POST /auth/2fa/enroll", twoFactorHandler.Enroll)
+	apiV1.HandleFunc("POST /auth/2fa/confirm", twoFactorHandler.Confirm)
+	apiV1.HandleFunc("POST /auth/2fa/disable", twoFactorHandler.Disable)
+	apiV1.HandleFunc("POST /auth/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+
+	// Персональные API ключи пользователя текущей сессии
+	apiV1.HandleFunc("GET /auth/api-keys", apiKeyHandler.GetKeys)
+	apiV1.HandleFunc("POST /auth/api-keys", apiKeyHandler.CreateKey)
+	apiV1.HandleFunc("DELETE /auth/api-keys/{id}", apiKeyHandler.RevokeKey)
+
+	// Подтверждение email и сброс пароля
+	apiV1.HandleFunc("POST /auth/verify-email", userHandler.VerifyEmail)
+	apiV1.HandleFunc("POST /auth/password-reset", userHandler.RequestPasswordReset)
+	apiV1.HandleFunc("POST /auth/password-reset/confirm", userHandler.ConfirmPasswordReset)
+
+	// Группы
+	apiV1.HandleFunc("/groups", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			groupHandler.GetGroups(w, r)
+		case http.MethodPost:
+			groupHandler.CreateGroup(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	apiV1.HandleFunc("GET /groups/{id}", groupHandler.GetGroup)
+	apiV1.HandleFunc("POST /groups/{id}/members", groupHandler.AddMember)
+	apiV1.HandleFunc("DELETE /groups/{id}/members/{userId}", groupHandler.RemoveMember)
+	apiV1.HandleFunc("POST /groups/{id}/guests", guestHandler.AddGuest)
+	apiV1.HandleFunc("GET /groups/{id}/invites", inviteHandler.GetInvites)
+	apiV1.HandleFunc("POST /groups/{id}/invites", inviteHandler.CreateInvite)
+	apiV1.HandleFunc("POST /groups/{id}/invites/{inviteId}/revoke", inviteHandler.RevokeInvite)
+	apiV1.HandleFunc("POST /invites/accept", inviteHandler.AcceptInvite)
+	apiV1.HandleFunc("POST /invites/decline", inviteHandler.DeclineInvite)
+	apiV1.HandleFunc("GET /groups/{id}/export.csv", ledgerHandler.ExportGroup)
+	apiV1.HandleFunc("POST /groups/{id}/import", ledgerHandler.ImportGroup)
+	apiV1.HandleFunc("GET /groups/{id}/statement", statementHandler.GetStatement)
+	apiV1.HandleFunc("POST /import/splitwise", ledgerHandler.ImportSplitwise)
+	apiV1.HandleFunc("POST /guests/claim", guestHandler.ClaimGuest)
+
+	// Долги
+	apiV1.HandleFunc("/debts", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			debtHandler.GetDebts(w, r)
+		case http.MethodPost:
+			debtHandler.CreateDebt(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	apiV1.HandleFunc("GET /debts/{id}", debtHandler.GetDebt)
+
+	// Переходы статуса долга
+	for _, action := range []string{
+		service.DebtActionConfirm,
+		service.DebtActionReject,
+		service.DebtActionDispute,
+		service.DebtActionSettle,
+		service.DebtActionCancel,
+	} {
+		apiV1.HandleFunc("POST /debts/{id}/"+action, debtHandler.DebtAction(action))
+	}
+
+	// Повторяющиеся долги и расходы
+	apiV1.HandleFunc("/recurring", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			recurringHandler.GetAllSeries(w, r)
+		case http.MethodPost:
+			recurringHandler.CreateSeries(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	apiV1.HandleFunc("GET /recurring/{id}", recurringHandler.GetSeries)
+	apiV1.HandleFunc("PUT /recurring/{id}", recurringHandler.UpdateSeries)
+	for _, action := range []string{"pause", "resume", "end"} {
+		apiV1.HandleFunc("POST /recurring/{id}/"+action, recurringHandler.SeriesAction(action))
+	}
+
+	// Вебхуки
+	apiV1.HandleFunc("/webhooks", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			webhookHandler.GetWebhooks(w, r)
+		case http.MethodPost:
+			webhookHandler.CreateWebhook(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	apiV1.HandleFunc("GET /webhooks/{id}", webhookHandler.GetWebhook)
+	apiV1.HandleFunc("DELETE /webhooks/{id}", webhookHandler.DeleteWebhook)
+	apiV1.HandleFunc("GET /webhooks/{id}/deliveries", webhookHandler.GetDeliveries)
+	apiV1.HandleFunc("POST /webhooks/{id}/deliveries/{deliveryId}/redeliver", webhookHandler.Redeliver)
+
+	// Администрирование
+	apiV1.Handle("POST /admin/users/{id}/merge", adminMiddleware(cfg.Admin.Token, http.HandlerFunc(adminHandler.MergeUsers)))
+	apiV1.Handle("GET /admin/merges", adminMiddleware(cfg.Admin.Token, http.HandlerFunc(adminHandler.GetMerges)))
+
+	// Ограничение частоты запросов. Лимиты в базе общие для всех экземпляров сервера
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == "postgres" {
+		rateLimitStore = repository.NewRateLimitRepository(db)
+	}
+	limiter := ratelimit.NewLimiter(rateLimitStore)
+
+	// Добавляем CORS по настройкам CORS_*. Маршруты apiV1 объявлены без префикса /api/v1.
+	// Лимит проверяется после authMiddleware, чтобы считать запросы по пользователю или ключу.
+	// Изменения без входа отклоняются после лимита, так что они тоже считаются
+	apiHandler := authMiddleware(authService, apiKeyService,
+		rateLimitMiddleware(limiter, cfg.RateLimit,
+			requireUserMiddleware(metrics.Routes("/api/v1", apiV1))))
+	mux.Handle("/api/v1/", cors.New(cors.Config(cfg.CORS)).Handler(http.StripPrefix("/api/v1", apiHandler)))
 
 	// Health check
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -61,24 +305,192 @@ func NewRouter(db *pgx.Conn) http.Handler {
 		w.Write([]byte(`{"status":"ok","message":"Server is running"}`))
 	})
 
-	return mux
+	// Проверки для оркестратора: процесс жив и сервер готов принимать трафик
+	mux.Handle("GET /livez", checker.LiveHandler())
+	mux.Handle("GET /readyz", checker.ReadyHandler())
+
+	// Описание API и Swagger UI
+	mux.Handle("GET /api/openapi.json", openapi.Handler(apiSpec()))
+	mux.Handle("GET /docs", openapi.DocsHandler(apiInfo.Title, "/api/openapi.json"))
+
+	// Метрики в формате Prometheus. Отдаются вне /api/v1, без CORS и лимитов
+	registry.Register(businessMetrics(repository.NewStatsRepository(db)))
+	httpMetrics := metrics.NewHTTPMetrics(registry)
+	mux.Handle("GET /metrics", metricsMiddleware(cfg.Metrics.Token, registry.Handler()))
+
+	// Идентификатор запроса и журнал доступа, метрики и трассировка для всех маршрутов
+	return logging.Middleware(logger, httpMetrics.Middleware(
+		tracing.Middleware(metrics.Route, metrics.Routes("", mux)),
+	)), apiV1.patterns
 }
 
-// corsMiddleware добавляет CORS заголовки
-func corsMiddleware(next http.Handler) http.Handler {
+// authMiddleware кладет в контекст запроса пользователя из заголовка
+// Authorization: Bearer <token>. Токен - это сессия или персональный API ключ
+// (начинается с bal_). Запрос по API ключу пропускается, только если у ключа есть
+// нужное право. Запросы без сессии проходят дальше без пользователя: тот же
+// заголовок несет и токен администратора
+func authMiddleware(authService *service.AuthService, apiKeyService *service.APIKeyService, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Разрешаем запросы с любого origin (для разработки)
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Max-Age", "86400")
-
-		// Обрабатываем preflight запросы
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
+		bearer, ok := auth.BearerToken(r)
+		if !ok {
+			next.ServeHTTP(w, r)
 			return
 		}
 
+		if strings.HasPrefix(bearer, models.APIKeyPrefix) {
+			key, user, err := apiKeyService.Authenticate(r.Context(), bearer)
+			if err != nil {
+				if strings.HasPrefix(err.Error(), "failed to ") {
+					utils.SendInternalError(w, "Failed to check API key")
+					return
+				}
+				// 401 отправит requireUserMiddleware после лимита на IP: иначе
+				// подбирать ключи можно было бы без ограничений
+				next.ServeHTTP(w, r.WithContext(auth.WithFailure(r.Context())))
+				return
+			}
+
+			scope := auth.RequiredScope(r.Method, r.URL.Path)
+			if scope == "" {
+				utils.SendError(w, http.StatusForbidden, "This endpoint is not available with an API key")
+				return
+			}
+			if !key.HasScope(scope) {
+				utils.SendError(w, http.StatusForbidden, "API key does not have the "+scope+" scope")
+				return
+			}
+
+			ctx := auth.WithAPIKey(auth.WithUser(r.Context(), user), key)
+			ctx = logging.With(ctx, "user_id", user.ID, "api_key_id", key.ID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		if user, err := authService.Authenticate(r.Context(), bearer); err == nil {
+			ctx := logging.With(auth.WithUser(r.Context(), user), "user_id", user.ID)
+			r = r.WithContext(ctx)
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
+
+// requireUserMiddleware отклоняет с 401 запросы с неверным API ключом и изменяющие
+// запросы без сессии или API ключа (см. auth.RequiresUser). Обработчики берут
+// пользователя, от имени которого выполняется изменение, из контекста запроса
+func requireUserMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.Failed(r.Context()) {
+			utils.SendError(w, http.StatusUnauthorized, "Invalid API key")
+			return
+		}
+		if _, ok := auth.UserFromContext(r.Context()); !ok && auth.RequiresUser(r.Method, r.URL.Path) {
+			utils.SendError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminMiddleware пускает только запросы с заголовком Authorization: Bearer <ADMIN_TOKEN>.
+// Без настроенного токена административные маршруты недоступны
+func adminMiddleware(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			utils.SendError(w, http.StatusForbidden, "Admin API is disabled")
+			return
+		}
+
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			utils.SendError(w, http.StatusUnauthorized, "Invalid admin token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// metricsMiddleware пускает к метрикам только с заголовком Authorization: Bearer <METRICS_TOKEN>,
+// если токен задан
+func metricsMiddleware(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := auth.BearerToken(r)
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			utils.SendError(w, http.StatusUnauthorized, "Invalid metrics token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitMiddleware ограничивает частоту запросов. У каждого клиента (API ключ,
+// пользователь сессии или IP адрес) свои лимиты на чтение, изменение и на маршруты
+// /auth. Запросы с неверным API ключом расходуют лимит /auth своего IP. Сверх
+// лимита запрос получает 429 с Retry-After. Если хранилище лимитов недоступно,
+// запрос пропускается
+func rateLimitMiddleware(limiter *ratelimit.Limiter, cfg config.RateLimitConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class, limit := "read", ratelimit.Limit(cfg.Read)
+		switch {
+		case auth.Failed(r.Context()):
+			class, limit = "auth", ratelimit.Limit(cfg.Auth)
+		case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions:
+		case r.URL.Path == "/auth" || strings.HasPrefix(r.URL.Path, "/auth/"):
+			class, limit = "auth", ratelimit.Limit(cfg.Auth)
+		default:
+			class, limit = "write", ratelimit.Limit(cfg.Write)
+		}
+
+		if !limit.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		result, err := limiter.Allow(r.Context(), class+":"+rateLimitClient(r, cfg.TrustProxy), limit)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Rate limit check failed", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		result.WriteHeaders(w.Header())
+		if !result.Allowed {
+			utils.SendError(w, http.StatusTooManyRequests, "Too many requests, try again later")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitClient возвращает ключ клиента для лимитов: API ключ, пользователь сессии или IP
+func rateLimitClient(r *http.Request, trustProxy bool) string {
+	if key, ok := auth.APIKeyFromContext(r.Context()); ok {
+		return "key:" + strconv.Itoa(key.ID)
+	}
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		return "user:" + strconv.Itoa(user.ID)
+	}
+
+	// Последний адрес в X-Forwarded-For добавил наш прокси, предыдущие может подделать клиент
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			parts := strings.Split(forwarded, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return "ip:" + ip
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
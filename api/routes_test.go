@@ -0,0 +1,127 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"balance/internal/auth"
+	"balance/internal/config"
+	"balance/internal/health"
+	"balance/internal/logging"
+	"balance/internal/metrics"
+	"balance/internal/models"
+	"balance/internal/ratelimit"
+)
+
+func TestRequireUserMiddleware(t *testing.T) {
+	handler := requireUserMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method, path string
+		user         bool
+		want         int
+	}{
+		{"GET", "/debts", false, http.StatusUnauthorized},
+		{"GET", "/debts", true, http.StatusNoContent},
+		{"GET", "/groups/1/export.csv", false, http.StatusUnauthorized},
+		{"HEAD", "/users/1/balances", false, http.StatusUnauthorized},
+		{"OPTIONS", "/debts", false, http.StatusNoContent},
+		{"POST", "/debts", false, http.StatusUnauthorized},
+		{"POST", "/debts", true, http.StatusNoContent},
+		{"POST", "/debts/1/settle", false, http.StatusUnauthorized},
+		{"PUT", "/users/", false, http.StatusUnauthorized},
+		{"DELETE", "/webhooks/1", false, http.StatusUnauthorized},
+		{"POST", "/groups/1/import", false, http.StatusUnauthorized},
+		{"POST", "/users", false, http.StatusNoContent},
+		{"POST", "/auth/login", false, http.StatusNoContent},
+		{"POST", "/auth/password-reset/confirm", false, http.StatusNoContent},
+		{"POST", "/invites/decline", false, http.StatusNoContent},
+		{"POST", "/invites/accept", false, http.StatusUnauthorized},
+		{"POST", "/admin/users/1/merge", false, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.user {
+			r = r.WithContext(auth.WithUser(r.Context(), &models.User{ID: 1}))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s %s (user %v): got %d, want %d", tt.method, tt.path, tt.user, w.Code, tt.want)
+		}
+	}
+}
+
+// Без заголовка Authorization запросы отклоняются до обработчика, поэтому
+// ограничения API ключей нельзя обойти, просто не передав ключ
+func TestRouterRejectsAnonymousRequests(t *testing.T) {
+	handler := NewRouter(nil, config.New(), logging.New(io.Discard, "text", "error"),
+		metrics.NewRegistry(), health.New(time.Second))
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{"POST", "/api/v1/debts", `{"from_user_id": 1, "to_user_id": 2, "amount": 10, "created_by": 1}`},
+		{"GET", "/api/v1/debts", ""},
+		{"GET", "/api/v1/groups/1/statement", ""},
+		{"GET", "/api/v1/users/1/notification-preferences", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without credentials: got %d, want 401", tt.method, tt.path, w.Code)
+		}
+	}
+}
+
+// Неверный API ключ получает 401 только в пределах лимита /auth для своего IP
+func TestFailedAuthenticationIsRateLimited(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		Read: config.RateLimit{Requests: 100, Period: time.Minute},
+		Auth: config.RateLimit{Requests: 2, Period: time.Minute},
+	}
+	limited := rateLimitMiddleware(ratelimit.NewLimiter(ratelimit.NewMemoryStore()), cfg,
+		requireUserMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})))
+	// Так authMiddleware передает запрос с ключом, который не подошел
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			r = r.WithContext(auth.WithFailure(r.Context()))
+		}
+		limited.ServeHTTP(w, r)
+	})
+
+	request := func(ip string, withKey bool) int {
+		r := httptest.NewRequest("GET", "/debts", nil)
+		r.RemoteAddr = ip + ":1234"
+		if withKey {
+			r.Header.Set("Authorization", "Bearer bal_invalid")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if got := request("203.0.113.1", true); got != want {
+			t.Errorf("attempt %d with an invalid key: got %d, want %d", i+1, got, want)
+		}
+	}
+	if got := request("203.0.113.2", true); got != http.StatusUnauthorized {
+		t.Errorf("invalid key from another IP: got %d, want 401", got)
+	}
+	// Чтение без ключа с того же IP расходует свой лимит и получает обычный 401
+	if got := request("203.0.113.1", false); got != http.StatusUnauthorized {
+		t.Errorf("anonymous read from the limited IP: got %d, want 401", got)
+	}
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,33 +12,108 @@ import (
 	"balance/api"
 	"balance/internal/config"
 	"balance/internal/database"
+	"balance/internal/health"
+	"balance/internal/logging"
+	"balance/internal/metrics"
+	"balance/internal/notification"
+	"balance/internal/repository"
+	"balance/internal/scheduler"
+	"balance/internal/service"
+	"balance/internal/tracing"
+	"balance/internal/webhook"
 
 	"github.com/joho/godotenv"
 )
 
+// Таймауты этапов остановки сервера
+const (
+	serverShutdownTimeout  = 30 * time.Second
+	schedulerStopTimeout   = 30 * time.Second
+	tracingShutdownTimeout = 5 * time.Second
+)
+
 func main() {
 	// Загружаем .env
 	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
+		fatal("Error loading .env file", err)
 	}
 
 	// Инициализируем конфигурацию
 	cfg := config.New()
+	if err := cfg.Validate(); err != nil {
+		fatal("Invalid configuration", err)
+	}
+
+	// Логи пишутся в stdout в формате LOG_FORMAT
+	logger := logging.New(os.Stdout, cfg.Log.Format, cfg.Log.Level)
+	slog.SetDefault(logger)
+
+	// Трассировка OpenTelemetry. Оставшиеся спаны отправляются при остановке
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config(cfg.Tracing))
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+
+	// Метрики для /metrics. Запросы к базе измеряются с момента подключения
+	registry := metrics.NewRegistry()
+	dbMetrics := metrics.NewDBMetrics(registry)
 
 	// Подключаемся к базе данных
-	db, err := database.Connect(cfg.Database)
+	db, err := database.Connect(cfg.Database, dbMetrics, tracing.QueryTracer{})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", err)
 	}
-	defer db.Close(context.Background())
+	defer db.Close()
+	registry.Register(metrics.PoolStats(db))
 
 	// Применяем миграции
 	if err := database.RunMigrations(db); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+		fatal("Failed to run migrations", err)
 	}
 
+	// Фоновые задачи: уведомления, вебхуки, напоминания о сроках долгов, повторяющиеся долги
+	// и очистка лимитов частоты запросов
+	notificationService := service.NewNotificationService(
+		repository.NewOutboxRepository(db),
+		repository.NewNotificationRepository(db),
+		repository.NewUserRepository(db),
+		cfg.Notifications.MaxAttempts,
+		notification.NewNotifiers(cfg.SMTP)...,
+	)
+	webhookService := service.NewWebhookService(
+		repository.NewWebhookRepository(db),
+		repository.NewGroupRepository(db),
+		webhook.NewSender(nil),
+		cfg.Webhooks.MaxAttempts,
+	)
+	reminderService := service.NewReminderService(
+		repository.NewDebtRepository(db),
+		cfg.Reminders.Offsets,
+	)
+	recurringService := service.NewRecurringService(
+		repository.NewRecurringRepository(db),
+		repository.NewFriendshipRepository(db),
+	)
+	jobs := scheduler.New()
+	jobs.Every("debt-reminders", cfg.Reminders.Interval, reminderService.SendDueReminders)
+	jobs.Every("recurring-debts", cfg.Recurring.Interval, recurringService.RunDueSeries)
+	jobs.Every("notifications", cfg.Notifications.Interval, notificationService.Dispatch)
+	jobs.Every("webhooks", cfg.Webhooks.Interval, webhookService.Deliver)
+	if cfg.RateLimit.Store == "postgres" {
+		jobs.Every("rate-limits", cfg.RateLimit.PruneInterval, repository.NewRateLimitRepository(db).DeleteExpired)
+	}
+	jobs.Start(context.Background())
+
+	// Проверки готовности для /readyz
+	checker := health.New(cfg.Health.Timeout)
+	checker.Add("database", db.Ping)
+	checker.Add("migrations", func(ctx context.Context) error {
+		return database.CheckMigrations(ctx, db)
+	})
+	checker.Add("scheduler", jobs.Check)
+
 	// Создаем роутер
-	router := api.NewRouter(db)
+	router := api.NewRouter(db, cfg, logger, registry, checker)
 
 	// Создаем HTTP сервер
 	server := &http.Server{
@@ -55,31 +130,49 @@ func main() {
 
 	// Запускаем сервер в горутине
 	go func() {
-		log.Printf("Server is running on: http://localhost:%s", cfg.Server.Port)
-		log.Println("Available endpoints:")
-		log.Println("GET  /api/v1/users")
-		log.Println("POST /api/v1/users")
-		log.Println("GET  /api/v1/debts")
-		log.Println("POST /api/v1/debts")
-		log.Println("GET  /api/v1/groups")
-		log.Println("POST /api/v1/groups")
+		logger.Info("Server is running", "addr", "http://localhost:"+cfg.Server.Port)
 
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+			fatal("Server error", err)
 		}
 	}()
 
 	// Ждем сигнал для graceful shutdown
 	<-done
-	log.Println("Server is shutting down...")
+	logger.Info("Server is shutting down", "drain_delay", cfg.Health.DrainDelay)
 
-	// Даем серверу время на завершение текущих запросов
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	// /readyz начинает отвечать 503. Сервер продолжает принимать запросы, пока
+	// балансировщик не заметит это и не уберет его из ротации
+	checker.Shutdown()
+	time.Sleep(cfg.Health.DrainDelay)
+
+	// Даем серверу время на завершение текущих запросов. У каждого этапа остановки
+	// свой таймаут: долгие запросы не должны съедать время фоновых задач
+	if err := withTimeout(serverShutdownTimeout, server.Shutdown); err != nil {
+		logger.Error("Server shutdown error", "error", err)
+	}
+
+	// Останавливаем фоновые задачи до закрытия пула соединений
+	if err := withTimeout(schedulerStopTimeout, jobs.Stop); err != nil {
+		logger.Error("Scheduler shutdown error", "error", err)
+	}
 
-	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+	if err := withTimeout(tracingShutdownTimeout, shutdownTracing); err != nil {
+		logger.Error("Tracing shutdown error", "error", err)
 	}
 
-	log.Println("Server stopped")
+	logger.Info("Server stopped")
+}
+
+// withTimeout выполняет этап остановки с собственным таймаутом
+func withTimeout(timeout time.Duration, stop func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return stop(ctx)
+}
+
+// fatal пишет ошибку в лог и завершает процесс
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
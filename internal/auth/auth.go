@@ -0,0 +1,105 @@
+// Package auth передает аутентифицированного пользователя через контекст запроса
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"balance/internal/models"
+)
+
+type contextKey struct{}
+
+// WithUser возвращает контекст с пользователем запроса
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFromContext возвращает пользователя запроса, если запрос аутентифицирован
+func UserFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(contextKey{}).(*models.User)
+	return user, ok && user != nil
+}
+
+// BearerToken возвращает токен из заголовка Authorization: Bearer <token>
+func BearerToken(r *http.Request) (string, bool) {
+	bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	bearer = strings.TrimSpace(bearer)
+	return bearer, ok && bearer != ""
+}
+
+type apiKeyContextKey struct{}
+
+// WithAPIKey возвращает контекст с API ключом, которым аутентифицирован запрос
+func WithAPIKey(ctx context.Context, key *models.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// APIKeyFromContext возвращает API ключ запроса. У запросов с сессией ключа нет
+func APIKeyFromContext(ctx context.Context) (*models.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(*models.APIKey)
+	return key, ok && key != nil
+}
+
+type failureContextKey struct{}
+
+// WithFailure отмечает запрос, учетные данные которого не подошли. Такой запрос
+// проходит ограничение частоты как анонимный и только потом получает 401
+func WithFailure(ctx context.Context) context.Context {
+	return context.WithValue(ctx, failureContextKey{}, true)
+}
+
+// Failed сообщает, что учетные данные запроса не подошли
+func Failed(ctx context.Context) bool {
+	failed, _ := ctx.Value(failureContextKey{}).(bool)
+	return failed
+}
+
+// RequiredScope возвращает право API ключа, нужное для запроса. Чтение требует read,
+// изменение долгов и повторяющихся долгов - write:debts, остальные изменения - admin.
+// Пустая строка - маршрут недоступен по API ключу: ключом нельзя управлять входом,
+// 2FA и другими ключами
+func RequiredScope(method, path string) string {
+	switch {
+	case path == "/auth" || strings.HasPrefix(path, "/auth/"):
+		return ""
+	case method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions:
+		return models.ScopeRead
+	case hasPathPrefix(path, "/debts"), hasPathPrefix(path, "/recurring"):
+		return models.ScopeWriteDebts
+	}
+	return models.ScopeAdmin
+}
+
+// RequiresUser сообщает, нужен ли запросу пользователь: сессия или API ключ. Долги,
+// группы и настройки видны только их участникам, а изменения выполняются от имени
+// пользователя запроса, поэтому без входа доступны только регистрация, вход,
+// подтверждение email, сброс пароля, отказ от приглашения и preflight-запросы CORS.
+// Маршруты /admin проверяют ADMIN_TOKEN сами
+func RequiresUser(method, path string) bool {
+	if method == http.MethodOptions {
+		return false
+	}
+	if hasPathPrefix(path, "/admin") {
+		return false
+	}
+
+	switch method + " " + path {
+	case "POST /users",
+		"POST /auth/login",
+		"POST /auth/login/2fa",
+		"POST /auth/oidc/login",
+		"POST /auth/oidc/callback",
+		"POST /auth/verify-email",
+		"POST /auth/password-reset",
+		"POST /auth/password-reset/confirm",
+		"POST /invites/decline":
+		return false
+	}
+	return true
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -1,16 +1,40 @@
 package config
 
 import (
+	"fmt"
+	"log/slog"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
+	Server        ServerConfig
+	Database      DatabaseConfig
+	Reminders     RemindersConfig
+	Recurring     RecurringConfig
+	Notifications NotificationsConfig
+	SMTP          SMTPConfig
+	Webhooks      WebhooksConfig
+	Guests        GuestsConfig
+	Invites       InvitesConfig
+	Admin         AdminConfig
+	Auth          AuthConfig
+	OIDC          OIDCConfig
+	RateLimit     RateLimitConfig
+	CORS          CORSConfig
+	Log           LogConfig
+	Metrics       MetricsConfig
+	Tracing       TracingConfig
+	Health        HealthConfig
 }
 
 type ServerConfig struct {
 	Port string
+	// PublicURL - адрес приложения для ссылок в письмах
+	PublicURL string
 }
 
 type DatabaseConfig struct {
@@ -22,10 +46,154 @@ type DatabaseConfig struct {
 	SSLMode  string
 }
 
+type RemindersConfig struct {
+	// Offsets - когда напоминать относительно срока долга: отрицательные значения - до срока,
+	// положительные - после
+	Offsets []time.Duration
+	// Interval - как часто планировщик проверяет наступившие напоминания
+	Interval time.Duration
+}
+
+type RecurringConfig struct {
+	// Interval - как часто планировщик создает долги по наступившим повторениям
+	Interval time.Duration
+}
+
+type NotificationsConfig struct {
+	// Interval - как часто диспетчер разбирает outbox и отправляет уведомления
+	Interval time.Duration
+	// MaxAttempts - после стольких неудачных попыток уведомление помечается failed
+	MaxAttempts int
+}
+
+// SMTPConfig - настройки канала email. Если Host пустой, канал отключен
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type WebhooksConfig struct {
+	// Interval - как часто отправляются ожидающие доставки вебхуков
+	Interval time.Duration
+	// MaxAttempts - после стольких неудачных попыток доставка помечается failed
+	MaxAttempts int
+}
+
+type GuestsConfig struct {
+	// ClaimTTL - сколько действует приглашение забрать гостя
+	ClaimTTL time.Duration
+}
+
+type InvitesConfig struct {
+	// TTL - сколько по умолчанию действует приглашение в группу
+	TTL time.Duration
+}
+
+// AdminConfig - доступ к административным маршрутам /api/v1/admin.
+// Если Token пустой, они отключены
+type AdminConfig struct {
+	Token string
+}
+
+type AuthConfig struct {
+	// VerifyTTL - сколько действует ссылка для подтверждения email
+	VerifyTTL time.Duration
+	// ResetTTL - сколько действует ссылка для сброса пароля
+	ResetTTL time.Duration
+	// SessionTTL - сколько действует сессия после входа
+	SessionTTL time.Duration
+	// TOTPIssuer - название приложения в приложении-аутентификаторе
+	TOTPIssuer string
+}
+
+// OIDCConfig - провайдер OpenID Connect для входа. Если Issuer пустой, вход через него отключен
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL - страница клиента, на которую провайдер возвращает пользователя с code и state
+	RedirectURL string
+	Scopes      []string
+}
+
+// RateLimitConfig - ограничение частоты запросов к /api/v1. Клиент - API ключ,
+// пользователь сессии или IP адрес
+type RateLimitConfig struct {
+	// Read - запросы на чтение, Write - изменяющие запросы, Auth - вход и другие
+	// изменяющие запросы к /auth. Лимит "0" отключен
+	Read  RateLimit
+	Write RateLimit
+	Auth  RateLimit
+	// Store - где хранятся лимиты: memory - в памяти процесса, postgres - в базе,
+	// общие для всех экземпляров сервера
+	Store string
+	// TrustProxy - брать IP клиента из X-Forwarded-For, который добавил прокси перед сервером
+	TrustProxy bool
+	// PruneInterval - как часто из базы удаляются неиспользуемые лимиты
+	PruneInterval time.Duration
+}
+
+// CORSConfig - какие сайты могут обращаться к API из браузера
+type CORSConfig struct {
+	// AllowedOrigins - точные origin (https://app.example.com), все поддомены
+	// (https://*.example.com) или "*". Пустой список запрещает запросы с других сайтов.
+	// "*" нельзя сочетать с AllowCredentials, см. Validate
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders - заголовки ответа, доступные скрипту на странице
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge - сколько браузер кэширует ответ на preflight запрос
+	MaxAge time.Duration
+}
+
+// LogConfig - формат и уровень логов
+type LogConfig struct {
+	// Format - json или text
+	Format string
+	// Level - debug, info, warn или error
+	Level string
+}
+
+// MetricsConfig - доступ к /metrics. Если Token пустой, метрики открыты без токена
+type MetricsConfig struct {
+	Token string
+}
+
+// TracingConfig - экспорт трассировки OpenTelemetry. Если Endpoint пустой, спаны не отправляются
+type TracingConfig struct {
+	// Endpoint - адрес OTLP/HTTP коллектора, например http://localhost:4318
+	Endpoint    string
+	ServiceName string
+	// SampleRatio - доля записываемых трасс, если вызывающий не решил за нас в traceparent
+	SampleRatio float64
+}
+
+type HealthConfig struct {
+	// Timeout - сколько ждать каждую проверку готовности
+	Timeout time.Duration
+	// DrainDelay - сколько после сигнала остановки сервер отвечает "не готов",
+	// продолжая обслуживать запросы, прежде чем закрыть соединения
+	DrainDelay time.Duration
+}
+
+// RateLimit - Requests запросов за Period
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
 func New() *Config {
+	publicURL := strings.TrimRight(getEnv("PUBLIC_URL", "http://localhost:8080"), "/")
+
 	return &Config{
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
+			Port:      getEnv("SERVER_PORT", "8080"),
+			PublicURL: publicURL,
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -35,12 +203,208 @@ func New() *Config {
 			Name:     getEnv("DB_NAME", "balance_db"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
+		Reminders: RemindersConfig{
+			Offsets:  getEnvDurations("REMINDER_OFFSETS", "-24h,24h,72h"),
+			Interval: getEnvDuration("REMINDER_INTERVAL", "1m"),
+		},
+		Recurring: RecurringConfig{
+			Interval: getEnvDuration("RECURRING_INTERVAL", "1m"),
+		},
+		Notifications: NotificationsConfig{
+			Interval:    getEnvDuration("NOTIFY_INTERVAL", "10s"),
+			MaxAttempts: getEnvInt("NOTIFY_MAX_ATTEMPTS", 8),
+		},
+		SMTP: SMTPConfig{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: getEnv("SMTP_USER", ""),
+			Password: getEnv("SMTP_PASS", ""),
+			From:     getEnv("SMTP_FROM", "balance@localhost"),
+		},
+		Webhooks: WebhooksConfig{
+			Interval:    getEnvDuration("WEBHOOK_INTERVAL", "5s"),
+			MaxAttempts: getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		},
+		Guests: GuestsConfig{
+			ClaimTTL: getEnvDuration("GUEST_CLAIM_TTL", "168h"),
+		},
+		Invites: InvitesConfig{
+			TTL: getEnvDuration("INVITE_TTL", "168h"),
+		},
+		Admin: AdminConfig{
+			Token: getEnv("ADMIN_TOKEN", ""),
+		},
+		Auth: AuthConfig{
+			VerifyTTL:  getEnvDuration("EMAIL_VERIFY_TTL", "48h"),
+			ResetTTL:   getEnvDuration("PASSWORD_RESET_TTL", "1h"),
+			SessionTTL: getEnvDuration("SESSION_TTL", "720h"),
+			TOTPIssuer: getEnv("TOTP_ISSUER", "Balance"),
+		},
+		OIDC: OIDCConfig{
+			Issuer:       getEnv("OIDC_ISSUER", ""),
+			ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("OIDC_REDIRECT_URL", publicURL+"/auth/callback"),
+			Scopes:       strings.Fields(getEnv("OIDC_SCOPES", "openid email profile")),
+		},
+		RateLimit: RateLimitConfig{
+			Read:          getEnvRateLimit("RATE_LIMIT_READ", "300/1m"),
+			Write:         getEnvRateLimit("RATE_LIMIT_WRITE", "60/1m"),
+			Auth:          getEnvRateLimit("RATE_LIMIT_AUTH", "10/1m"),
+			Store:         getEnv("RATE_LIMIT_STORE", "memory"),
+			TrustProxy:    getEnvBool("RATE_LIMIT_TRUST_PROXY", false),
+			PruneInterval: getEnvDuration("RATE_LIMIT_PRUNE_INTERVAL", "10m"),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   getEnvList("CORS_ALLOWED_ORIGINS", ""),
+			AllowedMethods:   getEnvList("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE"),
+			AllowedHeaders:   getEnvList("CORS_ALLOWED_HEADERS", "Content-Type,Authorization,X-Request-ID,traceparent,tracestate"),
+			ExposedHeaders:   getEnvList("CORS_EXPOSED_HEADERS", "X-Request-ID,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy,Retry-After"),
+			AllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:           getEnvDuration("CORS_MAX_AGE", "24h"),
+		},
+		Log: LogConfig{
+			Format: getEnv("LOG_FORMAT", "json"),
+			Level:  getEnv("LOG_LEVEL", "info"),
+		},
+		Metrics: MetricsConfig{
+			Token: getEnv("METRICS_TOKEN", ""),
+		},
+		Tracing: TracingConfig{
+			Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "balance"),
+			SampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+		},
+		Health: HealthConfig{
+			Timeout:    getEnvDuration("HEALTH_CHECK_TIMEOUT", "2s"),
+			DrainDelay: getEnvDuration("SHUTDOWN_DRAIN_DELAY", "5s"),
+		},
 	}
 }
 
+// Validate проверяет сочетания настроек, при которых сервер запускать нельзя
+func (c *Config) Validate() error {
+	// С credentials браузер отправляет cookie и Authorization, поэтому "любой сайт"
+	// означал бы, что любая страница читает ответы API от имени пользователя
+	if c.CORS.AllowCredentials && slices.Contains(c.CORS.AllowedOrigins, "*") {
+		return fmt.Errorf("CORS_ALLOWED_ORIGINS=* cannot be combined with CORS_ALLOW_CREDENTIALS=true")
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Invalid integer in environment, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("Invalid number in environment, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return f
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("Invalid boolean in environment, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+func getEnvDuration(key, defaultValue string) time.Duration {
+	value := getEnv(key, defaultValue)
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("Invalid duration in environment, using default", "key", key, "value", value, "default", defaultValue)
+		d, _ = time.ParseDuration(defaultValue)
+	}
+	return d
+}
+
+// getEnvList читает список через запятую, пустые элементы пропускаются
+func getEnvList(key, defaultValue string) []string {
+	var list []string
+	for _, part := range strings.Split(getEnv(key, defaultValue), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// getEnvDurations читает список длительностей через запятую, например "-24h,24h"
+func getEnvDurations(key, defaultValue string) []time.Duration {
+	value := getEnv(key, defaultValue)
+
+	var durations []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil {
+			slog.Warn("Invalid duration in environment, skipping", "key", key, "value", part)
+			continue
+		}
+		durations = append(durations, d)
+	}
+	return durations
+}
+
+// getEnvRateLimit читает лимит вида "60/1m": число запросов и период. "0" отключает лимит
+func getEnvRateLimit(key, defaultValue string) RateLimit {
+	parse := func(value string) (RateLimit, bool) {
+		if strings.TrimSpace(value) == "0" {
+			return RateLimit{}, true
+		}
+		requests, period, ok := strings.Cut(value, "/")
+		if !ok {
+			return RateLimit{}, false
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(requests))
+		if err != nil || n < 0 {
+			return RateLimit{}, false
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(period))
+		if err != nil || d <= 0 {
+			return RateLimit{}, false
+		}
+		return RateLimit{Requests: n, Period: d}, true
+	}
+
+	value := getEnv(key, defaultValue)
+	limit, ok := parse(value)
+	if !ok {
+		slog.Warn("Invalid rate limit in environment, using default", "key", key, "value", value, "default", defaultValue)
+		limit, _ = parse(defaultValue)
+	}
+	return limit
+}
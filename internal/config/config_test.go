@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestValidateCORS(t *testing.T) {
+	tests := []struct {
+		origins     []string
+		credentials bool
+		ok          bool
+	}{
+		{nil, true, true},
+		{[]string{"*"}, false, true},
+		{[]string{"https://app.example.com", "https://*.example.com"}, true, true},
+		{[]string{"*"}, true, false},
+		{[]string{"https://app.example.com", "*"}, true, false},
+	}
+	for _, tt := range tests {
+		cfg := &Config{CORS: CORSConfig{AllowedOrigins: tt.origins, AllowCredentials: tt.credentials}}
+		if err := cfg.Validate(); (err == nil) != tt.ok {
+			t.Errorf("origins %q, credentials %v: got %v, want ok=%v", tt.origins, tt.credentials, err, tt.ok)
+		}
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "")
+	if err := New().Validate(); err != nil {
+		t.Errorf("default configuration is invalid: %v", err)
+	}
+}
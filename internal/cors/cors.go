@@ -0,0 +1,236 @@
+// Package cors разрешает браузерам обращаться к API с других сайтов по правилам
+// Cross-Origin Resource Sharing
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"balance/pkg/utils"
+)
+
+// Config - политика CORS
+type Config struct {
+	// AllowedOrigins - точные origin (https://app.example.com), все поддомены
+	// (https://*.example.com) или "*" - любой сайт. Пустой список запрещает запросы с других сайтов
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders - заголовки ответа, которые может прочитать скрипт на странице
+	ExposedHeaders []string
+	// AllowCredentials - разрешить запросы с cookie и заголовком Authorization от браузера.
+	// Не действует вместе с "*" в AllowedOrigins
+	AllowCredentials bool
+	// MaxAge - сколько браузер может не повторять preflight запрос
+	MaxAge time.Duration
+}
+
+// Policy проверяет запросы по Config
+type Policy struct {
+	anyOrigin   bool
+	origins     map[string]bool
+	subdomains  []subdomainPattern
+	methods     map[string]bool
+	anyHeader   bool
+	headers     map[string]bool
+	credentials bool
+
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	maxAge        string
+}
+
+// subdomainPattern - origin вида scheme://*.domain[:port]
+type subdomainPattern struct {
+	prefix string // scheme://
+	suffix string // .domain[:port]
+}
+
+func New(config Config) *Policy {
+	p := &Policy{
+		origins:       make(map[string]bool),
+		methods:       make(map[string]bool),
+		headers:       make(map[string]bool),
+		credentials:   config.AllowCredentials,
+		exposeHeaders: strings.Join(config.ExposedHeaders, ", "),
+	}
+
+	for _, origin := range config.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))
+		switch {
+		case origin == "":
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			scheme, domain, _ := strings.Cut(origin, "://*")
+			p.subdomains = append(p.subdomains, subdomainPattern{prefix: scheme + "://", suffix: domain})
+		default:
+			p.origins[origin] = true
+		}
+	}
+
+	var methods []string
+	for _, method := range config.AllowedMethods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method != "" && !p.methods[method] {
+			p.methods[method] = true
+			methods = append(methods, method)
+		}
+	}
+	p.allowMethods = strings.Join(methods, ", ")
+
+	var headers []string
+	for _, header := range config.AllowedHeaders {
+		header = strings.TrimSpace(header)
+		switch {
+		case header == "":
+		case header == "*":
+			p.anyHeader = true
+		default:
+			p.headers[strings.ToLower(header)] = true
+			headers = append(headers, header)
+		}
+	}
+	p.allowHeaders = strings.Join(headers, ", ")
+
+	if config.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(config.MaxAge.Seconds()))
+	}
+
+	return p
+}
+
+// Handler добавляет CORS заголовки к ответам next и отвечает на preflight запросы.
+// Preflight с неразрешенного origin, с неразрешенным методом или заголовком
+// получает 403. Обычные запросы с неразрешенного origin обрабатываются, но без
+// CORS заголовков, поэтому браузер не отдаст ответ скрипту
+func (p *Policy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			p.preflight(w, r, origin)
+			return
+		}
+
+		// Ответ зависит от Origin, кэши должны это учитывать
+		w.Header().Add("Vary", "Origin")
+		if origin != "" && p.allowOrigin(origin) {
+			p.setOrigin(w.Header(), origin)
+			if p.exposeHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", p.exposeHeaders)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (p *Policy) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	method := r.Header.Get("Access-Control-Request-Method")
+	requested := requestedHeaders(r)
+	if origin == "" || !p.allowOrigin(origin) || !p.methods[method] || !p.allowHeadersList(requested) {
+		utils.SendError(w, http.StatusForbidden, "CORS request not allowed")
+		return
+	}
+
+	p.setOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", p.allowMethods)
+	if p.anyHeader {
+		// Браузеры не понимают "*" в запросах с credentials, поэтому возвращаем запрошенные заголовки
+		if len(requested) > 0 {
+			h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+		}
+	} else if p.allowHeaders != "" {
+		h.Set("Access-Control-Allow-Headers", p.allowHeaders)
+	}
+	if p.maxAge != "" {
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setOrigin разрешает ответ для origin. Если разрешены все сайты, отдается "*" и
+// credentials не разрешаются: иначе любая страница читала бы ответы от имени
+// пользователя. Конфигурацию с "*" и credentials отклоняет config.Validate
+func (p *Policy) setOrigin(h http.Header, origin string) {
+	if p.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if p.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (p *Policy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+
+	for _, pattern := range p.subdomains {
+		if !strings.HasPrefix(origin, pattern.prefix) || !strings.HasSuffix(origin, pattern.suffix) {
+			continue
+		}
+		sub := origin[len(pattern.prefix) : len(origin)-len(pattern.suffix)]
+		if validSubdomain(sub) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Policy) allowHeadersList(headers []string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, header := range headers {
+		if !p.headers[strings.ToLower(header)] {
+			return false
+		}
+	}
+	return true
+}
+
+// requestedHeaders разбирает Access-Control-Request-Headers
+func requestedHeaders(r *http.Request) []string {
+	var headers []string
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, header := range strings.Split(value, ",") {
+			if header = strings.TrimSpace(header); header != "" {
+				headers = append(headers, header)
+			}
+		}
+	}
+	return headers
+}
+
+// validSubdomain проверяет, что между схемой и доменом стоят только метки
+// поддомена, а не, например, user@ или другой хост с путем
+func validSubdomain(sub string) bool {
+	if sub == "" || strings.HasPrefix(sub, ".") || strings.HasSuffix(sub, ".") || strings.Contains(sub, "..") {
+		return false
+	}
+	for _, c := range sub {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestHandler(config Config) http.Handler {
+	return New(config).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+var testConfig = Config{
+	AllowedOrigins:   []string{"https://app.example.net", "https://*.example.com"},
+	AllowedMethods:   []string{"GET", "POST"},
+	AllowedHeaders:   []string{"Content-Type", "Authorization"},
+	ExposedHeaders:   []string{"X-Request-ID"},
+	AllowCredentials: true,
+	MaxAge:           10 * time.Minute,
+}
+
+func TestHandlerOrigins(t *testing.T) {
+	handler := newTestHandler(testConfig)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.net", true},
+		{"https://APP.example.net", true},
+		{"https://other.example.net", false},
+		{"http://app.example.net", false},
+		{"https://app.example.net.evil.test", false},
+		{"https://a.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://example.com", false},
+		{"https://evil-example.com", false},
+		{"https://evilexample.com", false},
+		{"http://a.example.com", false},
+		{"https://user@a.example.com", false},
+		{"https://evil.test/.example.com", false},
+		{"https://.example.com", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/debts", nil)
+		r.Header.Set("Origin", tt.origin)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		// Запрос с чужого сайта обрабатывается, браузер сам не отдаст ответ скрипту
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: got status %d, want 200", tt.origin, w.Code)
+		}
+		if !slices.Contains(w.Header().Values("Vary"), "Origin") {
+			t.Errorf("%s: Vary = %v, want Origin", tt.origin, w.Header().Values("Vary"))
+		}
+
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.allowed {
+			if got != tt.origin || w.Header().Get("Access-Control-Allow-Credentials") != "true" ||
+				w.Header().Get("Access-Control-Expose-Headers") != "X-Request-ID" {
+				t.Errorf("%s: got headers %v, want the origin echoed with credentials", tt.origin, w.Header())
+			}
+		} else if got != "" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("%s: got headers %v, want no CORS headers", tt.origin, w.Header())
+		}
+	}
+}
+
+func TestHandlerPreflight(t *testing.T) {
+	handler := newTestHandler(testConfig)
+
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		headers string
+		want    int
+	}{
+		{"allowed", "https://app.example.net", "POST", "content-type, authorization", http.StatusNoContent},
+		{"subdomain", "https://a.example.com", "GET", "", http.StatusNoContent},
+		{"disallowed origin", "https://evil.test", "POST", "", http.StatusForbidden},
+		{"no origin", "", "POST", "", http.StatusForbidden},
+		{"disallowed method", "https://app.example.net", "DELETE", "", http.StatusForbidden},
+		{"disallowed header", "https://app.example.net", "POST", "Content-Type, X-Secret", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("OPTIONS", "/debts", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		r.Header.Set("Access-Control-Request-Method", tt.method)
+		if tt.headers != "" {
+			r.Header.Set("Access-Control-Request-Headers", tt.headers)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.name, w.Code, tt.want)
+			continue
+		}
+		h := w.Header()
+		if tt.want != http.StatusNoContent {
+			if h.Get("Access-Control-Allow-Origin") != "" || h.Get("Access-Control-Allow-Methods") != "" {
+				t.Errorf("%s: rejected preflight has CORS headers %v", tt.name, h)
+			}
+			continue
+		}
+		if h.Get("Access-Control-Allow-Origin") != tt.origin || h.Get("Access-Control-Allow-Methods") != "GET, POST" ||
+			h.Get("Access-Control-Allow-Headers") != "Content-Type, Authorization" || h.Get("Access-Control-Max-Age") != "600" {
+			t.Errorf("%s: unexpected headers %v", tt.name, h)
+		}
+	}
+}
+
+// С "*" в ответ идет "*" и никогда не разрешаются credentials
+func TestHandlerAnyOrigin(t *testing.T) {
+	config := testConfig
+	config.AllowedOrigins = []string{"*"}
+	config.AllowedHeaders = []string{"*"}
+	handler := newTestHandler(config)
+
+	r := httptest.NewRequest("OPTIONS", "/debts", nil)
+	r.Header.Set("Origin", "https://anything.test")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	r.Header.Set("Access-Control-Request-Headers", "X-Custom")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	h := w.Header()
+	if w.Code != http.StatusNoContent || h.Get("Access-Control-Allow-Origin") != "*" ||
+		h.Get("Access-Control-Allow-Credentials") != "" || h.Get("Access-Control-Allow-Headers") != "X-Custom" {
+		t.Errorf("got status %d and headers %v, want * without credentials", w.Code, h)
+	}
+}
@@ -3,15 +3,18 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"balance/internal/config"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func Connect(cfg config.DatabaseConfig) (*pgx.Conn, error) {
+// Connect подключается к базе. tracers получают события запросов вместе с логом запросов
+func Connect(cfg config.DatabaseConfig, tracers ...pgx.QueryTracer) (*pgxpool.Pool, error) {
 	// Формируем строку подключения
 	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
 		cfg.User,
@@ -25,16 +28,25 @@ func Connect(cfg config.DatabaseConfig) (*pgx.Conn, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	db, err := pgx.Connect(ctx, connStr)
+	// Пул соединений: HTTP обработчики и фоновые задачи работают с базой параллельно,
+	// а одно соединение pgx.Conn не допускает конкурентного использования
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
+	}
+	poolConfig.ConnConfig.Tracer = multitracer.New(append([]pgx.QueryTracer{queryLogger{}}, tracers...)...)
+
+	db, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	// Проверяем подключение
 	if err := db.Ping(ctx); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("PostgreSQL successfully connected!")
+	slog.Info("PostgreSQL connected", "host", cfg.Host, "database", cfg.Name)
 	return db, nil
 }
@@ -0,0 +1,59 @@
+// Package dbtest подключает тесты к PostgreSQL из переменной TEST_DATABASE_URL.
+// Без нее тесты, которым нужна база, пропускаются
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"balance/internal/database"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationLock - ключ advisory lock: пакеты тестов запускаются параллельно,
+// а одновременные CREATE TABLE IF NOT EXISTS в PostgreSQL конфликтуют
+const migrationLock = 4242001
+
+// Open подключается к тестовой базе и применяет миграции. Пул закрывается в конце теста
+func Open(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+		t.Fatalf("failed to lock migrations: %v", err)
+	}
+	defer conn.Exec(ctx, `SELECT pg_advisory_unlock($1)`, migrationLock)
+
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	return db
+}
+
+var seq atomic.Int64
+
+// Email возвращает адрес, которого еще нет в базе: тесты не очищают таблицы
+func Email(prefix string) string {
+	return fmt.Sprintf("%s-%d-%d@example.com", prefix, time.Now().UnixNano(), seq.Add(1))
+}
@@ -2,15 +2,63 @@ package database
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func RunMigrations(db *pgx.Conn) error {
+// RunMigrations применяет миграции и записывает в schema_version, сколько их применено
+func RunMigrations(db *pgxpool.Pool) error {
 	ctx := context.Background()
 
-	migrations := []string{
+	migrations := migrationList()
+	for i, migration := range migrations {
+		if _, err := db.Exec(ctx, migration); err != nil {
+			slog.Error("Migration failed", "migration", i+1, "error", err)
+			return err
+		}
+		slog.Debug("Migration completed", "migration", i+1)
+	}
+
+	// Версия схемы - число миграций. Список только дополняется, поэтому сервер,
+	// собранный раньше, спокойно работает с базой, которую обновил более новый
+	_, err := db.Exec(ctx, `
+		INSERT INTO schema_version (id, version) VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET version = GREATEST(schema_version.version, EXCLUDED.version),
+			updated_at = CURRENT_TIMESTAMP`,
+		len(migrations),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update schema version: %w", err)
+	}
+
+	slog.Info("All migrations completed", "count", len(migrations))
+	return nil
+}
+
+// CheckMigrations возвращает ошибку, если в базе применены не все миграции этой версии сервера
+func CheckMigrations(ctx context.Context, db *pgxpool.Pool) error {
+	var version int
+	err := db.QueryRow(ctx, `SELECT version FROM schema_version WHERE id = 1`).Scan(&version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("migrations have not been applied")
+		}
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	if expected := len(migrationList()); version < expected {
+		return fmt.Errorf("schema version is %d, expected %d", version, expected)
+	}
+	return nil
+}
+
+// migrationList возвращает миграции по порядку. Все они идемпотентны и
+// выполняются при каждом запуске
+func migrationList() []string {
+	return []string{
 		// Таблица пользователей
 		`CREATE TABLE IF NOT EXISTS users (
 			id SERIAL PRIMARY KEY,
@@ -47,30 +95,306 @@ func RunMigrations(db *pgx.Conn) error {
 			to_user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
 			amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
 			description TEXT,
-			status TEXT DEFAULT 'active' CHECK (status IN ('active', 'settled', 'cancelled')),
+			status TEXT DEFAULT 'proposed' CHECK (status IN ('proposed', 'active', 'settled', 'cancelled', 'disputed')),
+			status_note TEXT,
+			created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			settled_at TIMESTAMP,
 			CHECK (from_user_id != to_user_id)
 		)`,
 
+		// Статусы долга: proposed -> active -> settled/cancelled, плюс disputed.
+		// Для баз, созданных до появления подтверждения долгов, расширяем CHECK
+		`ALTER TABLE debts ADD COLUMN IF NOT EXISTS status_note TEXT`,
+		`ALTER TABLE debts ADD COLUMN IF NOT EXISTS created_by INTEGER REFERENCES users(id) ON DELETE SET NULL`,
+		`ALTER TABLE debts ALTER COLUMN status SET DEFAULT 'proposed'`,
+		`ALTER TABLE debts DROP CONSTRAINT IF EXISTS debts_status_check`,
+		`ALTER TABLE debts ADD CONSTRAINT debts_status_check
+			CHECK (status IN ('proposed', 'active', 'settled', 'cancelled', 'disputed'))`,
+
+		// Срок возврата долга и отправленные напоминания о нем.
+		// offset_seconds - смещение напоминания от срока (отрицательное - до срока)
+		`ALTER TABLE debts ADD COLUMN IF NOT EXISTS due_at TIMESTAMP`,
+		`CREATE TABLE IF NOT EXISTS debt_reminders (
+			id SERIAL PRIMARY KEY,
+			debt_id INTEGER REFERENCES debts(id) ON DELETE CASCADE,
+			offset_seconds INTEGER NOT NULL,
+			sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(debt_id, offset_seconds)
+		)`,
+
+		// Повторяющиеся долги и расходы. interval - ключевое слово, поэтому repeat_interval
+		`CREATE TABLE IF NOT EXISTS recurring_series (
+			id SERIAL PRIMARY KEY,
+			group_id INTEGER REFERENCES groups(id) ON DELETE CASCADE,
+			to_user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			description TEXT,
+			frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+			repeat_interval INTEGER NOT NULL DEFAULT 1 CHECK (repeat_interval > 0),
+			month_day INTEGER CHECK (month_day BETWEEN 1 AND 31),
+			start_at TIMESTAMP NOT NULL,
+			until_at TIMESTAMP,
+			max_count INTEGER CHECK (max_count > 0),
+			status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'ended')),
+			occurrences INTEGER NOT NULL DEFAULT 0,
+			last_run_at TIMESTAMP,
+			next_run_at TIMESTAMP,
+			created_by INTEGER REFERENCES users(id),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS recurring_shares (
+			series_id INTEGER REFERENCES recurring_series(id) ON DELETE CASCADE,
+			from_user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+			PRIMARY KEY (series_id, from_user_id)
+		)`,
+		`ALTER TABLE debts ADD COLUMN IF NOT EXISTS series_id INTEGER REFERENCES recurring_series(id) ON DELETE SET NULL`,
+		`ALTER TABLE debts ADD COLUMN IF NOT EXISTS occurrence_at TIMESTAMP`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_debts_series_occurrence
+			ON debts(series_id, occurrence_at, from_user_id) WHERE series_id IS NOT NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_recurring_series_next_run_at
+			ON recurring_series(next_run_at) WHERE status = 'active'`,
+
+		// Дружба между пользователями. Пара хранится одной строкой, user_id < friend_id.
+		// Прямые долги без группы возможны только между друзьями
+		`CREATE TABLE IF NOT EXISTS friendships (
+			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			friend_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			requested_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted')),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			accepted_at TIMESTAMP,
+			PRIMARY KEY (user_id, friend_id),
+			CHECK (user_id < friend_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_friendships_friend_id ON friendships(friend_id)`,
+
+		// Гости: участники групп без аккаунта и email. Гостя можно передать
+		// настоящему пользователю по приглашению (хранится хеш токена)
+		`ALTER TABLE users ALTER COLUMN email DROP NOT NULL`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS is_guest BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE TABLE IF NOT EXISTS guest_claims (
+			id SERIAL PRIMARY KEY,
+			guest_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash TEXT UNIQUE NOT NULL,
+			email TEXT,
+			created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_guest_claims_guest_id ON guest_claims(guest_id)`,
+		// Гость не может войти и подтвердить долг, поэтому его долги не ждут подтверждения
+		`UPDATE debts SET status = 'active'
+		WHERE status = 'proposed' AND from_user_id IN (SELECT id FROM users WHERE is_guest)`,
+
+		// Приглашения в группы по email или ссылке. Хранится хеш токена;
+		// приглашение по email одноразовое, ссылка - до max_uses вступлений
+		`CREATE TABLE IF NOT EXISTS group_invites (
+			id SERIAL PRIMARY KEY,
+			group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+			token_hash TEXT UNIQUE NOT NULL,
+			email TEXT,
+			created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			max_uses INTEGER NOT NULL DEFAULT 1 CHECK (max_uses > 0),
+			uses INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'declined', 'revoked')),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_group_invites_group_id ON group_invites(group_id)`,
+
+		// Пароли (bcrypt) и подтверждение email. Одноразовые токены из писем
+		// хранятся как хеш; email запоминается, чтобы не подтвердить уже измененный адрес
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash TEXT`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE TABLE IF NOT EXISTS user_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			purpose TEXT NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+			token_hash TEXT UNIQUE NOT NULL,
+			email TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_tokens_user_id ON user_tokens(user_id)`,
+
+		// Сессии после входа и вход через провайдера OpenID Connect: привязки
+		// аккаунтов провайдера к пользователям и незавершенные попытки входа
+		`CREATE TABLE IF NOT EXISTS sessions (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash TEXT UNIQUE NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			last_used_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id)`,
+		`CREATE TABLE IF NOT EXISTS user_identities (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			issuer TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			email TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_login_at TIMESTAMP,
+			UNIQUE(issuer, subject)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id)`,
+		`CREATE TABLE IF NOT EXISTS oidc_logins (
+			state_hash TEXT PRIMARY KEY,
+			nonce TEXT NOT NULL,
+			code_verifier TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL
+		)`,
+
+		// Двухфакторная аутентификация по TOTP. totp_last_step - шаг последнего
+		// принятого кода, чтобы код нельзя было использовать повторно. Коды
+		// восстановления и вход, ожидающий второго фактора, хранятся как хеши
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT`,
+		`CREATE TABLE IF NOT EXISTS recovery_codes (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code_hash TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			used_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes(user_id)`,
+		`CREATE TABLE IF NOT EXISTS login_challenges (
+			token_hash TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL
+		)`,
+
+		// Персональные API ключи. Хранится только хеш ключа, prefix - его начало,
+		// по которому пользователь узнает ключ в списке
+		`CREATE TABLE IF NOT EXISTS api_keys (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			prefix TEXT NOT NULL,
+			key_hash TEXT UNIQUE NOT NULL,
+			scopes TEXT[] NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_used_at TIMESTAMP,
+			revoked_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id)`,
+
+		// Корзины ограничения частоты запросов, общие для экземпляров сервера
+		// (RATE_LIMIT_STORE=postgres). allowed - прошел ли последний запрос,
+		// expires_at - когда корзина наполнится и ее можно удалить
+		`CREATE TABLE IF NOT EXISTS rate_limits (
+			key TEXT PRIMARY KEY,
+			tokens DOUBLE PRECISION NOT NULL,
+			allowed BOOLEAN NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rate_limits_expires_at ON rate_limits(expires_at)`,
+
+		// Журнал слияний пользователей. Исходный пользователь удаляется, поэтому
+		// его данные копируются в запись, а summary хранит отчет о переносе
+		`CREATE TABLE IF NOT EXISTS user_merges (
+			id SERIAL PRIMARY KEY,
+			target_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			source_user_id INTEGER NOT NULL,
+			source_name TEXT NOT NULL,
+			source_email TEXT,
+			summary JSONB NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Подписки вебхуков: ровно одно из group_id и user_id
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id SERIAL PRIMARY KEY,
+			group_id INTEGER REFERENCES groups(id) ON DELETE CASCADE,
+			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			event_types TEXT[] NOT NULL,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_by INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK ((group_id IS NULL) != (user_id IS NULL))
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhooks_group_id ON webhooks(group_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id)`,
+
+		// Transactional outbox: события пишутся в одной транзакции с доменными изменениями,
+		// диспетчер превращает их в уведомления и доставляет с повторными попытками
+		`CREATE TABLE IF NOT EXISTS outbox_events (
+			id BIGSERIAL PRIMARY KEY,
+			event_type TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			processed_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(id) WHERE processed_at IS NULL`,
+		`CREATE TABLE IF NOT EXISTS notifications (
+			id BIGSERIAL PRIMARY KEY,
+			event_id BIGINT REFERENCES outbox_events(id) ON DELETE CASCADE,
+			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			channel TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			body TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			last_error TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			sent_at TIMESTAMP,
+			UNIQUE(event_id, user_id, channel)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_notifications_due ON notifications(next_attempt_at) WHERE status = 'pending'`,
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id BIGSERIAL PRIMARY KEY,
+			webhook_id INTEGER REFERENCES webhooks(id) ON DELETE CASCADE,
+			event_id BIGINT REFERENCES outbox_events(id) ON DELETE CASCADE,
+			event_type TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			response_status INTEGER,
+			response_body TEXT,
+			last_error TEXT,
+			duration_ms INTEGER,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			delivered_at TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending'`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id)`,
+		`CREATE TABLE IF NOT EXISTS notification_preferences (
+			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			channel TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			enabled BOOLEAN NOT NULL,
+			PRIMARY KEY (user_id, channel, event_type)
+		)`,
+
 		// Индексы для оптимизации
 		`CREATE INDEX IF NOT EXISTS idx_debts_group_id ON debts(group_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_debts_from_user_id ON debts(from_user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_debts_to_user_id ON debts(to_user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_debts_status ON debts(status)`,
+		`CREATE INDEX IF NOT EXISTS idx_debts_due_at ON debts(due_at) WHERE due_at IS NOT NULL`,
 		`CREATE INDEX IF NOT EXISTS idx_group_members_group_id ON group_members(group_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_group_members_user_id ON group_members(user_id)`,
-	}
 
-	for i, migration := range migrations {
-		if _, err := db.Exec(ctx, migration); err != nil {
-			log.Printf("Migration %d failed: %v", i+1, err)
-			return err
-		}
-		log.Printf("Migration %d completed successfully", i+1)
+		// Версия схемы для проверки готовности: сколько миграций применено
+		`CREATE TABLE IF NOT EXISTS schema_version (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			version INTEGER NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
 	}
-
-	log.Println("All migrations completed successfully")
-	return nil
 }
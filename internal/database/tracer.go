@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"balance/internal/logging"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql   string
+	start time.Time
+}
+
+// queryLogger пишет запросы к базе в лог на уровне debug логгером из контекста,
+// поэтому запросы репозиториев помечены идентификатором HTTP запроса или именем задачи
+type queryLogger struct{}
+
+func (queryLogger) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !logging.FromContext(ctx).Enabled(ctx, slog.LevelDebug) {
+		return ctx
+	}
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, start: time.Now()})
+}
+
+func (queryLogger) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	args := []any{"sql", q.sql, "duration_ms", logging.DurationMS(time.Since(q.start)), "rows", data.CommandTag.RowsAffected()}
+	if data.Err != nil {
+		args = append(args, "error", data.Err)
+	}
+	logging.FromContext(ctx).Debug("Database query", args...)
+}
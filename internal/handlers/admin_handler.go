@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+// AdminHandler обслуживает административные операции. Доступ проверяется
+// на уровне маршрутов
+type AdminHandler struct {
+	mergeService *service.MergeService
+}
+
+func NewAdminHandler(mergeService *service.MergeService) *AdminHandler {
+	return &AdminHandler{
+		mergeService: mergeService,
+	}
+}
+
+// MergeUsers обрабатывает POST запрос для слияния пользователя source_user_id
+// в пользователя из пути. С "dry_run": true возвращает предпросмотр
+func (h *AdminHandler) MergeUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.MergeUsersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	merge, err := h.mergeService.MergeUsers(r.Context(), id, &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to merge users")
+		return
+	}
+
+	utils.SendSuccess(w, merge)
+}
+
+// GetMerges обрабатывает GET запрос для получения журнала слияний.
+// Поддерживает фильтр user_id (целевой пользователь) в query
+func (h *AdminHandler) GetMerges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var userID int
+	if v := r.URL.Query().Get("user_id"); v != "" {
+		var err error
+		if userID, err = strconv.Atoi(v); err != nil {
+			utils.SendBadRequest(w, "Invalid user ID")
+			return
+		}
+	}
+
+	merges, err := h.mergeService.GetMerges(r.Context(), userID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get merges")
+		return
+	}
+
+	utils.SendSuccess(w, merges)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+// APIKeyHandler управляет API ключами пользователя текущей сессии
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateKey обрабатывает POST запрос для выпуска API ключа. Ключ виден только в этом ответе
+func (h *APIKeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	key, err := h.apiKeyService.CreateKey(r.Context(), user.ID, &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to create API key")
+		return
+	}
+
+	utils.SendCreated(w, key)
+}
+
+// GetKeys обрабатывает GET запрос для получения API ключей пользователя
+func (h *APIKeyHandler) GetKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyService.GetKeys(r.Context(), user.ID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get API keys")
+		return
+	}
+
+	utils.SendSuccess(w, keys)
+}
+
+// RevokeKey обрабатывает DELETE запрос для отзыва API ключа
+func (h *APIKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid API key ID")
+		return
+	}
+
+	key, err := h.apiKeyService.RevokeKey(r.Context(), user.ID, id)
+	if err != nil {
+		sendServiceError(w, err, "Failed to revoke API key")
+		return
+	}
+
+	utils.SendSuccess(w, key)
+}
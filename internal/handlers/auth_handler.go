@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"balance/internal/auth"
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+type AuthHandler struct {
+	authService *service.AuthService
+}
+
+func NewAuthHandler(authService *service.AuthService) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+	}
+}
+
+// StartOIDCLogin обрабатывает POST запрос для начала входа через провайдера OpenID Connect
+func (h *AuthHandler) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !h.authService.OIDCEnabled() {
+		utils.SendError(w, http.StatusForbidden, "OIDC login is disabled")
+		return
+	}
+
+	login, err := h.authService.StartOIDCLogin(r.Context())
+	if err != nil {
+		sendServiceError(w, err, "Failed to start login")
+		return
+	}
+
+	utils.SendSuccess(w, login)
+}
+
+// FinishOIDCLogin обрабатывает POST запрос с code и state, с которыми провайдер
+// вернул пользователя, и выдает сессию или запрашивает второй фактор
+func (h *AuthHandler) FinishOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !h.authService.OIDCEnabled() {
+		utils.SendError(w, http.StatusForbidden, "OIDC login is disabled")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.OIDCCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	result, err := h.authService.FinishOIDCLogin(r.Context(), &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to finish login")
+		return
+	}
+
+	utils.SendSuccess(w, result)
+}
+
+// CompleteTwoFactorLogin обрабатывает POST запрос со вторым фактором после пароля
+// или входа через провайдера и выдает сессию
+func (h *AuthHandler) CompleteTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	result, err := h.authService.CompleteTwoFactorLogin(r.Context(), &req)
+	if err != nil {
+		sendAuthError(w, err, "Failed to log in")
+		return
+	}
+
+	utils.SendSuccess(w, result)
+}
+
+// Me обрабатывает GET запрос для получения пользователя текущей сессии
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	utils.SendSuccess(w, user)
+}
+
+// Logout обрабатывает POST запрос для завершения текущей сессии
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	sessionToken, ok := auth.BearerToken(r)
+	if !ok {
+		utils.SendError(w, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	if err := h.authService.Logout(r.Context(), sessionToken); err != nil {
+		sendServiceError(w, err, "Failed to log out")
+		return
+	}
+
+	utils.SendSuccess(w, map[string]string{"message": "Logged out"})
+}
+
+// requireUser возвращает пользователя сессии или отвечает 401
+func requireUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		utils.SendError(w, http.StatusUnauthorized, "Authentication required")
+	}
+	return user, ok
+}
+
+// actingUser возвращает ID пользователя запроса, от имени которого выполняется изменение.
+// claimed - пользователь из тела или пути запроса. Если он не указан, подставляется
+// пользователь запроса, если указан другой - запрос отклоняется с 403
+func actingUser(w http.ResponseWriter, r *http.Request, claimed int) (int, bool) {
+	user, ok := requireUser(w, r)
+	if !ok {
+		return 0, false
+	}
+	if claimed != 0 && claimed != user.ID {
+		utils.SendError(w, http.StatusForbidden, "Cannot act on behalf of another user")
+		return 0, false
+	}
+	return user.ID, true
+}
+
+// ownData пропускает чтение данных пользователя id, только если это пользователь
+// запроса: чужие балансы, долги, друзья и настройки не видны
+func ownData(w http.ResponseWriter, r *http.Request, id int) bool {
+	user, ok := requireUser(w, r)
+	if !ok {
+		return false
+	}
+	if user.ID != id {
+		utils.SendError(w, http.StatusForbidden, "Cannot read another user's data")
+		return false
+	}
+	return true
+}
+
+// sendAuthError отвечает 401 на неверные учетные данные, остальные ошибки - как sendServiceError
+func sendAuthError(w http.ResponseWriter, err error, internalMessage string) {
+	switch err.Error() {
+	case "invalid email or password", "invalid password", "invalid two-factor code":
+		utils.SendError(w, http.StatusUnauthorized, err.Error())
+	default:
+		sendServiceError(w, err, internalMessage)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+type BalanceHandler struct {
+	balanceService *service.BalanceService
+}
+
+func NewBalanceHandler(balanceService *service.BalanceService) *BalanceHandler {
+	return &BalanceHandler{
+		balanceService: balanceService,
+	}
+}
+
+// GetBalances обрабатывает GET запрос для получения балансов пользователя со всеми
+func (h *BalanceHandler) GetBalances(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+	if !ownData(w, r, id) {
+		return
+	}
+
+	balances, err := h.balanceService.GetBalances(r.Context(), id)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get balances")
+		return
+	}
+
+	utils.SendSuccess(w, balances)
+}
+
+// GetBalance обрабатывает GET запрос для получения баланса пользователя с другим пользователем
+func (h *BalanceHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+
+	otherID, err := strconv.Atoi(r.PathValue("otherId"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid other user ID")
+		return
+	}
+	if !ownData(w, r, id) {
+		return
+	}
+
+	balance, err := h.balanceService.GetBalance(r.Context(), id, otherID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get balance")
+		return
+	}
+
+	utils.SendSuccess(w, balance)
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+type DebtHandler struct {
+	debtService *service.DebtService
+}
+
+func NewDebtHandler(debtService *service.DebtService) *DebtHandler {
+	return &DebtHandler{
+		debtService: debtService,
+	}
+}
+
+// CreateDebt обрабатывает POST запрос для создания долга
+func (h *DebtHandler) CreateDebt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.CreateDebtRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actorID, ok := actingUser(w, r, req.CreatedBy)
+	if !ok {
+		return
+	}
+	req.CreatedBy = actorID
+
+	debt, err := h.debtService.CreateDebt(r.Context(), &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to create debt")
+		return
+	}
+
+	utils.SendCreated(w, debt)
+}
+
+// GetDebt обрабатывает GET запрос для получения долга по ID
+func (h *DebtHandler) GetDebt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid debt ID")
+		return
+	}
+
+	debt, err := h.debtService.GetDebt(r.Context(), id)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get debt")
+		return
+	}
+
+	utils.SendSuccess(w, debt)
+}
+
+// GetDebts обрабатывает GET запрос для получения списка долгов.
+// Поддерживает фильтры group_id, user_id и status в query, limit и offset задают страницу
+func (h *DebtHandler) GetDebts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.DebtFilter{Status: query.Get("status")}
+
+	var err error
+	if v := query.Get("group_id"); v != "" {
+		if filter.GroupID, err = strconv.Atoi(v); err != nil {
+			utils.SendBadRequest(w, "Invalid group ID")
+			return
+		}
+	}
+	if v := query.Get("user_id"); v != "" {
+		if filter.UserID, err = strconv.Atoi(v); err != nil {
+			utils.SendBadRequest(w, "Invalid user ID")
+			return
+		}
+	}
+
+	var ok bool
+	if filter.Page, ok = parsePage(w, r); !ok {
+		return
+	}
+
+	debts, err := h.debtService.GetDebts(r.Context(), filter)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get debts")
+		return
+	}
+
+	utils.SendSuccess(w, debts)
+}
+
+// GetOverdueDebts обрабатывает GET запрос для получения просроченных долгов пользователя
+func (h *DebtHandler) GetOverdueDebts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+	if !ownData(w, r, userID) {
+		return
+	}
+
+	debts, err := h.debtService.GetOverdueDebts(r.Context(), userID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get overdue debts")
+		return
+	}
+
+	utils.SendSuccess(w, debts)
+}
+
+// DebtAction возвращает обработчик POST запроса, который выполняет
+// действие action (confirm, reject, dispute, settle, cancel) над долгом
+func (h *DebtHandler) DebtAction(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			utils.SendBadRequest(w, "Invalid debt ID")
+			return
+		}
+
+		// Ограничиваем размер тела запроса
+		r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+		var req models.DebtActionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendBadRequest(w, "Invalid JSON format")
+			return
+		}
+
+		actorID, ok := actingUser(w, r, req.UserID)
+		if !ok {
+			return
+		}
+		req.UserID = actorID
+
+		debt, err := h.debtService.ApplyAction(r.Context(), id, action, &req)
+		if err != nil {
+			sendServiceError(w, err, "Failed to update debt")
+			return
+		}
+
+		utils.SendSuccess(w, debt)
+	}
+}
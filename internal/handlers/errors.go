@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"balance/pkg/utils"
+)
+
+// sendServiceError переводит ошибку сервиса в HTTP ответ по тексту ошибки:
+// "... not found" - 404, "only the ..." - 403, "cannot ..." и конфликт статуса - 409,
+// "failed to ..." - 500 с сообщением internalMessage, остальное - ошибка валидации 400
+func sendServiceError(w http.ResponseWriter, err error, internalMessage string) {
+	message := err.Error()
+	switch {
+	case strings.HasSuffix(message, " not found"):
+		utils.SendNotFound(w, strings.ToUpper(message[:1])+message[1:])
+	case strings.HasPrefix(message, "only the "):
+		utils.SendError(w, http.StatusForbidden, message)
+	case strings.HasPrefix(message, "cannot "), strings.HasSuffix(message, "has changed, try again"):
+		utils.SendError(w, http.StatusConflict, message)
+	case strings.HasPrefix(message, "failed to "):
+		utils.SendInternalError(w, internalMessage)
+	default:
+		utils.SendBadRequest(w, message)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+type FriendshipHandler struct {
+	friendshipService *service.FriendshipService
+}
+
+func NewFriendshipHandler(friendshipService *service.FriendshipService) *FriendshipHandler {
+	return &FriendshipHandler{
+		friendshipService: friendshipService,
+	}
+}
+
+// GetFriends обрабатывает GET запрос для получения друзей пользователя.
+// Поддерживает фильтр status (pending, accepted) в query
+func (h *FriendshipHandler) GetFriends(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+	if !ownData(w, r, id) {
+		return
+	}
+
+	friendships, err := h.friendshipService.GetFriends(r.Context(), id, r.URL.Query().Get("status"))
+	if err != nil {
+		sendServiceError(w, err, "Failed to get friends")
+		return
+	}
+
+	utils.SendSuccess(w, friendships)
+}
+
+// AddFriend обрабатывает POST запрос для отправки запроса дружбы
+func (h *FriendshipHandler) AddFriend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.AddFriendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	if _, ok := actingUser(w, r, id); !ok {
+		return
+	}
+
+	friendship, err := h.friendshipService.AddFriend(r.Context(), id, &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to add friend")
+		return
+	}
+
+	utils.SendCreated(w, friendship)
+}
+
+// AcceptFriend обрабатывает POST запрос для принятия запроса дружбы
+func (h *FriendshipHandler) AcceptFriend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+
+	friendID, err := strconv.Atoi(r.PathValue("friendId"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid friend ID")
+		return
+	}
+
+	if _, ok := actingUser(w, r, id); !ok {
+		return
+	}
+
+	friendship, err := h.friendshipService.AcceptFriend(r.Context(), id, friendID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to accept friend request")
+		return
+	}
+
+	utils.SendSuccess(w, friendship)
+}
+
+// RemoveFriend обрабатывает DELETE запрос для удаления друга или запроса дружбы
+func (h *FriendshipHandler) RemoveFriend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+
+	friendID, err := strconv.Atoi(r.PathValue("friendId"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid friend ID")
+		return
+	}
+
+	if _, ok := actingUser(w, r, id); !ok {
+		return
+	}
+
+	if err := h.friendshipService.RemoveFriend(r.Context(), id, friendID); err != nil {
+		sendServiceError(w, err, "Failed to remove friend")
+		return
+	}
+
+	utils.SendSuccess(w, map[string]string{"message": "Friend removed successfully"})
+}
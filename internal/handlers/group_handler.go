@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+type GroupHandler struct {
+	groupService *service.GroupService
+}
+
+func NewGroupHandler(groupService *service.GroupService) *GroupHandler {
+	return &GroupHandler{
+		groupService: groupService,
+	}
+}
+
+// CreateGroup обрабатывает POST запрос для создания группы
+func (h *GroupHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.CreateGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actorID, ok := actingUser(w, r, req.CreatedBy)
+	if !ok {
+		return
+	}
+	req.CreatedBy = actorID
+
+	group, err := h.groupService.CreateGroup(r.Context(), &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to create group")
+		return
+	}
+
+	utils.SendCreated(w, group)
+}
+
+// GetGroups обрабатывает GET запрос для получения списка групп.
+// С параметром user_id возвращает только группы пользователя, limit и offset задают страницу
+func (h *GroupHandler) GetGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var userID int
+	if v := r.URL.Query().Get("user_id"); v != "" {
+		var err error
+		if userID, err = strconv.Atoi(v); err != nil {
+			utils.SendBadRequest(w, "Invalid user ID")
+			return
+		}
+	}
+
+	page, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	groups, err := h.groupService.GetGroups(r.Context(), userID, page)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get groups")
+		return
+	}
+
+	utils.SendSuccess(w, groups)
+}
+
+// GetGroup обрабатывает GET запрос для получения группы с участниками
+func (h *GroupHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid group ID")
+		return
+	}
+
+	group, err := h.groupService.GetGroup(r.Context(), id)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get group")
+		return
+	}
+
+	utils.SendSuccess(w, group)
+}
+
+// AddMember обрабатывает POST запрос для добавления участника в группу
+func (h *GroupHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid group ID")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.AddMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actor, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	member, err := h.groupService.AddMember(r.Context(), groupID, actor.ID, &req)
+	if err != nil {
+		if err.Error() == "user is already a member of this group" {
+			utils.SendError(w, http.StatusConflict, err.Error())
+			return
+		}
+		sendServiceError(w, err, "Failed to add member")
+		return
+	}
+
+	utils.SendCreated(w, member)
+}
+
+// RemoveMember обрабатывает DELETE запрос для удаления участника из группы
+func (h *GroupHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	groupID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid group ID")
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("userId"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+
+	actor, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.groupService.RemoveMember(r.Context(), groupID, userID, actor.ID); err != nil {
+		sendServiceError(w, err, "Failed to remove member")
+		return
+	}
+
+	utils.SendSuccess(w, map[string]string{"message": "Member removed successfully"})
+}
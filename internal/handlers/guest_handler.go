@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+type GuestHandler struct {
+	guestService *service.GuestService
+}
+
+func NewGuestHandler(guestService *service.GuestService) *GuestHandler {
+	return &GuestHandler{
+		guestService: guestService,
+	}
+}
+
+// AddGuest обрабатывает POST запрос для добавления гостя в группу
+func (h *GuestHandler) AddGuest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid group ID")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.CreateGuestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actorID, ok := actingUser(w, r, req.CreatedBy)
+	if !ok {
+		return
+	}
+	req.CreatedBy = actorID
+
+	guest, err := h.guestService.AddGuest(r.Context(), id, &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to add guest")
+		return
+	}
+
+	utils.SendCreated(w, guest)
+}
+
+// CreateClaim обрабатывает POST запрос для создания приглашения забрать гостя
+func (h *GuestHandler) CreateClaim(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.CreateGuestClaimRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actorID, ok := actingUser(w, r, req.CreatedBy)
+	if !ok {
+		return
+	}
+	req.CreatedBy = actorID
+
+	claim, err := h.guestService.CreateClaim(r.Context(), id, &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to create claim invite")
+		return
+	}
+
+	utils.SendCreated(w, claim)
+}
+
+// ClaimGuest обрабатывает POST запрос, которым пользователь забирает гостя по токену
+func (h *GuestHandler) ClaimGuest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.ClaimGuestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actorID, ok := actingUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	req.UserID = actorID
+
+	result, err := h.guestService.ClaimGuest(r.Context(), &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to claim guest")
+		return
+	}
+
+	utils.SendSuccess(w, result)
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+type InviteHandler struct {
+	inviteService *service.InviteService
+}
+
+func NewInviteHandler(inviteService *service.InviteService) *InviteHandler {
+	return &InviteHandler{
+		inviteService: inviteService,
+	}
+}
+
+// CreateInvite обрабатывает POST запрос для создания приглашения в группу
+func (h *InviteHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid group ID")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actorID, ok := actingUser(w, r, req.CreatedBy)
+	if !ok {
+		return
+	}
+	req.CreatedBy = actorID
+
+	invite, err := h.inviteService.CreateInvite(r.Context(), id, &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to create invite")
+		return
+	}
+
+	utils.SendCreated(w, invite)
+}
+
+// GetInvites обрабатывает GET запрос для получения приглашений группы
+func (h *InviteHandler) GetInvites(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid group ID")
+		return
+	}
+
+	invites, err := h.inviteService.GetInvites(r.Context(), id, user.ID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get invites")
+		return
+	}
+
+	utils.SendSuccess(w, invites)
+}
+
+// RevokeInvite обрабатывает POST запрос для отзыва приглашения
+func (h *InviteHandler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid group ID")
+		return
+	}
+
+	inviteID, err := strconv.Atoi(r.PathValue("inviteId"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid invite ID")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.RevokeInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actorID, ok := actingUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	req.UserID = actorID
+
+	invite, err := h.inviteService.RevokeInvite(r.Context(), id, inviteID, &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to revoke invite")
+		return
+	}
+
+	utils.SendSuccess(w, invite)
+}
+
+// AcceptInvite обрабатывает POST запрос для вступления в группу по приглашению
+func (h *InviteHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.InviteTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actorID, ok := actingUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	req.UserID = actorID
+
+	member, err := h.inviteService.AcceptInvite(r.Context(), &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to accept invite")
+		return
+	}
+
+	utils.SendCreated(w, member)
+}
+
+// DeclineInvite обрабатывает POST запрос для отказа от приглашения по email
+func (h *InviteHandler) DeclineInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.InviteTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	invite, err := h.inviteService.DeclineInvite(r.Context(), &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to decline invite")
+		return
+	}
+
+	utils.SendSuccess(w, invite)
+}
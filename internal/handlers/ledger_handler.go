@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"balance/internal/logging"
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+// maxImportSize - наибольший размер импортируемого файла
+const maxImportSize = 10 << 20 // 10MB
+
+// exportWriteTimeout - сколько может писаться выгрузка. Общий WriteTimeout
+// сервера рассчитан на обычные ответы и оборвал бы выгрузку большой группы
+const exportWriteTimeout = 10 * time.Minute
+
+type LedgerHandler struct {
+	ledgerService *service.LedgerService
+}
+
+func NewLedgerHandler(ledgerService *service.LedgerService) *LedgerHandler {
+	return &LedgerHandler{
+		ledgerService: ledgerService,
+	}
+}
+
+// ExportGroup обрабатывает GET запрос для выгрузки долгов группы в CSV.
+// Строки пишутся в ответ по мере чтения из базы
+func (h *LedgerHandler) ExportGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid group ID")
+		return
+	}
+
+	err = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logging.FromContext(r.Context()).Warn("Failed to extend export write deadline", "group_id", id, "error", err)
+	}
+
+	out := &lazyCSVWriter{w: w, filename: fmt.Sprintf("group-%d.csv", id)}
+	if err := h.ledgerService.ExportGroup(r.Context(), id, user.ID, out); err != nil {
+		if !out.started {
+			sendServiceError(w, err, "Failed to export group")
+			return
+		}
+		// Заголовки уже отправлены, сообщить клиенту об ошибке можно только обрывом ответа
+		logging.FromContext(r.Context()).Error("Failed to export group", "group_id", id, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// ImportGroup обрабатывает POST запрос с CSV в теле для импорта долгов в группу.
+// Импортирует пользователь запроса. Параметры query: user_id - необязательно, должен
+// совпадать с пользователем запроса; dry_run=true - только проверить файл
+func (h *LedgerHandler) ImportGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid group ID")
+		return
+	}
+
+	query := r.URL.Query()
+
+	var claimed int
+	if v := query.Get("user_id"); v != "" {
+		if claimed, err = strconv.Atoi(v); err != nil {
+			utils.SendBadRequest(w, "Invalid user ID")
+			return
+		}
+	}
+	userID, ok := actingUser(w, r, claimed)
+	if !ok {
+		return
+	}
+
+	var dryRun bool
+	if v := query.Get("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			utils.SendBadRequest(w, "Invalid dry_run value")
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	result, err := h.ledgerService.ImportGroup(r.Context(), id, userID, r.Body, dryRun)
+	if err != nil {
+		sendServiceError(w, err, "Failed to import debts")
+		return
+	}
+
+	// Файл с ошибками не импортируется: возвращаем отчет по строкам с кодом 422
+	if !dryRun && len(result.Errors) > 0 {
+		utils.SendJSON(w, http.StatusUnprocessableEntity, utils.Response{
+			Status: "error",
+			Error:  "Import has errors, nothing was imported",
+			Data:   result,
+		})
+		return
+	}
+
+	utils.SendSuccess(w, result)
+}
+
+// ImportSplitwise обрабатывает POST запрос для создания группы из экспорта Splitwise
+func (h *LedgerHandler) ImportSplitwise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var req models.SplitwiseImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actorID, ok := actingUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	req.UserID = actorID
+
+	result, err := h.ledgerService.ImportSplitwise(r.Context(), &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to import from Splitwise")
+		return
+	}
+
+	if !req.DryRun && len(result.Errors) > 0 {
+		utils.SendJSON(w, http.StatusUnprocessableEntity, utils.Response{
+			Status: "error",
+			Error:  "Import has errors, nothing was imported",
+			Data:   result,
+		})
+		return
+	}
+
+	if req.DryRun {
+		utils.SendSuccess(w, result)
+		return
+	}
+	utils.SendCreated(w, result)
+}
+
+// lazyCSVWriter выставляет заголовки CSV-ответа при первой записи, чтобы до нее
+// ошибку можно было отдать обычным JSON-ответом
+type lazyCSVWriter struct {
+	w        http.ResponseWriter
+	filename string
+	started  bool
+}
+
+func (l *lazyCSVWriter) Write(p []byte) (int, error) {
+	if !l.started {
+		l.started = true
+		l.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		l.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", l.filename))
+		l.w.WriteHeader(http.StatusOK)
+	}
+	return l.w.Write(p)
+}
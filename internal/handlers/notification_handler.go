@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// GetPreferences обрабатывает GET запрос для получения настроек уведомлений пользователя
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+	if !ownData(w, r, userID) {
+		return
+	}
+
+	preferences, err := h.notificationService.GetPreferences(r.Context(), userID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get notification preferences")
+		return
+	}
+
+	utils.SendSuccess(w, preferences)
+}
+
+// UpdatePreferences обрабатывает PUT запрос для изменения настроек уведомлений пользователя
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.UpdateNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	if _, ok := actingUser(w, r, userID); !ok {
+		return
+	}
+
+	preferences, err := h.notificationService.UpdatePreferences(r.Context(), userID, &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to update notification preferences")
+		return
+	}
+
+	utils.SendSuccess(w, preferences)
+}
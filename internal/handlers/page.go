@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"balance/internal/models"
+	"balance/pkg/utils"
+)
+
+// maxPageLimit - наибольший размер страницы списка
+const maxPageLimit = 1000
+
+// parsePage читает страницу списка из параметров limit и offset или отвечает 400.
+// Без limit возвращается весь список
+func parsePage(w http.ResponseWriter, r *http.Request) (models.Page, bool) {
+	var page models.Page
+	query := r.URL.Query()
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			utils.SendBadRequest(w, fmt.Sprintf("Invalid limit, use 1 to %d", maxPageLimit))
+			return page, false
+		}
+		page.Limit = limit
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			utils.SendBadRequest(w, "Invalid offset")
+			return page, false
+		}
+		page.Offset = offset
+	}
+
+	return page, true
+}
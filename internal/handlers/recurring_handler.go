@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+type RecurringHandler struct {
+	recurringService *service.RecurringService
+}
+
+func NewRecurringHandler(recurringService *service.RecurringService) *RecurringHandler {
+	return &RecurringHandler{
+		recurringService: recurringService,
+	}
+}
+
+// CreateSeries обрабатывает POST запрос для создания серии повторяющихся долгов
+func (h *RecurringHandler) CreateSeries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.CreateRecurringSeriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actorID, ok := actingUser(w, r, req.CreatedBy)
+	if !ok {
+		return
+	}
+	req.CreatedBy = actorID
+
+	series, err := h.recurringService.CreateSeries(r.Context(), &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to create recurring series")
+		return
+	}
+
+	utils.SendCreated(w, series)
+}
+
+// GetSeries обрабатывает GET запрос для получения серии по ID
+func (h *RecurringHandler) GetSeries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid series ID")
+		return
+	}
+
+	series, err := h.recurringService.GetSeries(r.Context(), id)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get recurring series")
+		return
+	}
+
+	utils.SendSuccess(w, series)
+}
+
+// GetAllSeries обрабатывает GET запрос для получения списка серий.
+// Поддерживает фильтры group_id и user_id в query
+func (h *RecurringHandler) GetAllSeries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+
+	var groupID, userID int
+	var err error
+	if v := query.Get("group_id"); v != "" {
+		if groupID, err = strconv.Atoi(v); err != nil {
+			utils.SendBadRequest(w, "Invalid group ID")
+			return
+		}
+	}
+	if v := query.Get("user_id"); v != "" {
+		if userID, err = strconv.Atoi(v); err != nil {
+			utils.SendBadRequest(w, "Invalid user ID")
+			return
+		}
+	}
+
+	series, err := h.recurringService.GetAllSeries(r.Context(), groupID, userID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get recurring series")
+		return
+	}
+
+	utils.SendSuccess(w, series)
+}
+
+// UpdateSeries обрабатывает PUT запрос для изменения серии
+func (h *RecurringHandler) UpdateSeries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid series ID")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.UpdateRecurringSeriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actorID, ok := actingUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	req.UserID = actorID
+
+	series, err := h.recurringService.UpdateSeries(r.Context(), id, &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to update recurring series")
+		return
+	}
+
+	utils.SendSuccess(w, series)
+}
+
+// SeriesAction возвращает обработчик POST запроса, который ставит серию на паузу,
+// возобновляет или завершает ее (action - pause, resume или end)
+func (h *RecurringHandler) SeriesAction(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			utils.SendBadRequest(w, "Invalid series ID")
+			return
+		}
+
+		// Ограничиваем размер тела запроса
+		r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+		var req models.SeriesActionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.SendBadRequest(w, "Invalid JSON format")
+			return
+		}
+
+		actorID, ok := actingUser(w, r, req.UserID)
+		if !ok {
+			return
+		}
+		req.UserID = actorID
+
+		series, err := h.recurringService.SetSeriesStatus(r.Context(), id, action, &req)
+		if err != nil {
+			sendServiceError(w, err, "Failed to update recurring series")
+			return
+		}
+
+		utils.SendSuccess(w, series)
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/internal/statement"
+	"balance/pkg/utils"
+)
+
+type StatementHandler struct {
+	statementService *service.StatementService
+}
+
+func NewStatementHandler(statementService *service.StatementService) *StatementHandler {
+	return &StatementHandler{
+		statementService: statementService,
+	}
+}
+
+// GetStatement обрабатывает GET запрос для получения выписки по группе.
+// Параметры query: format (html или pdf, по умолчанию html), from и to -
+// даты периода включительно (YYYY-MM-DD или RFC 3339), user_id - персональная выписка
+func (h *StatementHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid group ID")
+		return
+	}
+
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = "html"
+	}
+	if format != "html" && format != "pdf" {
+		utils.SendBadRequest(w, "Invalid format, use html or pdf")
+		return
+	}
+
+	var filter models.StatementFilter
+	if v := query.Get("user_id"); v != "" {
+		if filter.UserID, err = strconv.Atoi(v); err != nil {
+			utils.SendBadRequest(w, "Invalid user ID")
+			return
+		}
+	}
+	if v := query.Get("from"); v != "" {
+		from, _, err := parseStatementDate(v)
+		if err != nil {
+			utils.SendBadRequest(w, "Invalid from date")
+			return
+		}
+		filter.From = &from
+	}
+	if v := query.Get("to"); v != "" {
+		to, dateOnly, err := parseStatementDate(v)
+		if err != nil {
+			utils.SendBadRequest(w, "Invalid to date")
+			return
+		}
+		// Дата без времени включает весь день
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = &to
+	}
+
+	s, err := h.statementService.GetStatement(r.Context(), id, user.ID, filter)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get statement")
+		return
+	}
+
+	// Документ собирается целиком до отправки, чтобы ошибку отрисовки можно было вернуть как JSON
+	var buf bytes.Buffer
+	if format == "pdf" {
+		err = statement.RenderPDF(&buf, s)
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"statement-group-%d.pdf\"", id))
+	} else {
+		err = statement.RenderHTML(&buf, s)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	if err != nil {
+		w.Header().Del("Content-Disposition")
+		utils.SendInternalError(w, "Failed to render statement")
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
+
+// parseStatementDate разбирает дату YYYY-MM-DD или время RFC 3339.
+// dateOnly сообщает, что время не было указано
+func parseStatementDate(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	if t, err = time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), false, nil
+	}
+	return time.Time{}, false, err
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+// TwoFactorHandler управляет двухфакторной аутентификацией пользователя текущей сессии
+type TwoFactorHandler struct {
+	twoFactorService *service.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactorService *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+	}
+}
+
+// GetStatus обрабатывает GET запрос для получения состояния двухфакторной аутентификации
+func (h *TwoFactorHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.twoFactorService.GetStatus(r.Context(), user.ID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get two-factor status")
+		return
+	}
+
+	utils.SendSuccess(w, status)
+}
+
+// Enroll обрабатывает POST запрос для выпуска секрета TOTP
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.twoFactorService.Enroll(r.Context(), user.ID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to enroll two-factor authentication")
+		return
+	}
+
+	utils.SendSuccess(w, enrollment)
+}
+
+// Confirm обрабатывает POST запрос с кодом из приложения и включает двухфакторную аутентификацию
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	codes, err := h.twoFactorService.Confirm(r.Context(), user.ID, &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	utils.SendSuccess(w, codes)
+}
+
+// Disable обрабатывает POST запрос для отключения двухфакторной аутентификации
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.DisableTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	if err := h.twoFactorService.Disable(r.Context(), user.ID, &req); err != nil {
+		sendAuthError(w, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	utils.SendSuccess(w, map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes обрабатывает POST запрос для выпуска новых кодов восстановления
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(r.Context(), user.ID, &req)
+	if err != nil {
+		sendAuthError(w, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	utils.SendSuccess(w, codes)
+}
@@ -85,15 +85,20 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	utils.SendSuccess(w, user)
 }
 
-// GetAllUsers обрабатывает GET запрос для получения всех пользователей
+// GetAllUsers обрабатывает GET запрос для получения всех пользователей. limit и offset задают страницу
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
+	page, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+
 	// Получаем всех пользователей
-	users, err := h.userService.GetAllUsers(r.Context())
+	users, err := h.userService.GetAllUsers(r.Context(), page)
 	if err != nil {
 		utils.SendInternalError(w, "Failed to get users")
 		return
@@ -132,6 +137,10 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := actingUser(w, r, id); !ok {
+		return
+	}
+
 	// Обновляем пользователя
 	user, err := h.userService.UpdateUser(r.Context(), id, &req)
 	if err != nil {
@@ -170,6 +179,10 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := actingUser(w, r, id); !ok {
+		return
+	}
+
 	// Удаляем пользователя
 	if err := h.userService.DeleteUser(r.Context(), id); err != nil {
 		if err.Error() == "user not found" {
@@ -182,3 +195,127 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	utils.SendSuccess(w, map[string]string{"message": "User deleted successfully"})
 }
+
+// SendVerification обрабатывает POST запрос для повторной отправки письма с подтверждением email
+func (h *UserHandler) SendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid user ID")
+		return
+	}
+
+	if _, ok := actingUser(w, r, id); !ok {
+		return
+	}
+
+	if err := h.userService.SendVerification(r.Context(), id); err != nil {
+		sendServiceError(w, err, "Failed to send verification email")
+		return
+	}
+
+	utils.SendSuccess(w, map[string]string{"message": "Verification email sent"})
+}
+
+// VerifyEmail обрабатывает POST запрос для подтверждения email по токену из письма
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.TokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	user, err := h.userService.VerifyEmail(r.Context(), &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to verify email")
+		return
+	}
+
+	utils.SendSuccess(w, user)
+}
+
+// RequestPasswordReset обрабатывает POST запрос для отправки письма со сбросом пароля.
+// Ответ не зависит от того, зарегистрирован ли email
+func (h *UserHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(r.Context(), &req); err != nil {
+		sendServiceError(w, err, "Failed to request password reset")
+		return
+	}
+
+	utils.SendSuccess(w, map[string]string{"message": "If the email is registered, a password reset link has been sent"})
+}
+
+// ConfirmPasswordReset обрабатывает POST запрос для смены пароля по токену из письма
+func (h *UserHandler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.PasswordResetConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	if err := h.userService.ConfirmPasswordReset(r.Context(), &req); err != nil {
+		sendServiceError(w, err, "Failed to reset password")
+		return
+	}
+
+	utils.SendSuccess(w, map[string]string{"message": "Password has been reset"})
+}
+
+// Login обрабатывает POST запрос для входа по email и паролю
+func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	result, err := h.userService.Login(r.Context(), &req)
+	if err != nil {
+		sendAuthError(w, err, "Failed to log in")
+		return
+	}
+
+	utils.SendSuccess(w, result)
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"balance/internal/models"
+	"balance/internal/service"
+	"balance/pkg/utils"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook обрабатывает POST запрос для создания подписки на события
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Ограничиваем размер тела запроса
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB
+
+	var req models.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendBadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	actorID, ok := actingUser(w, r, req.CreatedBy)
+	if !ok {
+		return
+	}
+	req.CreatedBy = actorID
+
+	webhook, err := h.webhookService.CreateWebhook(r.Context(), &req)
+	if err != nil {
+		sendServiceError(w, err, "Failed to create webhook")
+		return
+	}
+
+	utils.SendCreated(w, webhook)
+}
+
+// GetWebhooks обрабатывает GET запрос для получения подписок.
+// Поддерживает фильтры group_id и user_id в query; без них возвращает
+// подписки пользователя запроса на его собственные события
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+
+	var groupID, userID int
+	var err error
+	if v := query.Get("group_id"); v != "" {
+		if groupID, err = strconv.Atoi(v); err != nil {
+			utils.SendBadRequest(w, "Invalid group ID")
+			return
+		}
+	}
+	if v := query.Get("user_id"); v != "" {
+		if userID, err = strconv.Atoi(v); err != nil {
+			utils.SendBadRequest(w, "Invalid user ID")
+			return
+		}
+	}
+
+	webhooks, err := h.webhookService.GetWebhooks(r.Context(), groupID, userID, user.ID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get webhooks")
+		return
+	}
+
+	utils.SendSuccess(w, webhooks)
+}
+
+// GetWebhook обрабатывает GET запрос для получения подписки по ID
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid webhook ID")
+		return
+	}
+
+	webhook, err := h.webhookService.GetWebhook(r.Context(), id, user.ID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get webhook")
+		return
+	}
+
+	utils.SendSuccess(w, webhook)
+}
+
+// DeleteWebhook обрабатывает DELETE запрос для удаления подписки
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid webhook ID")
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(r.Context(), id, user.ID); err != nil {
+		sendServiceError(w, err, "Failed to delete webhook")
+		return
+	}
+
+	utils.SendSuccess(w, map[string]string{"message": "Webhook deleted successfully"})
+}
+
+// GetDeliveries обрабатывает GET запрос для получения журнала доставок подписки
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid webhook ID")
+		return
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(r.Context(), id, user.ID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to get deliveries")
+		return
+	}
+
+	utils.SendSuccess(w, deliveries)
+}
+
+// Redeliver обрабатывает POST запрос для повторной отправки доставки
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.SendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid webhook ID")
+		return
+	}
+
+	deliveryID, err := strconv.Atoi(r.PathValue("deliveryId"))
+	if err != nil {
+		utils.SendBadRequest(w, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(r.Context(), id, deliveryID, user.ID)
+	if err != nil {
+		sendServiceError(w, err, "Failed to redeliver")
+		return
+	}
+
+	utils.SendCreated(w, delivery)
+}
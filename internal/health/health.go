@@ -0,0 +1,129 @@
+// Package health отвечает на проверки живости и готовности сервера для
+// оркестратора и балансировщика нагрузки
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"balance/internal/logging"
+	"balance/pkg/utils"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	// StatusShuttingDown - сервер останавливается и больше не принимает новый трафик
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check проверяет одну зависимость и возвращает ошибку, если она не работает
+type Check func(ctx context.Context) error
+
+// CheckResult - результат одной проверки
+type CheckResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Report - ответ /readyz
+type Report struct {
+	Status string                  `json:"status"`
+	Checks map[string]*CheckResult `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker выполняет проверки готовности
+type Checker struct {
+	checks       []namedCheck
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// New создает Checker. timeout ограничивает каждую проверку
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add регистрирует проверку готовности. Проверки нужно добавить до запуска сервера
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Shutdown переводит сервер в состояние "не готов": балансировщик перестает
+// направлять на него запросы, пока текущие дообрабатываются
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready выполняет все проверки параллельно
+func (c *Checker) Ready(ctx context.Context) *Report {
+	if c.shuttingDown.Load() {
+		return &Report{Status: StatusShuttingDown}
+	}
+
+	report := &Report{Status: StatusOK, Checks: make(map[string]*CheckResult, len(c.checks))}
+	results := make([]*CheckResult, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, nc.check)
+		}()
+	}
+	wg.Wait()
+
+	for i, nc := range c.checks {
+		report.Checks[nc.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+			logging.FromContext(ctx).Warn("Readiness check failed", "check", nc.name, "error", results[i].Error)
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) *CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := &CheckResult{Status: StatusOK, DurationMS: logging.DurationMS(time.Since(start))}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LiveHandler отвечает 200, пока процесс обслуживает запросы. Зависимости не
+// проверяются, иначе оркестратор перезапускал бы сервер при сбое базы
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utils.SendJSON(w, http.StatusOK, &Report{Status: StatusOK})
+	})
+}
+
+// ReadyHandler отвечает 200, если все проверки прошли, иначе 503 с результатом каждой проверки
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Ready(r.Context())
+
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		utils.SendJSON(w, status, report)
+	})
+}
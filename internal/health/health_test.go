@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serveReady(t *testing.T, c *Checker) (int, *Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	return rec.Code, &report
+}
+
+func TestReadyHandler(t *testing.T) {
+	c := New(time.Second)
+	c.Add("database", func(ctx context.Context) error { return nil })
+
+	code, report := serveReady(t, c)
+	if code != http.StatusOK || report.Status != StatusOK {
+		t.Errorf("got %d %s, want 200 %s", code, report.Status, StatusOK)
+	}
+	if report.Checks["database"] == nil || report.Checks["database"].Status != StatusOK {
+		t.Errorf("database check = %+v, want ok", report.Checks["database"])
+	}
+}
+
+func TestReadyHandlerFailedCheck(t *testing.T) {
+	c := New(time.Second)
+	c.Add("database", func(ctx context.Context) error { return nil })
+	c.Add("scheduler", func(ctx context.Context) error { return errors.New("scheduler is not running") })
+
+	code, report := serveReady(t, c)
+	if code != http.StatusServiceUnavailable || report.Status != StatusFail {
+		t.Errorf("got %d %s, want 503 %s", code, report.Status, StatusFail)
+	}
+	if got := report.Checks["scheduler"]; got == nil || got.Error != "scheduler is not running" {
+		t.Errorf("scheduler check = %+v, want the check error", got)
+	}
+}
+
+func TestReadyHandlerCheckTimeout(t *testing.T) {
+	c := New(10 * time.Millisecond)
+	c.Add("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	code, _ := serveReady(t, c)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("got %d, want 503", code)
+	}
+}
+
+// После Shutdown /readyz отвечает 503, даже если все проверки проходят, а /livez - 200
+func TestReadyHandlerAfterShutdown(t *testing.T) {
+	c := New(time.Second)
+	c.Add("database", func(ctx context.Context) error { return nil })
+	c.Shutdown()
+
+	code, report := serveReady(t, c)
+	if code != http.StatusServiceUnavailable || report.Status != StatusShuttingDown {
+		t.Errorf("got %d %s, want 503 %s", code, report.Status, StatusShuttingDown)
+	}
+
+	rec := httptest.NewRecorder()
+	c.LiveHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("livez got %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,219 @@
+// Package ledger читает и пишет журнал долгов группы в CSV.
+//
+// Одна строка файла - один долг. Расход, разделенный между несколькими
+// участниками, записывается несколькими строками с общими датой и описанием.
+// Колонки определяются по заголовку, их порядок не важен, лишние колонки
+// (например, id и имена из экспорта) при импорте игнорируются
+package ledger
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"balance/internal/models"
+)
+
+// Columns - колонки экспорта в порядке записи
+var Columns = []string{
+	"id", "date", "description", "amount",
+	"from_email", "from_name", "to_email", "to_name",
+	"status", "due_at", "settled_at",
+}
+
+// requiredColumns - колонки, без которых файл нельзя импортировать
+var requiredColumns = []string{"amount", "from_email", "to_email"}
+
+// maxAmount - верхняя граница суммы долга при импорте. Большие значения
+// теряют точность в копейках и почти наверняка означают ошибку в файле
+const maxAmount = 1e8
+
+// dateLayouts - форматы дат, которые принимаются при импорте
+var dateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// Writer пишет долги в CSV построчно, не накапливая их в памяти
+type Writer struct {
+	w *csv.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: csv.NewWriter(w)}
+}
+
+func (w *Writer) WriteHeader() error {
+	return w.w.Write(Columns)
+}
+
+// Write пишет одну строку. Имена и email сторон берутся из debt.FromUser и debt.ToUser
+func (w *Writer) Write(debt *models.Debt) error {
+	var fromEmail, fromName, toEmail, toName string
+	if debt.FromUser != nil {
+		fromEmail, fromName = debt.FromUser.Email, debt.FromUser.Name
+	}
+	if debt.ToUser != nil {
+		toEmail, toName = debt.ToUser.Email, debt.ToUser.Name
+	}
+
+	return w.w.Write([]string{
+		strconv.Itoa(debt.ID),
+		debt.CreatedAt.UTC().Format(time.RFC3339),
+		escapeFormula(debt.Description),
+		strconv.FormatFloat(debt.Amount, 'f', 2, 64),
+		escapeFormula(fromEmail), escapeFormula(fromName),
+		escapeFormula(toEmail), escapeFormula(toName),
+		debt.Status,
+		formatTime(debt.DueAt),
+		formatTime(debt.SettledAt),
+	})
+}
+
+// escapeFormula экранирует текст, который табличный редактор принял бы за формулу:
+// описания и имена задают пользователи, и открытая выгрузка не должна ничего вычислять
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// Flush дописывает буфер в нижележащий writer и возвращает ошибку записи, если она была
+func (w *Writer) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// Row - разобранная строка импорта. Пустые необязательные поля остаются нулевыми
+type Row struct {
+	Line        int
+	Date        *time.Time
+	Description string
+	Amount      float64
+	FromEmail   string
+	ToEmail     string
+	Status      string
+	DueAt       *time.Time
+	SettledAt   *time.Time
+}
+
+// Read разбирает CSV целиком. Ошибки в отдельных строках возвращаются списком
+// rowErrors, а такие строки в rows не попадают. err возвращается, если файл
+// нельзя прочитать как CSV или в заголовке нет обязательных колонок
+func Read(r io.Reader) (rows []*Row, rowErrors []models.ImportRowError, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, fmt.Errorf("file is empty")
+		}
+		return nil, nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := index[name]; !ok {
+			return nil, nil, fmt.Errorf("column %s is required", name)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row, err := parseRow(line, field)
+		if err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: line, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors, nil
+}
+
+func parseRow(line int, field func(name string) string) (*Row, error) {
+	row := &Row{
+		Line:        line,
+		Description: field("description"),
+		FromEmail:   strings.ToLower(field("from_email")),
+		ToEmail:     strings.ToLower(field("to_email")),
+		Status:      strings.ToLower(field("status")),
+	}
+
+	amount := field("amount")
+	if amount == "" {
+		return nil, fmt.Errorf("amount is required")
+	}
+	var err error
+	if row.Amount, err = strconv.ParseFloat(amount, 64); err != nil || math.IsNaN(row.Amount) || math.IsInf(row.Amount, 0) {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+	if row.Amount >= maxAmount {
+		return nil, fmt.Errorf("amount must be less than %.0f", maxAmount)
+	}
+
+	if row.FromEmail == "" || row.ToEmail == "" {
+		return nil, fmt.Errorf("from_email and to_email are required")
+	}
+
+	for _, f := range []struct {
+		name string
+		dest **time.Time
+	}{
+		{"date", &row.Date},
+		{"due_at", &row.DueAt},
+		{"settled_at", &row.SettledAt},
+	} {
+		value := field(f.name)
+		if value == "" {
+			continue
+		}
+		t, err := parseTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", f.name, value)
+		}
+		*f.dest = &t
+	}
+
+	return row, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown date format")
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
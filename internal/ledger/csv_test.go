@@ -0,0 +1,73 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"balance/internal/models"
+)
+
+func TestReadRejectsInvalidAmounts(t *testing.T) {
+	file := "amount,from_email,to_email\n" +
+		"12.50,a@example.com,b@example.com\n" +
+		"NaN,a@example.com,b@example.com\n" +
+		"+Inf,a@example.com,b@example.com\n" +
+		"-inf,a@example.com,b@example.com\n" +
+		"1e8,a@example.com,b@example.com\n" +
+		"1e300,a@example.com,b@example.com\n" +
+		"abc,a@example.com,b@example.com\n"
+
+	rows, rowErrors, err := Read(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Amount != 12.5 {
+		t.Fatalf("got rows %+v, want one row with amount 12.5", rows)
+	}
+	if len(rowErrors) != 6 {
+		t.Fatalf("got %d row errors, want 6: %+v", len(rowErrors), rowErrors)
+	}
+	for i, rowError := range rowErrors {
+		if want := i + 3; rowError.Row != want {
+			t.Errorf("error %d is for row %d, want %d", i, rowError.Row, want)
+		}
+	}
+}
+
+func TestWriteEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	err := w.Write(&models.Debt{
+		ID:          1,
+		Amount:      -10,
+		Description: "=HYPERLINK(\"https://evil.example.com\")",
+		Status:      models.DebtStatusActive,
+		FromUser:    &models.User{Email: "a@example.com", Name: "+Alice"},
+		ToUser:      &models.User{Email: "b@example.com", Name: "@Bob"},
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	record, err := csv.NewReader(&buf).Read()
+	if err != nil {
+		t.Fatalf("failed to read written row: %v", err)
+	}
+	want := map[int]string{
+		2: `'=HYPERLINK("https://evil.example.com")`,
+		3: "-10.00", // суммы пишет сервер, их не экранируем
+		4: "a@example.com",
+		5: "'+Alice",
+		7: "'@Bob",
+	}
+	for i, v := range want {
+		if record[i] != v {
+			t.Errorf("column %s = %q, want %q", Columns[i], record[i], v)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Package logging настраивает структурированные логи log/slog и передает
+// логгер запроса через context в обработчики, сервисы и репозитории
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+type loggerKey struct{}
+
+// New создает логгер. format - json или text, level - debug, info, warn или error
+func New(w io.Writer, format, level string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level)}
+	if strings.EqualFold(format, "text") {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// ParseLevel разбирает уровень логов. Неизвестный уровень считается info
+func ParseLevel(level string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+// DurationMS переводит длительность в миллисекунды для атрибутов лога
+func DurationMS(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// WithLogger кладет логгер в контекст
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext возвращает логгер из контекста, а если его нет - slog.Default()
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With добавляет атрибуты к логгеру из контекста. В запросе они попадают и в журнал доступа
+func With(ctx context.Context, args ...any) context.Context {
+	if e, ok := ctx.Value(entryKey{}).(*entry); ok {
+		e.add(args...)
+	}
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
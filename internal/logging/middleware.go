@@ -0,0 +1,131 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestIDHeader - заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+type entryKey struct{}
+
+// entry - атрибуты журнала доступа, которые добавляют обработчики внутри запроса
+type entry struct {
+	mu   sync.Mutex
+	args []any
+}
+
+func (e *entry) add(args ...any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.args = append(e.args, args...)
+}
+
+func (e *entry) attrs() []any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]any(nil), e.args...)
+}
+
+// RequestID возвращает идентификатор текущего запроса
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Middleware присваивает запросу идентификатор, кладет в контекст логгер с ним и
+// после ответа пишет журнал доступа: метод, путь, статус, время и пользователь.
+// Идентификатор берется из X-Request-ID, если клиент или прокси его передали,
+// и возвращается в том же заголовке ответа
+func Middleware(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		e := &entry{}
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		ctx = context.WithValue(ctx, entryKey{}, e)
+		ctx = WithLogger(ctx, logger.With("request_id", id))
+
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			level := slog.LevelInfo
+			if rw.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			args := []any{
+				"request_id", id,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", rw.status,
+				"bytes", rw.bytes,
+				"duration_ms", DurationMS(time.Since(start)),
+				"remote_addr", r.RemoteAddr,
+			}
+			logger.Log(ctx, level, "HTTP request", append(args, e.attrs()...)...)
+		}()
+
+		next.ServeHTTP(rw, r.WithContext(ctx))
+	})
+}
+
+// responseWriter запоминает статус и размер ответа
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap нужен http.ResponseController, чтобы добраться до Flush и таймаутов
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID принимает только короткие идентификаторы из безопасных символов,
+// чтобы клиент не мог подделать строки лога
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == ':') {
+			return false
+		}
+	}
+	return true
+}
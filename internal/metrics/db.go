@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type queryStartKey struct{}
+
+// DBMetrics измеряет запросы к базе. Реализует pgx.QueryTracer
+type DBMetrics struct {
+	duration *HistogramVec
+}
+
+// NewDBMetrics создает метрики запросов к базе и регистрирует их в registry
+func NewDBMetrics(registry *Registry) *DBMetrics {
+	m := &DBMetrics{
+		duration: NewHistogramVec("balance_db_query_duration_seconds",
+			"Database query latency by operation and result.", DefaultBuckets, "operation", "result"),
+	}
+	registry.Register(m.duration)
+	return m
+}
+
+func (m *DBMetrics) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{operation: operation(data.SQL), start: time.Now()})
+}
+
+func (m *DBMetrics) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	result := "ok"
+	if data.Err != nil && data.Err != pgx.ErrNoRows {
+		result = "error"
+	}
+	m.duration.Observe(time.Since(q.start).Seconds(), q.operation, result)
+}
+
+type queryStart struct {
+	operation string
+	start     time.Time
+}
+
+// operation - первое слово запроса: select, insert, update, delete и т.д.
+func operation(sql string) string {
+	word := strings.TrimLeftFunc(sql, unicode.IsSpace)
+	if i := strings.IndexFunc(word, unicode.IsSpace); i >= 0 {
+		word = word[:i]
+	}
+	word = strings.ToLower(word)
+	switch word {
+	case "select", "insert", "update", "delete", "with", "begin", "commit", "rollback",
+		"create", "alter", "drop":
+		return word
+	}
+	return "other"
+}
+
+// PoolStats отдает состояние пула соединений
+func PoolStats(db *pgxpool.Pool) Collector {
+	return CollectorFunc(func(ctx context.Context, e *Encoder) error {
+		s := db.Stat()
+		e.Gauge("balance_db_pool_acquired_conns", "Connections currently in use.", float64(s.AcquiredConns()))
+		e.Gauge("balance_db_pool_idle_conns", "Idle connections in the pool.", float64(s.IdleConns()))
+		e.Gauge("balance_db_pool_total_conns", "Open connections in the pool.", float64(s.TotalConns()))
+		e.Gauge("balance_db_pool_max_conns", "Maximum size of the pool.", float64(s.MaxConns()))
+		e.Counter("balance_db_pool_acquires_total", "Successful connection acquires.", float64(s.AcquireCount()))
+		e.Counter("balance_db_pool_empty_acquires_total", "Acquires that had to wait for a connection.", float64(s.EmptyAcquireCount()))
+		e.Counter("balance_db_pool_canceled_acquires_total", "Acquires canceled by the context.", float64(s.CanceledAcquireCount()))
+		e.Counter("balance_db_pool_acquire_duration_seconds_total", "Total time spent acquiring connections.", s.AcquireDuration().Seconds())
+		return nil
+	})
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type routeKey struct{}
+
+// route - шаблон маршрута, который обработал запрос. Его заполняет самый
+// внутренний ServeMux, поэтому он передается через контекст по ссылке
+type route struct {
+	pattern string
+}
+
+// HTTPMetrics считает HTTP запросы и их длительность по маршрутам и статусам.
+// Маршрут - шаблон ServeMux, например /api/v1/debts/{id}, а не сам путь, чтобы
+// число серий не росло с числом объектов
+type HTTPMetrics struct {
+	requests *CounterVec
+	duration *HistogramVec
+	inFlight atomic.Int64
+}
+
+// NewHTTPMetrics создает метрики HTTP и регистрирует их в registry
+func NewHTTPMetrics(registry *Registry) *HTTPMetrics {
+	m := &HTTPMetrics{
+		requests: NewCounterVec("balance_http_requests_total",
+			"HTTP requests by method, route and status.", "method", "route", "status"),
+		duration: NewHistogramVec("balance_http_request_duration_seconds",
+			"HTTP request latency by method, route and status.", DefaultBuckets, "method", "route", "status"),
+	}
+	registry.Register(m.requests, m.duration, CollectorFunc(func(ctx context.Context, e *Encoder) error {
+		e.Gauge("balance_http_requests_in_flight", "HTTP requests being served.", float64(m.inFlight.Load()))
+		return nil
+	}))
+	return m
+}
+
+// Middleware считает запросы к next
+func (m *HTTPMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		m.inFlight.Add(1)
+		defer m.inFlight.Add(-1)
+
+		rt := &route{}
+		r = r.WithContext(context.WithValue(r.Context(), routeKey{}, rt))
+		rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		defer func() {
+			if rt.pattern == "" {
+				rt.pattern = patternPath(r.Pattern)
+			}
+			if rt.pattern == "" {
+				rt.pattern = "unmatched"
+			}
+			method, status := normalizeMethod(r.Method), strconv.Itoa(rw.status)
+			m.requests.Inc(method, rt.pattern, status)
+			m.duration.Observe(time.Since(start).Seconds(), method, rt.pattern, status)
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// Routes запоминает шаблон маршрута, который выбрал mux. prefix - путь, который
+// срезал http.StripPrefix перед mux
+func Routes(prefix string, mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+
+		// ServeMux записывает выбранный шаблон в r.Pattern
+		if rt, ok := r.Context().Value(routeKey{}).(*route); ok && rt.pattern == "" && r.Pattern != "" {
+			rt.pattern = prefix + patternPath(r.Pattern)
+		}
+	})
+}
+
+// Route возвращает шаблон маршрута текущего запроса. Заполняется, когда mux
+// внутри Routes уже выбрал обработчик
+func Route(ctx context.Context) string {
+	if rt, ok := ctx.Value(routeKey{}).(*route); ok {
+		return rt.pattern
+	}
+	return ""
+}
+
+// patternPath убирает метод из шаблона "GET /debts/{id}"
+func patternPath(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return strings.TrimSpace(path)
+	}
+	return pattern
+}
+
+// normalizeMethod ограничивает метку method стандартными методами
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
+
+// statusWriter запоминает статус ответа
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,147 @@
+// Package metrics собирает метрики сервера и отдает их в текстовом формате Prometheus
+// (https://prometheus.io/docs/instrumenting/exposition_formats/)
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"balance/internal/logging"
+)
+
+// contentType - версия текстового формата Prometheus
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collector пишет свои метрики при каждом запросе /metrics
+type Collector interface {
+	Collect(ctx context.Context, e *Encoder) error
+}
+
+// CollectorFunc - Collector из функции
+type CollectorFunc func(ctx context.Context, e *Encoder) error
+
+func (f CollectorFunc) Collect(ctx context.Context, e *Encoder) error {
+	return f(ctx, e)
+}
+
+// Registry - набор метрик сервера
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register добавляет метрики в выдачу /metrics
+func (r *Registry) Register(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// Handler отдает все метрики. Если сборщик вернул ошибку, его метрики
+// пропускаются, а остальные отдаются
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		collectors := append([]Collector(nil), r.collectors...)
+		r.mu.Unlock()
+
+		var out bytes.Buffer
+		for _, c := range collectors {
+			e := &Encoder{}
+			if err := c.Collect(req.Context(), e); err != nil {
+				logging.FromContext(req.Context()).Error("Failed to collect metrics", "error", err)
+				continue
+			}
+			out.Write(e.buf.Bytes())
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Write(out.Bytes())
+	})
+}
+
+// Encoder пишет метрики в текстовом формате Prometheus
+type Encoder struct {
+	buf bytes.Buffer
+}
+
+// Family начинает метрику: строки HELP и TYPE. typ - counter, gauge или histogram
+func (e *Encoder) Family(name, help, typ string) {
+	e.buf.WriteString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	e.buf.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// Sample пишет одно значение. labels - пары имя, значение
+func (e *Encoder) Sample(name string, value float64, labels ...string) {
+	e.buf.WriteString(name)
+	if len(labels) > 0 {
+		e.buf.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.buf.WriteString(labels[i] + `="` + escapeLabel(labels[i+1]) + `"`)
+		}
+		e.buf.WriteByte('}')
+	}
+	e.buf.WriteByte(' ')
+	e.buf.WriteString(formatValue(value))
+	e.buf.WriteByte('\n')
+}
+
+// Gauge пишет метрику из одного значения без меток
+func (e *Encoder) Gauge(name, help string, value float64) {
+	e.Family(name, help, "gauge")
+	e.Sample(name, value)
+}
+
+// Counter пишет счетчик из одного значения без меток
+func (e *Encoder) Counter(name, help string, value float64) {
+	e.Family(name, help, "counter")
+	e.Sample(name, value)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// sortedKeys возвращает ключи серий в постоянном порядке, чтобы выдача не прыгала
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets - границы гистограммы длительности в секундах, как в клиентах Prometheus
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// labelSeparator разделяет значения меток в ключе серии
+const labelSeparator = "\xff"
+
+// CounterVec - счетчики с метками
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{name: name, help: help, labels: labels, values: make(map[string]float64)}
+}
+
+// Add увеличивает счетчик серии с значениями меток values в порядке labels
+func (c *CounterVec) Add(v float64, values ...string) {
+	key := strings.Join(values, labelSeparator)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+// Inc увеличивает счетчик на 1
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *CounterVec) Collect(ctx context.Context, e *Encoder) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e.Family(c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		e.Sample(c.name, c.values[key], pairs(c.labels, key)...)
+	}
+	return nil
+}
+
+// HistogramVec - гистограммы с метками
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // counts[i] - значения не больше buckets[i], без накопления
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec создает гистограммы. buckets - верхние границы по возрастанию, без +Inf
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogram)}
+}
+
+// Observe добавляет значение в серию с значениями меток values в порядке labels
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	key := strings.Join(values, labelSeparator)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *HistogramVec) Collect(ctx context.Context, e *Encoder) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	e.Family(h.name, h.help, "histogram")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		labels := pairs(h.labels, key)
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			e.Sample(h.name+"_bucket", float64(cumulative), append(labels, "le", formatValue(upper))...)
+		}
+		e.Sample(h.name+"_bucket", float64(s.count), append(labels, "le", "+Inf")...)
+		e.Sample(h.name+"_sum", s.sum, labels...)
+		e.Sample(h.name+"_count", float64(s.count), labels...)
+	}
+	return nil
+}
+
+// pairs собирает пары имя, значение из имен меток и ключа серии
+func pairs(labels []string, key string) []string {
+	if len(labels) == 0 {
+		return nil
+	}
+	values := strings.Split(key, labelSeparator)
+	result := make([]string, 0, 2*len(labels))
+	for i, label := range labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		result = append(result, label, value)
+	}
+	return result
+}
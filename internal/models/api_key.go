@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// Права персональных API ключей. Каждое следующее включает предыдущие:
+// admin - полный доступ от имени владельца ключа
+const (
+	ScopeRead       = "read"
+	ScopeWriteDebts = "write:debts"
+	ScopeAdmin      = "admin"
+)
+
+// APIKeyPrefix - начало каждого API ключа, по нему ключ отличается от токена сессии
+const APIKeyPrefix = "bal_"
+
+// APIKey - персональный API ключ для скриптов и ботов. В базе хранится только хеш ключа
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`        // Начало ключа, чтобы его можно было узнать в списке
+	Key        string     `json:"key,omitempty"` // Возвращается только при создании
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateAPIKeyRequest создает API ключ для пользователя текущей сессии
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+}
+
+// HasScope сообщает, что ключ дает право scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+		if s == ScopeWriteDebts && scope == ScopeRead {
+			return true
+		}
+	}
+	return false
+}
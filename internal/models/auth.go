@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+)
+
+// Session - результат входа. Token передается в заголовке Authorization: Bearer <token>,
+// в базе хранится только его хеш
+type Session struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      *User     `json:"user"`
+}
+
+// OIDCLogin - начало входа через провайдера OpenID Connect. Клиент открывает
+// AuthorizationURL и после возврата на redirect URL передает code и state в OIDCCallbackRequest
+type OIDCLogin struct {
+	AuthorizationURL string    `json:"authorization_url"`
+	State            string    `json:"state"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// OIDCCallbackRequest - параметры, с которыми провайдер вернул пользователя на redirect URL
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+}
+
+// OIDCIdentity - пользователь провайдера по проверенному ID токену
+type OIDCIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// LoginRequest - вход по email и паролю
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// LoginResult - ответ на вход. Если у пользователя включена двухфакторная
+// аутентификация, сессия выдается только после кода: TwoFactorRequired равно true,
+// а ChallengeToken передается в TwoFactorLoginRequest
+type LoginResult struct {
+	Session           *Session   `json:"session,omitempty"`
+	TwoFactorRequired bool       `json:"two_factor_required"`
+	ChallengeToken    string     `json:"challenge_token,omitempty"`
+	ChallengeExpires  *time.Time `json:"challenge_expires_at,omitempty"`
+}
+
+// TwoFactorLoginRequest - второй шаг входа: код из приложения или код восстановления
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+// TwoFactorEnrollment - новый секрет TOTP. Двухфакторная аутентификация
+// включается после подтверждения кодом из приложения
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"` // Для QR-кода
+}
+
+// TwoFactorCodeRequest - код из приложения-аутентификатора
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// TwoFactorRecoveryCodes - одноразовые коды восстановления. Показываются один раз,
+// в базе хранятся только их хеши
+type TwoFactorRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// DisableTwoFactorRequest - отключение двухфакторной аутентификации. Нужен пароль,
+// если он задан, и код из приложения или код восстановления
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// TwoFactorStatus - включена ли двухфакторная аутентификация
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
@@ -4,14 +4,27 @@ import (
 	"time"
 )
 
+// Статусы долга. Допустимые переходы между ними описаны в service.DebtService
+const (
+	DebtStatusProposed  = "proposed"  // Долг создан, ждет подтверждения должника
+	DebtStatusActive    = "active"    // Должник подтвердил долг
+	DebtStatusSettled   = "settled"   // Долг погашен
+	DebtStatusCancelled = "cancelled" // Долг отклонен или отменен
+	DebtStatusDisputed  = "disputed"  // Должник оспаривает долг
+)
+
 type Debt struct {
 	ID          int        `json:"id"`
-	GroupID     int        `json:"group_id"`
+	GroupID     int        `json:"group_id,omitempty"` // 0 - прямой долг между друзьями, без группы
 	FromUserID  int        `json:"from_user_id"`
 	ToUserID    int        `json:"to_user_id"`
 	Amount      float64    `json:"amount"`
 	Description string     `json:"description"`
 	Status      string     `json:"status"`
+	StatusNote  string     `json:"status_note,omitempty"`
+	CreatedBy   int        `json:"created_by"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	SeriesID    *int       `json:"series_id,omitempty"` // Серия, по которой создан повторяющийся долг
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	SettledAt   *time.Time `json:"settled_at,omitempty"`
@@ -23,17 +36,35 @@ type Debt struct {
 }
 
 type CreateDebtRequest struct {
-	GroupID     int     `json:"group_id" validate:"required"`
-	FromUserID  int     `json:"from_user_id" validate:"required"`
-	ToUserID    int     `json:"to_user_id" validate:"required"`
-	Amount      float64 `json:"amount" validate:"required,gt=0"`
-	Description string  `json:"description" validate:"max=500"`
+	GroupID     int        `json:"group_id,omitempty"` // Необязателен: без группы долг прямой, стороны должны быть друзьями
+	FromUserID  int        `json:"from_user_id" validate:"required"`
+	ToUserID    int        `json:"to_user_id" validate:"required"`
+	Amount      float64    `json:"amount" validate:"required,gt=0"`
+	Description string     `json:"description" validate:"max=500"`
+	CreatedBy   int        `json:"created_by,omitempty"` // Необязателен: по умолчанию пользователь запроса
+	DueAt       *time.Time `json:"due_at,omitempty"`
 }
 
 type UpdateDebtRequest struct {
 	Amount      *float64 `json:"amount,omitempty" validate:"omitempty,gt=0"`
 	Description *string  `json:"description,omitempty" validate:"omitempty,max=500"`
-	Status      *string  `json:"status,omitempty" validate:"omitempty,oneof=active settled cancelled"`
+	Status      *string  `json:"status,omitempty" validate:"omitempty,oneof=proposed active settled cancelled disputed"`
+}
+
+// DebtActionRequest - тело запроса на смену статуса долга (confirm, reject, dispute, settle, cancel)
+type DebtActionRequest struct {
+	UserID int    `json:"user_id,omitempty"` // Необязателен: по умолчанию пользователь запроса
+	Note   string `json:"note" validate:"max=500"`
+}
+
+// DebtFilter задает необязательные фильтры для списка долгов
+type DebtFilter struct {
+	GroupID int
+	UserID  int
+	Status  string
+	From    *time.Time // Созданы не раньше From
+	To      *time.Time // Созданы раньше To
+	Page    Page
 }
 
 type DebtSummary struct {
@@ -43,3 +74,30 @@ type DebtSummary struct {
 	TotalOwedTo float64 `json:"total_owed_to"` // Сколько должны пользователю
 	NetBalance  float64 `json:"net_balance"`   // Чистый баланс (положительный = должны ему, отрицательный = он должен)
 }
+
+// Settlement - один перевод в плане расчета: FromUserID платит ToUserID сумму Amount
+type Settlement struct {
+	FromUserID   int     `json:"from_user_id"`
+	FromUserName string  `json:"from_user_name"`
+	ToUserID     int     `json:"to_user_id"`
+	ToUserName   string  `json:"to_user_name"`
+	Amount       float64 `json:"amount"`
+}
+
+// GroupBalance - баланс пользователя с другим пользователем в одной группе
+type GroupBalance struct {
+	GroupID   int     `json:"group_id"`
+	GroupName string  `json:"group_name"`
+	Balance   float64 `json:"balance"` // Положительный - должны пользователю, отрицательный - должен он
+}
+
+// UserBalance - чистый баланс пользователя с другим пользователем по всем общим группам
+// и прямым долгам
+type UserBalance struct {
+	UserID        int            `json:"user_id"`
+	OtherUserID   int            `json:"other_user_id"`
+	OtherUserName string         `json:"other_user_name"`
+	Balance       float64        `json:"balance"` // Положительный - должны пользователю, отрицательный - должен он
+	Direct        float64        `json:"direct"`  // Баланс по прямым долгам, без группы
+	Groups        []GroupBalance `json:"groups"`
+}
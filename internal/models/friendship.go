@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Статусы дружбы
+const (
+	FriendshipStatusPending  = "pending"  // Запрос отправлен, ждет ответа
+	FriendshipStatusAccepted = "accepted" // Пользователи - друзья
+)
+
+// Friendship - дружба пользователя UserID с пользователем FriendID, с точки зрения UserID
+type Friendship struct {
+	UserID      int        `json:"user_id"`
+	FriendID    int        `json:"friend_id"`
+	Status      string     `json:"status"`
+	RequestedBy int        `json:"requested_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+
+	// Дополнительные поля для API
+	Friend *User `json:"friend,omitempty"`
+}
+
+type AddFriendRequest struct {
+	FriendID int `json:"friend_id" validate:"required"`
+}
@@ -21,7 +21,7 @@ type Group struct {
 type CreateGroupRequest struct {
 	Name        string `json:"name" validate:"required,min=2,max=100"`
 	Description string `json:"description" validate:"max=500"`
-	CreatedBy   int    `json:"created_by" validate:"required"`
+	CreatedBy   int    `json:"created_by,omitempty"` // Необязателен: по умолчанию пользователь запроса
 }
 
 type UpdateGroupRequest struct {
@@ -41,3 +41,32 @@ type GroupSummary struct {
 	SettledDebts int     `json:"settled_debts"`
 	MemberCount  int     `json:"member_count"`
 }
+
+// GroupMember - участие пользователя в группе
+type GroupMember struct {
+	GroupID  int       `json:"group_id"`
+	UserID   int       `json:"user_id"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// Statement - выписка по группе за период: сводка, балансы участников,
+// план расчета и список долгов. Если UserID не ноль, выписка персональная:
+// балансы, план и долги ограничены этим пользователем
+type Statement struct {
+	Group        *Group        `json:"group"`
+	UserID       int           `json:"user_id,omitempty"`
+	From         *time.Time    `json:"from,omitempty"`
+	To           *time.Time    `json:"to,omitempty"`
+	GeneratedAt  time.Time     `json:"generated_at"`
+	Summary      *GroupSummary `json:"summary"`
+	Members      []DebtSummary `json:"members"`
+	SettlePlan   []Settlement  `json:"settle_plan"`
+	Transactions []*Debt       `json:"transactions"`
+}
+
+// StatementFilter - параметры выписки
+type StatementFilter struct {
+	UserID int
+	From   *time.Time
+	To     *time.Time
+}
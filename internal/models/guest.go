@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// CreateGuestRequest добавляет в группу гостя - участника без аккаунта и email
+type CreateGuestRequest struct {
+	Name      string `json:"name" validate:"required,min=2,max=100"`
+	CreatedBy int    `json:"created_by,omitempty"` // Участник группы, который добавляет гостя; по умолчанию пользователь запроса
+}
+
+// CreateGuestClaimRequest создает приглашение забрать гостя. Если Email задан,
+// ссылка уходит письмом, иначе ее передают сами
+type CreateGuestClaimRequest struct {
+	CreatedBy int    `json:"created_by,omitempty"` // Необязателен: по умолчанию пользователь запроса
+	Email     string `json:"email,omitempty" validate:"omitempty,email"`
+}
+
+// GuestClaim - приглашение забрать гостя. В базе хранится только хеш токена
+type GuestClaim struct {
+	ID        int       `json:"id"`
+	GuestID   int       `json:"guest_id"`
+	Email     string    `json:"email,omitempty"`
+	CreatedBy int       `json:"created_by"`
+	Token     string    `json:"token,omitempty"` // Возвращается только при создании
+	URL       string    `json:"url,omitempty"`   // Ссылка с токеном, возвращается только при создании
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ClaimGuestRequest - пользователь забирает гостя по токену из приглашения
+type ClaimGuestRequest struct {
+	Token  string `json:"token" validate:"required"`
+	UserID int    `json:"user_id,omitempty"` // Необязателен: по умолчанию пользователь запроса
+}
+
+// GuestClaimResult - что перешло от гостя к пользователю
+type GuestClaimResult struct {
+	UserID          int `json:"user_id"`
+	GuestID         int `json:"guest_id"`
+	Groups          int `json:"groups"`
+	Debts           int `json:"debts"`
+	RecurringSeries int `json:"recurring_series"`
+}
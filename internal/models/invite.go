@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// Статусы приглашения в группу
+const (
+	InviteStatusActive   = "active"
+	InviteStatusDeclined = "declined" // Приглашенный по email отказался
+	InviteStatusRevoked  = "revoked"  // Отозвано создателем приглашения или группы
+)
+
+// GroupInvite - приглашение в группу по email или ссылке. В базе хранится
+// только хеш токена. Приглашение по email одноразовое, ссылку можно
+// использовать до MaxUses раз
+type GroupInvite struct {
+	ID        int        `json:"id"`
+	GroupID   int        `json:"group_id"`
+	Email     string     `json:"email,omitempty"` // Пустой у ссылки
+	CreatedBy int        `json:"created_by"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	Status    string     `json:"status"`
+	Token     string     `json:"token,omitempty"` // Возвращается только при создании
+	URL       string     `json:"url,omitempty"`   // Ссылка с токеном, возвращается только при создании
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateInviteRequest создает приглашение. С Email приглашение уходит письмом,
+// без него создается ссылка на MaxUses вступлений (по умолчанию одно)
+type CreateInviteRequest struct {
+	CreatedBy int        `json:"created_by,omitempty"` // Необязателен: по умолчанию пользователь запроса
+	Email     string     `json:"email,omitempty" validate:"omitempty,email"`
+	MaxUses   int        `json:"max_uses,omitempty" validate:"omitempty,min=1,max=1000"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // По умолчанию - через INVITE_TTL
+}
+
+// InviteTokenRequest - ответ на приглашение по токену из письма или ссылки
+type InviteTokenRequest struct {
+	Token  string `json:"token" validate:"required"`
+	UserID int    `json:"user_id,omitempty"` // Кто принимает, по умолчанию пользователь запроса; для отказа не нужен
+}
+
+// RevokeInviteRequest - кто отзывает приглашение
+type RevokeInviteRequest struct {
+	UserID int `json:"user_id,omitempty"` // Необязателен: по умолчанию пользователь запроса
+}
@@ -0,0 +1,73 @@
+package models
+
+// ImportRowError - ошибка в одной строке импортируемого файла
+type ImportRowError struct {
+	Row   int    `json:"row"` // Номер строки в файле, заголовок - строка 1
+	Error string `json:"error"`
+}
+
+// ImportResult - итог импорта журнала группы
+type ImportResult struct {
+	DryRun   bool             `json:"dry_run"`
+	Rows     int              `json:"rows"`     // Сколько строк с данными прочитано
+	Imported int              `json:"imported"` // Сколько долгов записано (0 при dry-run или ошибках)
+	Errors   []ImportRowError `json:"errors,omitempty"`
+}
+
+// Как участник импорта из Splitwise сопоставлен с пользователем
+const (
+	ParticipantExisting    = "existing"    // Найден пользователь с указанным email
+	ParticipantCreated     = "created"     // Создан пользователь с указанным email
+	ParticipantPlaceholder = "placeholder" // Email не указан, создан гость
+)
+
+// SplitwiseImportRequest - импорт группы из CSV-экспорта Splitwise
+type SplitwiseImportRequest struct {
+	UserID    int               `json:"user_id,omitempty"` // Кто импортирует, становится создателем группы; по умолчанию пользователь запроса
+	GroupName string            `json:"group_name" validate:"required,min=2,max=100"`
+	Members   map[string]string `json:"members,omitempty"` // Имя участника из файла -> email
+	CSV       string            `json:"csv" validate:"required"`
+	DryRun    bool              `json:"dry_run"`
+}
+
+// ImportParticipant - участник из файла и пользователь, которому он сопоставлен
+type ImportParticipant struct {
+	Name   string `json:"name"`
+	UserID int    `json:"user_id,omitempty"` // 0, если пользователь еще не создан (dry-run)
+	Email  string `json:"email"`
+	Match  string `json:"match"`
+	// Invited - существующему пользователю отправлено приглашение в группу:
+	// без согласия его в группу не добавляют
+	Invited bool `json:"invited,omitempty"`
+}
+
+// ReconciliationLine сравнивает итоговый баланс участника из файла с балансом по импортированным долгам
+type ReconciliationLine struct {
+	Name       string  `json:"name"`
+	UserID     int     `json:"user_id,omitempty"`
+	Expected   float64 `json:"expected"`
+	Actual     float64 `json:"actual"`
+	Difference float64 `json:"difference"`
+}
+
+// SplitwiseImportResult - итог импорта и сверка балансов
+type SplitwiseImportResult struct {
+	DryRun         bool                 `json:"dry_run"`
+	Group          *Group               `json:"group,omitempty"`
+	Participants   []*ImportParticipant `json:"participants"`
+	Rows           int                  `json:"rows"`
+	Expenses       int                  `json:"expenses"`
+	Payments       int                  `json:"payments"`
+	Debts          int                  `json:"debts"`
+	Errors         []ImportRowError     `json:"errors,omitempty"`
+	Reconciliation []ReconciliationLine `json:"reconciliation"`
+	Balanced       bool                 `json:"balanced"` // Все балансы совпали с файлом
+}
+
+// GroupImport - группа со всеми участниками и долгами, которая записывается одной транзакцией
+type GroupImport struct {
+	Group    CreateGroupRequest
+	NewUsers []*User // Пользователи, которых нужно создать; ID заполняется при записи
+	Members  []*User // Участники группы, включая новых
+	Debts    []*Debt // FromUser и ToUser указывают на элементы Members
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// MergeUsersRequest - слияние пользователя SourceUserID в пользователя из пути запроса
+type MergeUsersRequest struct {
+	SourceUserID int  `json:"source_user_id" validate:"required"`
+	DryRun       bool `json:"dry_run"`
+}
+
+// UserMerge - запись журнала слияний пользователей. При dry-run это только
+// предпросмотр: ID равен нулю, в базе ничего не меняется
+type UserMerge struct {
+	ID           int              `json:"id,omitempty"`
+	TargetUserID int              `json:"target_user_id"`
+	SourceUserID int              `json:"source_user_id"`
+	SourceName   string           `json:"source_name"`
+	SourceEmail  string           `json:"source_email,omitempty"`
+	DryRun       bool             `json:"dry_run"`
+	Summary      UserMergeSummary `json:"summary"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+// UserMergeSummary - что перенесено от исходного пользователя к целевому
+type UserMergeSummary struct {
+	MembershipsMoved    int           `json:"memberships_moved"`
+	MembershipsMerged   int           `json:"memberships_merged"` // Группы, в которых уже состоял целевой пользователь
+	DebtsMoved          int           `json:"debts_moved"`
+	SelfDebtsDeleted    []int         `json:"self_debts_deleted"`   // Долги между двумя аккаунтами: после слияния они стали бы долгами самому себе
+	SelfDebts           []DeletedDebt `json:"self_debts,omitempty"` // Те же долги с суммами, сторонами и статусами
+	SeriesMoved         int           `json:"series_moved"`
+	SeriesEnded         []int         `json:"series_ended"` // Серии, в которых не осталось плательщиков
+	SharesMoved         int           `json:"shares_moved"`
+	SharesMerged        int           `json:"shares_merged"` // Доли обоих аккаунтов в одной серии сложены
+	SelfSharesDeleted   int           `json:"self_shares_deleted"`
+	FriendshipsMoved    int           `json:"friendships_moved"`
+	GroupsMoved         int           `json:"groups_moved"` // Группы, созданные исходным пользователем
+	WebhooksMoved       int           `json:"webhooks_moved"`
+	NotificationsMoved  int           `json:"notifications_moved"`
+	NotificationsMerged int           `json:"notifications_merged"` // Уведомления о событиях, о которых целевой пользователь уже уведомлен
+	PreferencesMoved    int           `json:"preferences_moved"`    // Настройки уведомлений, которых не было у целевого пользователя
+	IdentitiesMoved     int           `json:"identities_moved"`     // Привязки к провайдеру входа OpenID Connect
+	APIKeysMoved        int           `json:"api_keys_moved"`
+}
+
+// DeletedDebt - долг, удаленный при слиянии, в том виде, в каком он был до удаления
+type DeletedDebt struct {
+	ID          int     `json:"id"`
+	GroupID     int     `json:"group_id,omitempty"`
+	FromUserID  int     `json:"from_user_id"`
+	ToUserID    int     `json:"to_user_id"`
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description,omitempty"`
+	Status      string  `json:"status"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Типы доменных событий, которые пишутся в outbox
+const (
+	EventDebtCreated  = "debt.created"
+	EventDebtSettled  = "debt.settled"
+	EventDebtReminder = "debt.reminder"
+	EventMemberAdded  = "member.added"
+)
+
+// EventTypes - все типы событий, на которые можно подписаться
+var EventTypes = []string{EventDebtCreated, EventDebtSettled, EventDebtReminder, EventMemberAdded}
+
+// Статусы уведомления
+const (
+	NotificationStatusPending = "pending"
+	NotificationStatusSent    = "sent"
+	NotificationStatusFailed  = "failed"
+)
+
+// Event - запись outbox. Пишется в той же транзакции, что и изменение, которое ее породило
+type Event struct {
+	ID        int             `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// EventScope - кого касается событие. По нему событие сопоставляется с подписками вебхуков
+type EventScope struct {
+	GroupID int
+	UserIDs []int
+}
+
+// DebtEventScope возвращает область события о долге: его группа и обе стороны
+func DebtEventScope(debt *Debt) EventScope {
+	return EventScope{GroupID: debt.GroupID, UserIDs: []int{debt.FromUserID, debt.ToUserID}}
+}
+
+// MemberAddedPayload - данные события member.added
+type MemberAddedPayload struct {
+	GroupID   int    `json:"group_id"`
+	GroupName string `json:"group_name"`
+	UserID    int    `json:"user_id"`
+}
+
+// DebtReminderPayload - данные события debt.reminder
+type DebtReminderPayload struct {
+	Debt          *Debt `json:"debt"`
+	OffsetSeconds int   `json:"offset_seconds"` // Отрицательное - до срока, положительное - после
+}
+
+// Notification - уведомление одному пользователю по одному каналу
+type Notification struct {
+	ID            int        `json:"id"`
+	EventID       int        `json:"event_id"`
+	UserID        int        `json:"user_id"`
+	Channel       string     `json:"channel"`
+	EventType     string     `json:"event_type"`
+	Subject       string     `json:"subject"`
+	Body          string     `json:"body"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+
+	// Получатель, заполняется при отправке
+	User *User `json:"user,omitempty"`
+}
+
+// NotificationPreference - включен ли канал для типа события у пользователя.
+// Если настройки нет, уведомление отправляется
+type NotificationPreference struct {
+	Channel   string `json:"channel" validate:"required"`
+	EventType string `json:"event_type" validate:"required"`
+	Enabled   bool   `json:"enabled"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreference `json:"preferences" validate:"required,min=1"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// Page - страница списка: Limit записей после первых Offset. Нулевой Limit - весь список
+type Page struct {
+	Limit  int
+	Offset int
+}
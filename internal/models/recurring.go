@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+)
+
+// Статусы серии повторяющихся долгов
+const (
+	SeriesStatusActive = "active"
+	SeriesStatusPaused = "paused"
+	SeriesStatusEnded  = "ended"
+)
+
+// RecurringSeries - повторяющийся долг или расход (аренда, подписки).
+// При каждом повторении для каждой доли создается долг участника перед ToUserID.
+// Серия с несколькими долями описывает общий расход, который оплачивает ToUserID
+type RecurringSeries struct {
+	ID          int              `json:"id"`
+	GroupID     int              `json:"group_id,omitempty"` // 0 - серия прямых долгов между друзьями
+	ToUserID    int              `json:"to_user_id"`
+	Description string           `json:"description"`
+	Frequency   string           `json:"frequency"`
+	Interval    int              `json:"interval"`
+	MonthDay    int              `json:"month_day,omitempty"`
+	StartAt     time.Time        `json:"start_at"`
+	Until       *time.Time       `json:"until,omitempty"`
+	Count       int              `json:"count,omitempty"`
+	Status      string           `json:"status"`
+	Occurrences int              `json:"occurrences"`
+	LastRunAt   *time.Time       `json:"last_run_at,omitempty"`
+	NextRunAt   *time.Time       `json:"next_run_at,omitempty"`
+	CreatedBy   int              `json:"created_by"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Shares      []RecurringShare `json:"shares"`
+}
+
+// RecurringShare - доля участника в повторяющемся долге
+type RecurringShare struct {
+	FromUserID int     `json:"from_user_id" validate:"required"`
+	Amount     float64 `json:"amount" validate:"required,gt=0"`
+}
+
+type CreateRecurringSeriesRequest struct {
+	GroupID     int              `json:"group_id,omitempty"`
+	ToUserID    int              `json:"to_user_id" validate:"required"`
+	Description string           `json:"description" validate:"max=500"`
+	Frequency   string           `json:"frequency" validate:"required,oneof=daily weekly monthly"`
+	Interval    int              `json:"interval" validate:"omitempty,gt=0"`
+	MonthDay    int              `json:"month_day" validate:"omitempty,min=1,max=31"`
+	StartAt     time.Time        `json:"start_at" validate:"required"`
+	Until       *time.Time       `json:"until,omitempty"`
+	Count       int              `json:"count" validate:"omitempty,gt=0"`
+	Shares      []RecurringShare `json:"shares" validate:"required,min=1"`
+	CreatedBy   int              `json:"created_by,omitempty"` // Необязателен: по умолчанию пользователь запроса
+}
+
+// UpdateRecurringSeriesRequest изменяет серию. Поля правила повторения заменяются
+// целиком, если указана частота. Уже созданные долги не меняются
+// UpdateRecurringSeriesRequest - изменение серии. Каждое переданное поле
+// применяется само по себе, непереданные остаются прежними
+type UpdateRecurringSeriesRequest struct {
+	UserID      int              `json:"user_id,omitempty"` // Необязателен: по умолчанию пользователь запроса
+	Description *string          `json:"description,omitempty" validate:"omitempty,max=500"`
+	Frequency   *string          `json:"frequency,omitempty" validate:"omitempty,oneof=daily weekly monthly"`
+	Interval    *int             `json:"interval,omitempty" validate:"omitempty,gt=0"`
+	MonthDay    *int             `json:"month_day,omitempty" validate:"omitempty,min=0,max=31"` // 0 - день месяца из start_at
+	StartAt     *time.Time       `json:"start_at,omitempty"`
+	Until       *time.Time       `json:"until,omitempty"`
+	Count       *int             `json:"count,omitempty" validate:"omitempty,min=0"` // 0 - без ограничения
+	Shares      []RecurringShare `json:"shares,omitempty" validate:"omitempty,min=1"`
+}
+
+// SeriesActionRequest - тело запроса pause, resume и end
+type SeriesActionRequest struct {
+	UserID int `json:"user_id,omitempty"` // Необязателен: по умолчанию пользователь запроса
+}
+
+// SeriesRun - результат планирования очередного повторения серии
+type SeriesRun struct {
+	OccurrenceAt time.Time  // Повторение, для которого создаются долги
+	NextRunAt    *time.Time // Следующее повторение, nil - серия закончилась
+}
@@ -0,0 +1,16 @@
+package models
+
+// Stats - сводные показатели для метрик
+type Stats struct {
+	Users  int
+	Guests int
+	Groups int
+	// Debts и DebtAmounts - число и сумма долгов по статусам
+	Debts       map[string]int
+	DebtAmounts map[string]float64
+	// ActiveSeries - повторяющиеся долги, которые еще создают новые
+	ActiveSeries int
+	// PendingNotifications и PendingDeliveries - очереди уведомлений и вебхуков
+	PendingNotifications int
+	PendingDeliveries    int
+}
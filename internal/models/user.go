@@ -5,19 +5,44 @@ import (
 )
 
 type User struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email,omitempty"` // Пустой у гостей
+	EmailVerified bool      `json:"email_verified"`  // Владение адресом подтверждено по ссылке из письма
+	Guest         bool      `json:"guest"`           // Участник без аккаунта, см. guest.go
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 type CreateUserRequest struct {
-	Name  string `json:"name" validate:"required,min=2,max=100"`
-	Email string `json:"email" validate:"required,email"`
+	Name     string `json:"name" validate:"required,min=2,max=100"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password,omitempty" validate:"omitempty,min=8,max=72"`
 }
 
 type UpdateUserRequest struct {
 	Name  string `json:"name" validate:"required,min=2,max=100"`
 	Email string `json:"email" validate:"required,email"`
 }
+
+// Назначение одноразовых токенов пользователя
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// TokenRequest - одноразовый токен из письма
+type TokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// PasswordResetRequest запрашивает письмо со ссылкой для смены пароля
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// PasswordResetConfirmRequest задает новый пароль по токену из письма
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
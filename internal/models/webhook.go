@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Статусы доставки вебхука
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// Webhook - подписка на события группы или пользователя
+type Webhook struct {
+	ID         int       `json:"id"`
+	GroupID    *int      `json:"group_id,omitempty"`
+	UserID     *int      `json:"user_id,omitempty"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"` // Возвращается только при создании
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedBy  int       `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CreateWebhookRequest создает подписку. Нужно указать ровно одно из group_id и user_id
+type CreateWebhookRequest struct {
+	GroupID    *int     `json:"group_id,omitempty"`
+	UserID     *int     `json:"user_id,omitempty"`
+	URL        string   `json:"url" validate:"required,url"`
+	EventTypes []string `json:"event_types" validate:"required,min=1"`
+	CreatedBy  int      `json:"created_by,omitempty"` // Необязателен: по умолчанию пользователь запроса
+}
+
+// WebhookDelivery - запись журнала доставок
+type WebhookDelivery struct {
+	ID             int        `json:"id"`
+	WebhookID      int        `json:"webhook_id"`
+	EventID        int        `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	ResponseBody   string     `json:"response_body,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DurationMs     *int       `json:"duration_ms,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+
+	// Заполняются при отправке
+	Webhook *Webhook `json:"-"`
+	Event   *Event   `json:"-"`
+}
+
+// WebhookPayload - тело запроса, которое получает подписчик
+type WebhookPayload struct {
+	DeliveryID int             `json:"delivery_id"`
+	EventID    int             `json:"event_id"`
+	Type       string          `json:"type"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Data       json.RawMessage `json:"data"`
+}
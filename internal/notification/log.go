@@ -0,0 +1,41 @@
+package notification
+
+import (
+	"context"
+	"regexp"
+
+	"balance/internal/logging"
+	"balance/internal/models"
+)
+
+// tokenParam - токен в ссылке письма: подтверждение email, сброс пароля,
+// приглашение в группу или передача гостя
+var tokenParam = regexp.MustCompile(`([?&]token=)[^&\s]+`)
+
+// LogNotifier пишет уведомления в лог сервера. Удобен для разработки
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Channel() string {
+	return ChannelLog
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, user *models.User, msg Message) error {
+	logging.FromContext(ctx).Info("Notification", "user_id", user.ID, "email", user.Email, "subject", msg.Subject, "body", redactTokens(msg.Body))
+	return nil
+}
+
+// Mail пишет письмо в лог без токенов из ссылок: лог читают не только адресаты,
+// и действующая ссылка из него позволила бы сбросить чужой пароль
+func (n *LogNotifier) Mail(ctx context.Context, to string, msg Message) error {
+	logging.FromContext(ctx).Info("Mail", "to", to, "subject", msg.Subject, "body", redactTokens(msg.Body))
+	return nil
+}
+
+// redactTokens заменяет значения токенов в ссылках на [REDACTED]
+func redactTokens(body string) string {
+	return tokenParam.ReplaceAllString(body, "${1}[REDACTED]")
+}
@@ -0,0 +1,29 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"balance/internal/logging"
+)
+
+func TestLogNotifierRedactsTokens(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := logging.WithLogger(context.Background(), logging.New(&buf, "text", "info"))
+
+	body := "Reset your password: https://balance.example.com/reset-password?token=secret-1\n" +
+		"Or join: https://balance.example.com/invite?lang=ru&token=secret-2 before Friday."
+	if err := NewLogNotifier().Mail(ctx, "ivan@example.com", Message{Subject: "Reset", Body: body}); err != nil {
+		t.Fatalf("Mail: %v", err)
+	}
+
+	logged := buf.String()
+	if strings.Contains(logged, "secret-1") || strings.Contains(logged, "secret-2") {
+		t.Errorf("log contains a token: %s", logged)
+	}
+	if !strings.Contains(logged, "reset-password?token=[REDACTED]") || !strings.Contains(logged, "lang=ru&token=[REDACTED]") {
+		t.Errorf("log does not keep the links: %s", logged)
+	}
+}
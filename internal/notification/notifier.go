@@ -0,0 +1,51 @@
+package notification
+
+import (
+	"context"
+
+	"balance/internal/config"
+	"balance/internal/models"
+)
+
+// Каналы доставки уведомлений
+const (
+	ChannelEmail = "email"
+	ChannelLog   = "log"
+)
+
+// Message - текст уведомления
+type Message struct {
+	Subject string
+	Body    string
+}
+
+// Notifier доставляет уведомление пользователю по одному каналу.
+// Ошибка означает, что доставку нужно повторить позже
+type Notifier interface {
+	Channel() string
+	Notify(ctx context.Context, user *models.User, msg Message) error
+}
+
+// Mailer отправляет письмо на произвольный адрес, например приглашение тому,
+// у кого еще нет аккаунта
+type Mailer interface {
+	Mail(ctx context.Context, to string, msg Message) error
+}
+
+// NewNotifiers возвращает каналы, доступные при данной конфигурации:
+// лог всегда, email - если задан SMTP сервер
+func NewNotifiers(smtpCfg config.SMTPConfig) []Notifier {
+	notifiers := []Notifier{NewLogNotifier()}
+	if smtpCfg.Host != "" {
+		notifiers = append(notifiers, NewSMTPNotifier(smtpCfg))
+	}
+	return notifiers
+}
+
+// NewMailer возвращает SMTP, если задан сервер, иначе письма пишутся в лог без токенов
+func NewMailer(smtpCfg config.SMTPConfig) Mailer {
+	if smtpCfg.Host != "" {
+		return NewSMTPNotifier(smtpCfg)
+	}
+	return NewLogNotifier()
+}
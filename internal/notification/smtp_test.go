@@ -0,0 +1,216 @@
+package notification
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"balance/internal/config"
+	"balance/internal/models"
+)
+
+// envelope - то, что получил тестовый SMTP сервер
+type envelope struct {
+	Auth string // Логин и пароль из AUTH PLAIN через ":"
+	From string
+	To   []string
+	Data string
+}
+
+// smtpServer - SMTP сервер в процессе. Принимает письма без TLS и с AUTH PLAIN,
+// отклоняет получателей из rejectRcpt
+type smtpServer struct {
+	ln         net.Listener
+	rejectRcpt string
+
+	mu        sync.Mutex
+	envelopes []envelope
+	wg        sync.WaitGroup
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &smtpServer{ln: ln}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(func() {
+		ln.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+// config возвращает настройки SMTP для этого сервера
+func (s *smtpServer) config() config.SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return config.SMTPConfig{Host: host, Port: port, Username: "mailer", Password: "secret", From: "balance@example.com"}
+}
+
+func (s *smtpServer) received() []envelope {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]envelope(nil), s.envelopes...)
+}
+
+func (s *smtpServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	c := textproto.NewConn(conn)
+	defer c.Close()
+
+	var env envelope
+	c.PrintfLine("220 test ESMTP")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			c.PrintfLine("250-test")
+			c.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			_, encoded, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(encoded)
+			// identity \x00 username \x00 password
+			parts := strings.Split(string(decoded), "\x00")
+			if len(parts) == 3 {
+				env.Auth = parts[1] + ":" + parts[2]
+			}
+			c.PrintfLine("235 Authentication successful")
+		case "MAIL":
+			env.From = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			c.PrintfLine("250 OK")
+		case "RCPT":
+			to := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if to == s.rejectRcpt {
+				c.PrintfLine("550 No such user")
+				continue
+			}
+			env.To = append(env.To, to)
+			c.PrintfLine("250 OK")
+		case "DATA":
+			c.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(c.DotReader())
+			if err != nil {
+				return
+			}
+			env.Data = string(data)
+			s.mu.Lock()
+			s.envelopes = append(s.envelopes, env)
+			s.mu.Unlock()
+			env = envelope{}
+			c.PrintfLine("250 OK")
+		case "RSET":
+			env = envelope{}
+			c.PrintfLine("250 OK")
+		case "QUIT":
+			c.PrintfLine("221 Bye")
+			return
+		default:
+			c.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPNotifierSendsMail(t *testing.T) {
+	server := newSMTPServer(t)
+	notifier := NewSMTPNotifier(server.config())
+
+	msg := Message{Subject: "Напоминание о долге", Body: "Иван, срок долга 500 ₽ истекает завтра.\nbalance"}
+	if err := notifier.Mail(context.Background(), "ivan@example.com", msg); err != nil {
+		t.Fatalf("Mail: %v", err)
+	}
+
+	envelopes := server.received()
+	if len(envelopes) != 1 {
+		t.Fatalf("server got %d messages, want 1", len(envelopes))
+	}
+	env := envelopes[0]
+	if env.Auth != "mailer:secret" {
+		t.Errorf("AUTH = %q, want mailer:secret", env.Auth)
+	}
+	if env.From != "balance@example.com" {
+		t.Errorf("MAIL FROM = %q", env.From)
+	}
+	if len(env.To) != 1 || env.To[0] != "ivan@example.com" {
+		t.Errorf("RCPT TO = %q", env.To)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(env.Data))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	if got := parsed.Header.Get("From"); got != "balance@example.com" {
+		t.Errorf("From = %q", got)
+	}
+	if got := parsed.Header.Get("To"); got != "ivan@example.com" {
+		t.Errorf("To = %q", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject = %q (%v), want %q", subject, err, msg.Subject)
+	}
+	if got := parsed.Header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if _, err := parsed.Header.Date(); err != nil {
+		t.Errorf("invalid Date header: %v", err)
+	}
+	body, _ := io.ReadAll(parsed.Body)
+	if got := strings.ReplaceAll(strings.TrimRight(string(body), "\r\n"), "\r\n", "\n"); got != msg.Body {
+		t.Errorf("body = %q, want %q", got, msg.Body)
+	}
+}
+
+func TestSMTPNotifierSkipsGuests(t *testing.T) {
+	server := newSMTPServer(t)
+	notifier := NewSMTPNotifier(server.config())
+
+	if err := notifier.Notify(context.Background(), &models.User{ID: 1, Name: "Гость"}, Message{Subject: "s", Body: "b"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if got := server.received(); len(got) != 0 {
+		t.Errorf("server got %d messages for a user without email", len(got))
+	}
+}
+
+func TestSMTPNotifierReportsRejectedRecipient(t *testing.T) {
+	server := newSMTPServer(t)
+	server.rejectRcpt = "nobody@example.com"
+	notifier := NewSMTPNotifier(server.config())
+
+	err := notifier.Notify(context.Background(), &models.User{Email: "nobody@example.com"}, Message{Subject: "s", Body: "b"})
+	if err == nil || !strings.Contains(err.Error(), "RCPT TO") {
+		t.Errorf("Notify: got %v, want a RCPT TO error", err)
+	}
+	if got := server.received(); len(got) != 0 {
+		t.Errorf("server got %d messages for a rejected recipient", len(got))
+	}
+}
@@ -0,0 +1,249 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// leeway - допустимое расхождение часов с провайдером
+const leeway = time.Minute
+
+// refreshInterval - не чаще этого ключи JWKS перечитываются из-за неизвестного kid
+const refreshInterval = time.Minute
+
+// keySet - загруженные ключи JWKS
+type keySet struct {
+	keys      map[string]crypto.PublicKey // По kid
+	fetchedAt time.Time
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Verify проверяет подпись ID токена по ключам провайдера, а также iss, aud, azp,
+// exp, iat и nonce. Поддерживаются алгоритмы RS256, RS384, RS512 и ES256
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid ID token: malformed JWT")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: malformed signature")
+	}
+
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	now := p.now()
+	switch {
+	case strings.TrimRight(claims.Issuer, "/") != p.config.Issuer:
+		return nil, fmt.Errorf("invalid ID token: unexpected issuer %q", claims.Issuer)
+	case !claims.Audience.contains(p.config.ClientID):
+		return nil, fmt.Errorf("invalid ID token: not issued for this client")
+	case len(claims.Audience) > 1 && claims.AuthorizedBy != p.config.ClientID:
+		return nil, fmt.Errorf("invalid ID token: unexpected azp %q", claims.AuthorizedBy)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("invalid ID token: no subject")
+	case claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(leeway)):
+		return nil, fmt.Errorf("invalid ID token: token has expired")
+	case claims.IssuedAt != 0 && now.Add(leeway).Before(time.Unix(claims.IssuedAt, 0)):
+		return nil, fmt.Errorf("invalid ID token: issued in the future")
+	case claims.Nonce != nonce:
+		return nil, fmt.Errorf("invalid ID token: nonce mismatch")
+	}
+
+	return &claims, nil
+}
+
+// key возвращает ключ провайдера по kid. Если ключа нет, JWKS перечитывается:
+// провайдер мог сменить ключи
+func (p *Provider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	m, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys.lookup(kid); ok {
+		return key, nil
+	}
+	if p.keys != nil && p.now().Sub(p.keys.fetchedAt) < refreshInterval {
+		return nil, fmt.Errorf("invalid ID token: unknown signing key %q", kid)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, m.JWKSURI, &doc); err != nil {
+		return nil, fmt.Errorf("failed to fetch provider keys: %w", err)
+	}
+
+	keys := &keySet{keys: make(map[string]crypto.PublicKey), fetchedAt: p.now()}
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Ключи неподдерживаемых типов пропускаются, чтобы не ломать вход из-за них
+		if key, err := k.publicKey(); err == nil {
+			keys.keys[k.Kid] = key
+		}
+	}
+	p.keys = keys
+
+	if key, ok := p.keys.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("invalid ID token: unknown signing key %q", kid)
+}
+
+// lookup ищет ключ по kid. Токен без kid подходит, только если ключ у провайдера один
+func (s *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if s == nil {
+		return nil, false
+	}
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("invalid EC point")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	switch alg {
+	case "RS256", "RS384", "RS512":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key does not match algorithm %s", alg)
+		}
+		hash, digest := digest(alg, signed)
+		if err := rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature); err != nil {
+			return fmt.Errorf("signature mismatch")
+		}
+		return nil
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return fmt.Errorf("key does not match algorithm %s", alg)
+		}
+		_, digest := digest(alg, signed)
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return fmt.Errorf("signature mismatch")
+		}
+		return nil
+	}
+	// В том числе "none": неподписанные токены не принимаются
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
+
+func digest(alg, signed string) (crypto.Hash, []byte) {
+	switch alg {
+	case "RS384":
+		sum := sha512.Sum384([]byte(signed))
+		return crypto.SHA384, sum[:]
+	case "RS512":
+		sum := sha512.Sum512([]byte(signed))
+		return crypto.SHA512, sum[:]
+	}
+	sum := sha256.Sum256([]byte(signed))
+	return crypto.SHA256, sum[:]
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("malformed JWT segment")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("malformed JWT segment: %w", err)
+	}
+	return nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
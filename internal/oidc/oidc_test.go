@@ -0,0 +1,238 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"balance/internal/oidc/oidctest"
+)
+
+const (
+	clientID    = "balance"
+	redirectURL = "https://balance.example.com/api/v1/auth/oidc/callback"
+)
+
+func newTestProvider(t *testing.T) (*oidctest.Provider, *Provider) {
+	t.Helper()
+
+	idp := oidctest.NewProvider(clientID, oidctest.Claims{
+		Subject: "user-1", Email: "ivan@example.com", EmailVerified: true, Name: "Иван",
+	})
+	t.Cleanup(idp.Close)
+
+	provider := NewProvider(Config{Issuer: idp.Issuer(), ClientID: clientID, RedirectURL: redirectURL}, idp.Server.Client())
+	return idp, provider
+}
+
+// claims возвращает утверждения действующего токена для провайдера idp
+func claims(idp *oidctest.Provider, nonce string) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":   idp.Issuer(),
+		"sub":   "user-1",
+		"aud":   clientID,
+		"exp":   now.Add(time.Hour).Unix(),
+		"iat":   now.Unix(),
+		"nonce": nonce,
+	}
+}
+
+// authorize проходит страницу входа провайдера и возвращает код и state из перенаправления
+func authorize(t *testing.T, provider *Provider, state, nonce, verifier string) (code, gotState string) {
+	t.Helper()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, Challenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	client := *provider.client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("failed to open authorization page: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorization page responded with status %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+	if !strings.HasPrefix(location.String(), redirectURL) {
+		t.Fatalf("redirected to %s, want %s", location, redirectURL)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestDiscover(t *testing.T) {
+	idp, provider := newTestProvider(t)
+
+	m, err := provider.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if m.Issuer != idp.Issuer() || m.TokenEndpoint != idp.Issuer()+"/token" || m.JWKSURI != idp.Issuer()+"/jwks" {
+		t.Errorf("unexpected metadata %+v", m)
+	}
+
+	// Документ называет провайдера 127.0.0.1, а клиент настроен на localhost
+	other := NewProvider(Config{
+		Issuer:   strings.Replace(idp.Issuer(), "127.0.0.1", "localhost", 1),
+		ClientID: clientID,
+	}, idp.Server.Client())
+	if _, err := other.Discover(context.Background()); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Discover with another issuer: got %v, want an issuer mismatch", err)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	_, provider := newTestProvider(t)
+	ctx := context.Background()
+
+	verifier, _ := NewVerifier()
+	code, state := authorize(t, provider, "state-1", "nonce-1", verifier)
+	if state != "state-1" {
+		t.Errorf("state = %q, want state-1", state)
+	}
+
+	got, err := provider.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if got.Subject != "user-1" || got.Email != "ivan@example.com" || !bool(got.EmailVerified) || got.Name != "Иван" {
+		t.Errorf("unexpected claims %+v", got)
+	}
+
+	// Код одноразовый
+	if _, err := provider.Exchange(ctx, code, verifier, "nonce-1"); err == nil {
+		t.Error("Exchange accepted a used code")
+	}
+}
+
+func TestExchangeRequiresPKCEVerifier(t *testing.T) {
+	_, provider := newTestProvider(t)
+
+	verifier, _ := NewVerifier()
+	other, _ := NewVerifier()
+	code, _ := authorize(t, provider, "state", "nonce", verifier)
+
+	_, err := provider.Exchange(context.Background(), code, other, "nonce")
+	if err == nil || !strings.Contains(err.Error(), "PKCE") {
+		t.Errorf("Exchange with another verifier: got %v, want a PKCE error", err)
+	}
+}
+
+func TestExchangeChecksNonce(t *testing.T) {
+	_, provider := newTestProvider(t)
+
+	verifier, _ := NewVerifier()
+	code, _ := authorize(t, provider, "state", "nonce-1", verifier)
+
+	_, err := provider.Exchange(context.Background(), code, verifier, "nonce-2")
+	if err == nil || !strings.Contains(err.Error(), "nonce mismatch") {
+		t.Errorf("Exchange with another nonce: got %v, want a nonce mismatch", err)
+	}
+}
+
+func TestVerifyRejectsInvalidClaims(t *testing.T) {
+	idp, provider := newTestProvider(t)
+
+	tests := []struct {
+		name   string
+		change func(map[string]interface{})
+		want   string
+	}{
+		{"issuer", func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }, "unexpected issuer"},
+		{"audience", func(c map[string]interface{}) { c["aud"] = "other-client" }, "not issued for this client"},
+		{"audience list without azp", func(c map[string]interface{}) { c["aud"] = []string{clientID, "other-client"} }, "unexpected azp"},
+		{"expired", func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, "expired"},
+		{"no expiry", func(c map[string]interface{}) { delete(c, "exp") }, "expired"},
+		{"issued in the future", func(c map[string]interface{}) { c["iat"] = time.Now().Add(time.Hour).Unix() }, "in the future"},
+		{"no subject", func(c map[string]interface{}) { delete(c, "sub") }, "no subject"},
+		{"nonce", func(c map[string]interface{}) { c["nonce"] = "other" }, "nonce mismatch"},
+	}
+	for _, tt := range tests {
+		c := claims(idp, "nonce")
+		tt.change(c)
+		_, err := provider.Verify(context.Background(), idp.Sign(c), "nonce")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	// Расхождение часов в пределах leeway допустимо
+	c := claims(idp, "nonce")
+	c["exp"] = time.Now().Add(-leeway / 2).Unix()
+	if _, err := provider.Verify(context.Background(), idp.Sign(c), "nonce"); err != nil {
+		t.Errorf("Verify a token expired within leeway: %v", err)
+	}
+}
+
+func TestVerifyRejectsBadSignature(t *testing.T) {
+	idp, provider := newTestProvider(t)
+	ctx := context.Background()
+
+	token := idp.Sign(claims(idp, "nonce"))
+	if _, err := provider.Verify(ctx, token, "nonce"); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// Подпись от одних утверждений не подходит к другим
+	parts := strings.Split(token, ".")
+	forged := strings.Split(idp.Sign(map[string]interface{}{"sub": "admin"}), ".")
+	tampered := parts[0] + "." + forged[1] + "." + parts[2]
+	if _, err := provider.Verify(ctx, tampered, "nonce"); err == nil || !strings.Contains(err.Error(), "signature mismatch") {
+		t.Errorf("Verify a tampered token: got %v, want a signature mismatch", err)
+	}
+
+	// Неподписанный токен
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+	if _, err := provider.Verify(ctx, none, "nonce"); err == nil {
+		t.Error("Verify accepted an unsigned token")
+	}
+
+	// Токен другого провайдера с тем же kid
+	stranger := oidctest.NewProvider(clientID, oidctest.Claims{})
+	defer stranger.Close()
+	c := claims(idp, "nonce")
+	if _, err := provider.Verify(ctx, stranger.Sign(c), "nonce"); err == nil || !strings.Contains(err.Error(), "signature mismatch") {
+		t.Errorf("Verify a token signed by another key: got %v, want a signature mismatch", err)
+	}
+}
+
+func TestVerifyAfterKeyRotation(t *testing.T) {
+	idp, provider := newTestProvider(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+
+	old := idp.Sign(claims(idp, "nonce"))
+	if _, err := provider.Verify(ctx, old, "nonce"); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	idp.RotateKey()
+	rotated := idp.Sign(claims(idp, "nonce"))
+
+	// Сразу после загрузки ключей JWKS не перечитывается на каждый неизвестный kid
+	if _, err := provider.Verify(ctx, rotated, "nonce"); err == nil || !strings.Contains(err.Error(), "unknown signing key") {
+		t.Errorf("Verify right after rotation: got %v, want an unknown key error", err)
+	}
+
+	now = now.Add(2 * refreshInterval)
+	if _, err := provider.Verify(ctx, rotated, "nonce"); err != nil {
+		t.Errorf("Verify with the new key: %v", err)
+	}
+	if _, err := provider.Verify(ctx, old, "nonce"); err == nil {
+		t.Error("Verify accepted a token signed by the removed key")
+	}
+}
@@ -0,0 +1,52 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+)
+
+// SwaggerUIAssets - откуда страница документации загружает Swagger UI
+const SwaggerUIAssets = "https://cdn.jsdelivr.net/npm/swagger-ui-dist@5.17.14"
+
+//go:embed docs.html
+var docsHTML string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsHTML))
+
+// Handler отдает документ в JSON. Документ кодируется один раз при создании
+func Handler(doc *Document) http.Handler {
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		panic("openapi: failed to encode document: " + err.Error())
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write(body)
+	})
+}
+
+// DocsHandler отдает страницу Swagger UI для документа по адресу specURL
+func DocsHandler(title, specURL string) http.Handler {
+	var buf bytes.Buffer
+	err := docsTemplate.Execute(&buf, map[string]string{
+		"Title":     title,
+		"SpecURL":   specURL,
+		"AssetsURL": SwaggerUIAssets,
+	})
+	if err != nil {
+		panic("openapi: failed to render docs page: " + err.Error())
+	}
+	body := buf.Bytes()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write(body)
+	})
+}
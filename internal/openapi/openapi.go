@@ -0,0 +1,275 @@
+// Package openapi описывает HTTP API в формате OpenAPI 3.1. Схемы тел запросов
+// и ответов строятся по моделям, поэтому не расходятся с тем, что кодирует сервер
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version - версия спецификации OpenAPI
+const Version = "3.1.0"
+
+// Document - документ OpenAPI
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// SecurityRequirement - схемы авторизации, которые нужны вместе. Пустое требование
+// означает, что операция доступна без авторизации
+type SecurityRequirement map[string][]string
+
+// PathItem - операции одного пути по HTTP методу в нижнем регистре
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	Responses       map[string]*Response       `json:"responses,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Route описывает один маршрут API
+type Route struct {
+	Method  string
+	Path    string // Шаблон ServeMux без префикса, например /debts/{id}
+	ID      string // operationId
+	Tag     string
+	Summary string
+	Query   []Parameter
+
+	Body     any    // Модель тела запроса в JSON
+	BodyType string // Тип тела не в JSON, например text/csv
+
+	Status       int      // Код успешного ответа, по умолчанию 200
+	Data         any      // Модель поля data успешного ответа utils.Response
+	ContentTypes []string // Типы ответа не в JSON, тогда Data не используется
+
+	Security []SecurityRequirement // Если отличается от общей для документа
+}
+
+// Query описывает необязательный параметр строки запроса
+func Query(name, typ, description string, enum ...string) Parameter {
+	s := &Schema{Type: typ}
+	for _, v := range enum {
+		s.Enum = append(s.Enum, v)
+	}
+	return Parameter{Name: name, In: "query", Description: description, Schema: s}
+}
+
+// RequiredQuery описывает обязательный параметр строки запроса
+func RequiredQuery(name, typ, description string) Parameter {
+	p := Query(name, typ, description)
+	p.Required = true
+	return p
+}
+
+var pathParamRe = regexp.MustCompile(`\{(\w+)\}`)
+
+// Build собирает документ по маршрутам. prefix добавляется к путям маршрутов.
+// Ответы с ошибкой описываются общим ответом Error в формате utils.Response
+func Build(info Info, prefix string, routes []Route) *Document {
+	g := NewGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Responses: map[string]*Response{
+				"Error": {
+					Description: "Ошибка",
+					Content:     jsonContent(envelope("error", nil, true)),
+				},
+			},
+		},
+	}
+
+	seenTags := make(map[string]bool)
+	for _, rt := range routes {
+		if rt.Tag != "" && !seenTags[rt.Tag] {
+			seenTags[rt.Tag] = true
+			doc.Tags = append(doc.Tags, Tag{Name: rt.Tag})
+		}
+
+		op := &Operation{
+			Summary:     rt.Summary,
+			OperationID: rt.ID,
+			Responses:   make(map[string]*Response),
+			Security:    rt.Security,
+		}
+		if rt.Tag != "" {
+			op.Tags = []string{rt.Tag}
+		}
+
+		// Все параметры пути - идентификаторы
+		for _, m := range pathParamRe.FindAllStringSubmatch(rt.Path, -1) {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     m[1],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "integer"},
+			})
+		}
+		op.Parameters = append(op.Parameters, rt.Query...)
+
+		switch {
+		case rt.BodyType != "":
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{rt.BodyType: {Schema: &Schema{Type: "string"}}},
+			}
+		case rt.Body != nil:
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  jsonContent(g.Schema(reflect.TypeOf(rt.Body))),
+			}
+		}
+
+		status := rt.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := &Response{Description: http.StatusText(status)}
+		if len(rt.ContentTypes) > 0 {
+			success.Content = make(map[string]MediaType)
+			for _, ct := range rt.ContentTypes {
+				success.Content[ct] = MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+			}
+		} else {
+			var data *Schema
+			if rt.Data != nil {
+				data = g.Schema(reflect.TypeOf(rt.Data))
+			}
+			success.Content = jsonContent(envelope("success", data, false))
+		}
+		op.Responses[strconv.Itoa(status)] = success
+		op.Responses["default"] = &Response{Ref: "#/components/responses/Error"}
+
+		p := prefix + rt.Path
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = make(PathItem)
+		}
+		doc.Paths[p][strings.ToLower(rt.Method)] = op
+	}
+
+	doc.Components.Schemas = g.Schemas()
+	return doc
+}
+
+// envelope - схема utils.Response со статусом status и полем data по схеме data
+func envelope(status string, data *Schema, withError bool) *Schema {
+	s := &Schema{
+		Type:     "object",
+		Required: []string{"status"},
+		Properties: map[string]*Schema{
+			"status":  {Type: "string", Const: status},
+			"message": {Type: "string"},
+		},
+	}
+	if data != nil {
+		s.Properties["data"] = data
+	}
+	if withError {
+		s.Properties["data"] = &Schema{}
+		s.Properties["error"] = &Schema{Type: "string"}
+		s.Required = append(s.Required, "error")
+	}
+	return s
+}
+
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+// Diff сверяет документ с шаблонами, зарегистрированными в ServeMux: "GET /debts/{id}"
+// или "/debts" без метода (тогда достаточно любой операции на этом пути).
+// missing - маршруты без описания, extra - описанные операции без маршрута
+func (d *Document) Diff(prefix string, patterns []string) (missing, extra []string) {
+	registered := make(map[string]map[string]bool)
+	for _, pattern := range patterns {
+		method, p, ok := strings.Cut(pattern, " ")
+		if !ok {
+			method, p = "", pattern
+		}
+		method = strings.ToLower(method)
+
+		if registered[prefix+p] == nil {
+			registered[prefix+p] = make(map[string]bool)
+		}
+		registered[prefix+p][method] = true
+
+		item := d.Paths[prefix+p]
+		if len(item) == 0 || (method != "" && item[method] == nil) {
+			missing = append(missing, pattern)
+		}
+	}
+
+	for p, item := range d.Paths {
+		for method := range item {
+			if methods := registered[p]; !methods[method] && !methods[""] {
+				extra = append(extra, strings.ToUpper(method)+" "+p)
+			}
+		}
+	}
+	sort.Strings(extra)
+
+	return missing, extra
+}
@@ -0,0 +1,219 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema - JSON Schema (диалект OpenAPI 3.1)
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"` // string или []string, если значение может быть null
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Generator строит схемы по типам Go так же, как их кодирует encoding/json.
+// Именованные структуры попадают в components/schemas и подставляются ссылкой.
+// Обязательные поля и ограничения берутся из тегов validate
+type Generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func NewGenerator() *Generator {
+	return &Generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// Schemas возвращает схемы всех встреченных структур по имени
+func (g *Generator) Schemas() map[string]*Schema {
+	return g.schemas
+}
+
+// Schema возвращает схему значения типа t
+func (g *Generator) Schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.Schema(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.Schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Schema(t.Elem())}
+	case reflect.Struct:
+		return g.ref(t)
+	}
+
+	// interface{} и все, что нельзя описать заранее
+	return &Schema{}
+}
+
+// ref описывает именованную структуру в components/schemas один раз
+func (g *Generator) ref(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return g.object(t)
+	}
+
+	name, ok := g.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := g.schemas[name]; taken {
+			name = path.Base(t.PkgPath()) + name
+		}
+		g.names[t] = name
+		// Имя занимается до обхода полей, чтобы рекурсивные типы ссылались сами на себя
+		g.schemas[name] = &Schema{}
+		g.schemas[name] = g.object(t)
+	}
+
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (g *Generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *Generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// Поля встроенной структуры без имени в JSON поднимаются на уровень выше
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := g.Schema(f.Type)
+		if f.Type.Kind() == reflect.Pointer && !strings.Contains(opts, "omitempty") {
+			prop = nullable(prop)
+		}
+		if applyValidate(prop, f.Type, f.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+}
+
+// nullable разрешает значению схемы s быть null
+func nullable(s *Schema) *Schema {
+	if t, ok := s.Type.(string); ok && s.Ref == "" {
+		s.Type = []string{t, "null"}
+		return s
+	}
+	return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+}
+
+// applyValidate переносит в схему правила go-playground/validator и сообщает, что поле обязательное
+func applyValidate(s *Schema, t reflect.Type, tag string) (required bool) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, v)
+			}
+		case "min", "max":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			switch t.Kind() {
+			case reflect.String:
+				setLimit(&s.MinLength, &s.MaxLength, name, int(n))
+			case reflect.Slice, reflect.Array:
+				setLimit(&s.MinItems, &s.MaxItems, name, int(n))
+			default:
+				setLimit(&s.Minimum, &s.Maximum, name, n)
+			}
+		case "gt":
+			if n, err := strconv.ParseFloat(param, 64); err == nil {
+				s.ExclusiveMinimum = &n
+			}
+		}
+	}
+
+	return required
+}
+
+func setLimit[T int | float64](min, max **T, rule string, n T) {
+	if rule == "min" {
+		*min = &n
+	} else {
+		*max = &n
+	}
+}
@@ -0,0 +1,198 @@
+// Package pdf - минимальный генератор PDF без внешних зависимостей.
+//
+// Поддерживается ровно то, что нужно для печатных выписок: страницы A4, текст
+// стандартными шрифтами Helvetica и Helvetica-Bold, линии и залитые
+// прямоугольники. Шрифты не встраиваются, текст кодируется в WinAnsiEncoding;
+// кириллица транслитерируется, остальные символы вне кодировки заменяются на "?".
+// Координаты - в пунктах от левого нижнего угла страницы
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Размер страницы A4 в пунктах
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Font - один из стандартных шрифтов
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+var fontNames = map[Font]string{
+	Helvetica:     "Helvetica",
+	HelveticaBold: "Helvetica-Bold",
+}
+
+// Document - документ из нескольких страниц. Содержимое страниц накапливается
+// в памяти и записывается в WriteTo
+type Document struct {
+	title string
+	pages []*bytes.Buffer
+	cur   *bytes.Buffer
+}
+
+// New создает пустой документ с заголовком title (для метаданных)
+func New(title string) *Document {
+	return &Document{title: title}
+}
+
+// AddPage начинает новую страницу. Все рисование идет на последнюю страницу
+func (d *Document) AddPage() {
+	d.cur = &bytes.Buffer{}
+	d.pages = append(d.pages, d.cur)
+}
+
+// PageCount возвращает число страниц
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// Text пишет строку s, левый край базовой линии - в точке (x, y)
+func (d *Document) Text(x, y float64, font Font, size float64, s string) {
+	d.page()
+	fmt.Fprintf(d.cur, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n",
+		font+1, num(size), num(x), num(y), escape(Encode(s)))
+}
+
+// Line рисует отрезок толщиной width оттенком серого gray (0 - черный, 1 - белый)
+func (d *Document) Line(x1, y1, x2, y2, width, gray float64) {
+	d.page()
+	fmt.Fprintf(d.cur, "%s G %s w %s %s m %s %s l S\n",
+		num(gray), num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// FillRect заливает прямоугольник с левым нижним углом (x, y) оттенком серого gray
+func (d *Document) FillRect(x, y, w, h, gray float64) {
+	d.page()
+	fmt.Fprintf(d.cur, "%s g %s %s %s %s re f 0 g\n", num(gray), num(x), num(y), num(w), num(h))
+}
+
+func (d *Document) page() {
+	if d.cur == nil {
+		d.AddPage()
+	}
+}
+
+// WriteTo записывает документ в w
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	d.page()
+
+	out := &bytes.Buffer{}
+	var offsets []int
+
+	// Объекты нумеруются с 1: каталог, дерево страниц, два шрифта, информация,
+	// затем для каждой страницы - сама страница и ее содержимое
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	const firstPage = 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (balance) >>", escape(Encode(d.title))))
+
+	for i, content := range d.pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] "+
+				"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(PageWidth), num(PageHeight), firstPage+2*i+1,
+		))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write(content.Bytes())
+		zw.Close()
+
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(out, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", len(offsets), compressed.Len())
+		out.Write(compressed.Bytes())
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.WriteTo(w)
+}
+
+// TextWidth возвращает ширину строки s в пунктах
+func TextWidth(font Font, size float64, s string) float64 {
+	widths := helveticaWidths
+	if font == HelveticaBold {
+		widths = helveticaBoldWidths
+	}
+
+	var total int
+	for _, b := range Encode(s) {
+		if b >= 32 && b < 127 {
+			total += widths[b-32]
+		} else {
+			total += defaultWidth
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// Truncate обрезает строку так, чтобы она помещалась в width, добавляя "..."
+func Truncate(font Font, size float64, s string, width float64) string {
+	if TextWidth(font, size, s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := strings.TrimRight(string(runes), " ") + "..."
+		if TextWidth(font, size, candidate) <= width {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// escape экранирует строку для литерала PDF
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		switch c {
+		case '\\', '(', ')':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\n', '\r':
+			sb.WriteByte(' ')
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// num форматирует число для оператора PDF
+func num(f float64) string {
+	s := fmt.Sprintf("%.2f", f)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,98 @@
+// Package ratelimit ограничивает частоту запросов алгоритмом token bucket.
+// У каждого клиента своя корзина на Limit.Requests токенов, которая наполняется
+// заново за Limit.Period. Запрос забирает один токен, в пустую корзину запрос не проходит
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Limit - Requests запросов за Period. Limit с неположительным Requests отключен
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Enabled сообщает, что лимит задан
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// Rate - сколько токенов добавляется в корзину за секунду
+func (l Limit) Rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Store хранит корзины. Take забирает токен из корзины key, предварительно
+// наполнив ее за прошедшее время, и возвращает, сколько токенов осталось и
+// прошел ли запрос
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (tokens float64, allowed bool, err error)
+}
+
+// Result - решение по запросу
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// Reset - через сколько корзина снова будет полной
+	Reset time.Duration
+	// RetryAfter - через сколько появится токен, если запрос не прошел
+	RetryAfter time.Duration
+}
+
+type Limiter struct {
+	store Store
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{store: store}
+}
+
+// Allow забирает токен из корзины key
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	tokens, allowed, err := l.store.Take(ctx, key, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := limit.Rate()
+	result := &Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: max(int(math.Floor(tokens)), 0),
+		Reset:     seconds((float64(limit.Requests) - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	return result, nil
+}
+
+// WriteHeaders добавляет заголовки RateLimit-* по черновику IETF, а если запрос
+// не прошел - Retry-After. Значения в целых секундах с округлением вверх
+func (r *Result) WriteHeaders(h http.Header) {
+	h.Set("RateLimit-Limit", strconv.Itoa(r.Limit.Requests))
+	h.Set("RateLimit-Remaining", strconv.Itoa(r.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(r.Reset)))
+	h.Set("RateLimit-Policy", strconv.Itoa(r.Limit.Requests)+";w="+strconv.Itoa(ceilSeconds(r.Limit.Period)))
+	if !r.Allowed {
+		h.Set("Retry-After", strconv.Itoa(max(ceilSeconds(r.RetryAfter), 1)))
+	}
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
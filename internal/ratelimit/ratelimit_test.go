@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// clock - часы, которые двигает тест
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time          { return c.now }
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestLimiter() (*Limiter, *MemoryStore, *clock) {
+	c := &clock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = c.Now
+	return NewLimiter(store), store, c
+}
+
+func TestLimiterRefill(t *testing.T) {
+	limiter, _, c := newTestLimiter()
+	ctx := context.Background()
+	limit := Limit{Requests: 2, Period: 10 * time.Second}
+
+	type step struct {
+		advance    time.Duration
+		allowed    bool
+		remaining  int
+		reset      time.Duration
+		retryAfter time.Duration
+	}
+	steps := []step{
+		{0, true, 1, 5 * time.Second, 0},
+		{0, true, 0, 10 * time.Second, 0},
+		{0, false, 0, 10 * time.Second, 5 * time.Second},
+		// Токен восстанавливается за Period/Requests
+		{2 * time.Second, false, 0, 8 * time.Second, 3 * time.Second},
+		{3 * time.Second, true, 0, 10 * time.Second, 0},
+		// Больше емкости корзина не копит, сколько бы ни прошло времени
+		{time.Hour, true, 1, 5 * time.Second, 0},
+		{0, true, 0, 10 * time.Second, 0},
+		{0, false, 0, 10 * time.Second, 5 * time.Second},
+	}
+	for i, s := range steps {
+		c.Advance(s.advance)
+		got, err := limiter.Allow(ctx, "client", limit)
+		if err != nil {
+			t.Fatalf("step %d: Allow: %v", i, err)
+		}
+		if got.Allowed != s.allowed || got.Remaining != s.remaining ||
+			got.Reset.Round(time.Millisecond) != s.reset || got.RetryAfter.Round(time.Millisecond) != s.retryAfter {
+			t.Errorf("step %d: got allowed %v, remaining %d, reset %v, retry after %v; want %v, %d, %v, %v",
+				i, got.Allowed, got.Remaining, got.Reset, got.RetryAfter, s.allowed, s.remaining, s.reset, s.retryAfter)
+		}
+	}
+}
+
+func TestLimiterBurst(t *testing.T) {
+	limiter, _, _ := newTestLimiter()
+	ctx := context.Background()
+	limit := Limit{Requests: 5, Period: time.Minute}
+
+	// Вся емкость доступна сразу, у каждого клиента своя корзина
+	for _, key := range []string{"a", "b"} {
+		for i := 0; i < limit.Requests; i++ {
+			if got, _ := limiter.Allow(ctx, key, limit); !got.Allowed {
+				t.Fatalf("%s: request %d was rejected within the burst", key, i+1)
+			}
+		}
+		if got, _ := limiter.Allow(ctx, key, limit); got.Allowed {
+			t.Errorf("%s: request over the burst was allowed", key)
+		}
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	limiter, store, c := newTestLimiter()
+	ctx := context.Background()
+	limit := Limit{Requests: 2, Period: 10 * time.Second}
+
+	limiter.Allow(ctx, "idle", limit)
+	c.Advance(sweepInterval)
+	limiter.Allow(ctx, "active", limit)
+
+	if _, ok := store.buckets["idle"]; ok {
+		t.Error("a full bucket was not swept")
+	}
+	if _, ok := store.buckets["active"]; !ok {
+		t.Error("the bucket of the current request was swept")
+	}
+}
+
+func TestWriteHeaders(t *testing.T) {
+	limit := Limit{Requests: 10, Period: time.Minute}
+
+	tests := []struct {
+		name   string
+		result Result
+		want   map[string]string
+	}{
+		{
+			name:   "allowed",
+			result: Result{Allowed: true, Limit: limit, Remaining: 7, Reset: 17500 * time.Millisecond},
+			want: map[string]string{
+				"RateLimit-Limit":     "10",
+				"RateLimit-Remaining": "7",
+				"RateLimit-Reset":     "18",
+				"RateLimit-Policy":    "10;w=60",
+				"Retry-After":         "",
+			},
+		},
+		{
+			name:   "rejected",
+			result: Result{Allowed: false, Limit: limit, Remaining: 0, Reset: time.Minute, RetryAfter: 5200 * time.Millisecond},
+			want: map[string]string{
+				"RateLimit-Remaining": "0",
+				"RateLimit-Reset":     "60",
+				"Retry-After":         "6",
+			},
+		},
+		{
+			// Retry-After: 0 клиенты поняли бы как "повторить сразу"
+			name:   "rejected with a token almost refilled",
+			result: Result{Allowed: false, Limit: limit, RetryAfter: time.Millisecond},
+			want:   map[string]string{"Retry-After": "1"},
+		},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		tt.result.WriteHeaders(h)
+		for name, want := range tt.want {
+			if got := h.Get(name); got != want {
+				t.Errorf("%s: %s = %q, want %q", tt.name, name, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package recurrence
+
+import (
+	"fmt"
+	"time"
+)
+
+// Частота повторения
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+)
+
+// Rule - упрощенное правило повторения в духе RRULE (RFC 5545):
+// FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYMONTHDAY, UNTIL и COUNT.
+// Количество уже созданных повторений (COUNT) отслеживает вызывающая сторона
+type Rule struct {
+	Frequency string
+	Interval  int        // Каждые N дней/недель/месяцев, по умолчанию 1
+	MonthDay  int        // Для monthly: день месяца 1..31. 0 - день месяца из Start
+	Start     time.Time  // Первое повторение не раньше Start, время суток берется из Start
+	Until     *time.Time // Повторения после Until не создаются
+	Count     int        // Максимальное количество повторений, 0 - без ограничения
+}
+
+// Validate проверяет правило на корректность
+func (r Rule) Validate() error {
+	switch r.Frequency {
+	case Daily, Weekly, Monthly:
+	default:
+		return fmt.Errorf("frequency must be one of daily, weekly, monthly")
+	}
+	if r.Interval < 0 {
+		return fmt.Errorf("interval must not be negative")
+	}
+	if r.MonthDay < 0 || r.MonthDay > 31 {
+		return fmt.Errorf("month_day must be between 1 and 31")
+	}
+	if r.MonthDay != 0 && r.Frequency != Monthly {
+		return fmt.Errorf("month_day is only supported for monthly frequency")
+	}
+	if r.Start.IsZero() {
+		return fmt.Errorf("start_at is required")
+	}
+	if r.Until != nil && r.Until.Before(r.Start) {
+		return fmt.Errorf("until must be after start_at")
+	}
+	if r.Count < 0 {
+		return fmt.Errorf("count must not be negative")
+	}
+	return nil
+}
+
+// Next возвращает первое повторение строго после after.
+// Второе значение false означает, что повторений больше нет (прошел Until)
+func (r Rule) Next(after time.Time) (time.Time, bool) {
+	n := r.estimate(after)
+	for {
+		occurrence := r.occurrence(n)
+		if occurrence.After(after) {
+			if r.Until != nil && occurrence.After(*r.Until) {
+				return time.Time{}, false
+			}
+			return occurrence, true
+		}
+		n++
+	}
+}
+
+// First возвращает первое повторение правила
+func (r Rule) First() (time.Time, bool) {
+	return r.Next(r.Start.Add(-time.Nanosecond))
+}
+
+func (r Rule) interval() int {
+	if r.Interval <= 0 {
+		return 1
+	}
+	return r.Interval
+}
+
+// occurrence вычисляет n-е повторение (с нуля) от Start. Повторения считаются
+// от Start, а не от предыдущего повторения, поэтому "31 число каждого месяца"
+// не съезжает на 30 после коротких месяцев
+func (r Rule) occurrence(n int) time.Time {
+	step := n * r.interval()
+
+	switch r.Frequency {
+	case Daily:
+		return r.Start.AddDate(0, 0, step)
+	case Weekly:
+		return r.Start.AddDate(0, 0, 7*step)
+	}
+
+	day := r.MonthDay
+	if day == 0 {
+		day = r.Start.Day()
+	}
+
+	// Если нужный день в месяце Start уже прошел, начинаем со следующего месяца
+	shift := 0
+	if monthDay(r.Start, 0, day, r.Start.Location()).Before(r.Start) {
+		shift = 1
+	}
+	return monthDay(r.Start, shift+step, day, r.Start.Location())
+}
+
+// estimate возвращает номер повторения, с которого имеет смысл начинать поиск
+// повторения после after. Результат не больше искомого номера
+func (r Rule) estimate(after time.Time) int {
+	if !after.After(r.Start) {
+		return 0
+	}
+
+	var n int
+	switch r.Frequency {
+	case Daily:
+		n = int(after.Sub(r.Start).Hours()/24) / r.interval()
+	case Weekly:
+		n = int(after.Sub(r.Start).Hours()/(24*7)) / r.interval()
+	default:
+		months := (after.Year()-r.Start.Year())*12 + int(after.Month()) - int(r.Start.Month())
+		n = months / r.interval()
+	}
+
+	// Запас на переходы на летнее время и сдвиг первого месяца
+	if n -= 2; n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// monthDay возвращает дату в месяце start+months с днем day и временем суток из start.
+// Если в месяце меньше дней, берется последний день месяца
+func monthDay(start time.Time, months, day int, loc *time.Location) time.Time {
+	firstOfMonth := time.Date(start.Year(), start.Month()+time.Month(months), 1,
+		start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), loc)
+
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return firstOfMonth.AddDate(0, 0, day-1)
+}
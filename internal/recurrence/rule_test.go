@@ -0,0 +1,189 @@
+package recurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+}
+
+// occurrences возвращает первые n повторений правила через First и Next
+func occurrences(t *testing.T, r Rule, n int) []time.Time {
+	t.Helper()
+	var got []time.Time
+	next, ok := r.First()
+	for ok && len(got) < n {
+		got = append(got, next)
+		next, ok = r.Next(next)
+	}
+	return got
+}
+
+func TestRuleOccurrences(t *testing.T) {
+	until := date(2024, 1, 20)
+
+	tests := []struct {
+		name string
+		rule Rule
+		want []time.Time
+	}{
+		{
+			name: "daily",
+			rule: Rule{Frequency: Daily, Start: date(2024, 2, 27)},
+			want: []time.Time{date(2024, 2, 27), date(2024, 2, 28), date(2024, 2, 29), date(2024, 3, 1)},
+		},
+		{
+			name: "every third day",
+			rule: Rule{Frequency: Daily, Interval: 3, Start: date(2024, 1, 30)},
+			want: []time.Time{date(2024, 1, 30), date(2024, 2, 2), date(2024, 2, 5)},
+		},
+		{
+			name: "every second week until",
+			rule: Rule{Frequency: Weekly, Interval: 2, Start: date(2024, 1, 1), Until: &until},
+			want: []time.Time{date(2024, 1, 1), date(2024, 1, 15)},
+		},
+		{
+			name: "day 31 does not drift after short months",
+			rule: Rule{Frequency: Monthly, Start: date(2024, 1, 31)},
+			want: []time.Time{date(2024, 1, 31), date(2024, 2, 29), date(2024, 3, 31), date(2024, 4, 30), date(2024, 5, 31)},
+		},
+		{
+			name: "february in a common year",
+			rule: Rule{Frequency: Monthly, MonthDay: 30, Start: date(2023, 1, 1)},
+			want: []time.Time{date(2023, 1, 30), date(2023, 2, 28), date(2023, 3, 30)},
+		},
+		{
+			name: "leap day every year",
+			rule: Rule{Frequency: Monthly, Interval: 12, MonthDay: 29, Start: date(2024, 2, 29)},
+			want: []time.Time{date(2024, 2, 29), date(2025, 2, 28), date(2026, 2, 28), date(2027, 2, 28), date(2028, 2, 29)},
+		},
+		{
+			name: "month day already passed in the start month",
+			rule: Rule{Frequency: Monthly, MonthDay: 5, Start: date(2024, 1, 10)},
+			want: []time.Time{date(2024, 2, 5), date(2024, 3, 5)},
+		},
+		{
+			name: "month day later in the start month",
+			rule: Rule{Frequency: Monthly, MonthDay: 15, Start: date(2024, 1, 10)},
+			want: []time.Time{date(2024, 1, 15), date(2024, 2, 15)},
+		},
+		{
+			name: "quarterly",
+			rule: Rule{Frequency: Monthly, Interval: 3, Start: date(2024, 11, 30)},
+			want: []time.Time{date(2024, 11, 30), date(2025, 2, 28), date(2025, 5, 30)},
+		},
+	}
+	for _, tt := range tests {
+		got := occurrences(t, tt.rule, len(tt.want)+1)
+		if tt.rule.Until == nil {
+			got = got[:len(tt.want)]
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(tt.want[i]) {
+				t.Errorf("%s: occurrence %d is %v, want %v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestRuleUntil(t *testing.T) {
+	until := date(2024, 3, 31)
+	r := Rule{Frequency: Monthly, Start: date(2024, 1, 31), Until: &until}
+
+	// Повторение ровно в Until еще создается
+	if next, ok := r.Next(date(2024, 3, 1)); !ok || !next.Equal(until) {
+		t.Errorf("Next before until: got %v, %v; want %v", next, ok, until)
+	}
+	if next, ok := r.Next(until); ok {
+		t.Errorf("Next after until: got %v, want no occurrences", next)
+	}
+
+	// До первого повторения Until уже прошел
+	early := date(2024, 1, 20)
+	r = Rule{Frequency: Monthly, MonthDay: 25, Start: date(2024, 1, 10), Until: &early}
+	if first, ok := r.First(); ok {
+		t.Errorf("First with until before it: got %v, want no occurrences", first)
+	}
+}
+
+// Поиск с оценки дает то же, что перебор от первого повторения
+func TestRuleNextFarFromStart(t *testing.T) {
+	rules := []Rule{
+		{Frequency: Daily, Interval: 2, Start: date(2020, 1, 1)},
+		{Frequency: Weekly, Interval: 3, Start: date(2020, 1, 1)},
+		{Frequency: Monthly, Start: date(2020, 1, 31)},
+		{Frequency: Monthly, Interval: 5, MonthDay: 3, Start: date(2020, 1, 10)},
+	}
+	after := date(2024, 6, 15)
+
+	for _, r := range rules {
+		want, _ := r.First()
+		for !want.After(after) {
+			want, _ = r.Next(want)
+		}
+		if n := r.estimate(after); !r.occurrence(n).Before(want) && !r.occurrence(n).Equal(want) {
+			t.Errorf("%s/%d: estimate %d is past the next occurrence %v", r.Frequency, r.Interval, n, want)
+		}
+		if got, ok := r.Next(after); !ok || !got.Equal(want) {
+			t.Errorf("%s/%d: Next(%v) = %v, want %v", r.Frequency, r.Interval, after, got, want)
+		}
+	}
+
+	// До начала оценка - первое повторение
+	if n := rules[0].estimate(date(2019, 1, 1)); n != 0 {
+		t.Errorf("estimate before start = %d, want 0", n)
+	}
+}
+
+// Время суток берется из Start и сохраняется при переходе на летнее время
+func TestRuleKeepsTimeOfDay(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	r := Rule{Frequency: Weekly, Start: time.Date(2024, 3, 25, 9, 30, 0, 0, berlin).AddDate(0, 0, -7)}
+
+	for _, occurrence := range occurrences(t, r, 3) {
+		if occurrence.Hour() != 9 || occurrence.Minute() != 30 {
+			t.Errorf("occurrence %v lost the time of day", occurrence)
+		}
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	until := date(2023, 1, 1)
+
+	tests := []struct {
+		rule Rule
+		want string
+	}{
+		{Rule{Frequency: Daily, Start: date(2024, 1, 1)}, ""},
+		{Rule{Frequency: Monthly, MonthDay: 31, Count: 3, Start: date(2024, 1, 1)}, ""},
+		{Rule{Frequency: "yearly", Start: date(2024, 1, 1)}, "frequency"},
+		{Rule{Frequency: Daily, Interval: -1, Start: date(2024, 1, 1)}, "interval"},
+		{Rule{Frequency: Monthly, MonthDay: 32, Start: date(2024, 1, 1)}, "month_day"},
+		{Rule{Frequency: Weekly, MonthDay: 5, Start: date(2024, 1, 1)}, "month_day"},
+		{Rule{Frequency: Daily}, "start_at"},
+		{Rule{Frequency: Daily, Start: date(2024, 1, 1), Until: &until}, "until"},
+		{Rule{Frequency: Daily, Count: -1, Start: date(2024, 1, 1)}, "count"},
+	}
+	for _, tt := range tests {
+		err := tt.rule.Validate()
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("Validate(%+v): %v", tt.rule, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate(%+v): got %v, want an error about %s", tt.rule, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const apiKeyColumns = `id, user_id, name, prefix, scopes, created_at, last_used_at, revoked_at`
+
+type APIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func scanAPIKey(row pgx.Row, extra ...interface{}) (*models.APIKey, error) {
+	var k models.APIKey
+	dest := []interface{}{&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// Create сохраняет ключ пользователя userID. keyHash - хеш ключа, prefix - его начало
+func (r *APIKeyRepository) Create(ctx context.Context, userID int, name, prefix, keyHash string, scopes []string) (*models.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRow(ctx, `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+apiKeyColumns,
+		userID, name, prefix, keyHash, scopes,
+	))
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetAll возвращает ключи пользователя, включая отозванные, новые первыми
+func (r *APIKeyRepository) GetAll(ctx context.Context, userID int) ([]*models.APIKey, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC, id DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Revoke отзывает ключ id пользователя userID
+func (r *APIKeyRepository) Revoke(ctx context.Context, id, userID int) (*models.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRow(ctx, `
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+apiKeyColumns,
+		id, userID,
+	))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("API key not found")
+		}
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Authenticate находит действующий ключ по хешу, отмечает время использования
+// и возвращает ключ и его владельца
+func (r *APIKeyRepository) Authenticate(ctx context.Context, keyHash string) (*models.APIKey, *models.User, error) {
+	query := `
+		WITH k AS (
+			UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+			WHERE key_hash = $1 AND revoked_at IS NULL
+			RETURNING ` + apiKeyColumns + `
+		)
+		SELECT k.*, u.id, u.name, COALESCE(u.email, ''), u.email_verified, u.is_guest, u.created_at, u.updated_at
+		FROM k
+		JOIN users u ON u.id = k.user_id`
+
+	var u models.User
+	key, err := scanAPIKey(r.db.QueryRow(ctx, query, keyHash),
+		&u.ID, &u.Name, &u.Email, &u.EmailVerified, &u.Guest, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil, fmt.Errorf("API key not found")
+		}
+		return nil, nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return key, &u, nil
+}
@@ -0,0 +1,462 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// debtColumns - список колонок, которые читаются в models.Debt через scanDebt
+const debtColumns = `id, COALESCE(group_id, 0), from_user_id, to_user_id, amount, COALESCE(description, ''),
+	status, COALESCE(status_note, ''), COALESCE(created_by, 0), due_at, series_id, created_at, updated_at, settled_at`
+
+type DebtRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDebtRepository(db *pgxpool.Pool) *DebtRepository {
+	return &DebtRepository{db: db}
+}
+
+// scanDebt читает колонки debtColumns, а затем, если запрос выбирает что-то еще, колонки extra
+func scanDebt(row pgx.Row, extra ...interface{}) (*models.Debt, error) {
+	var debt models.Debt
+	dest := []interface{}{
+		&debt.ID, &debt.GroupID, &debt.FromUserID, &debt.ToUserID, &debt.Amount, &debt.Description,
+		&debt.Status, &debt.StatusNote, &debt.CreatedBy, &debt.DueAt, &debt.SeriesID, &debt.CreatedAt, &debt.UpdatedAt, &debt.SettledAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &debt, nil
+}
+
+// Create создает долг и в той же транзакции пишет событие debt.created в outbox
+func (r *DebtRepository) Create(ctx context.Context, req *models.CreateDebtRequest, status string) (*models.Debt, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO debts (group_id, from_user_id, to_user_id, amount, description, status, created_by, due_at)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + debtColumns
+
+	debt, err := scanDebt(tx.QueryRow(ctx, query,
+		req.GroupID, req.FromUserID, req.ToUserID, req.Amount, req.Description, status, req.CreatedBy, req.DueAt,
+	))
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return nil, fmt.Errorf("group or user not found")
+		}
+		return nil, fmt.Errorf("failed to create debt: %w", err)
+	}
+
+	if err := insertEvent(ctx, tx, models.EventDebtCreated, debt, models.DebtEventScope(debt)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return debt, nil
+}
+
+func (r *DebtRepository) GetByID(ctx context.Context, id int) (*models.Debt, error) {
+	query := `SELECT ` + debtColumns + ` FROM debts WHERE id = $1`
+
+	debt, err := scanDebt(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("debt not found")
+		}
+		return nil, fmt.Errorf("failed to get debt: %w", err)
+	}
+
+	return debt, nil
+}
+
+func (r *DebtRepository) GetAll(ctx context.Context, filter models.DebtFilter) ([]*models.Debt, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.GroupID != 0 {
+		args = append(args, filter.GroupID)
+		conditions = append(conditions, fmt.Sprintf("group_id = $%d", len(args)))
+	}
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("(from_user_id = $%d OR to_user_id = $%d)", len(args), len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	query := `SELECT ` + debtColumns + ` FROM debts`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+
+	clause, args := pageClause(filter.Page, args)
+	return r.queryDebts(ctx, query+clause, args...)
+}
+
+// GetSummaries считает балансы по активным долгам группы, созданным в [from, to).
+// Нулевые from и to не ограничивают период. В список входят участники группы
+// и все стороны долгов за период, даже если они уже вышли из группы
+func (r *DebtRepository) GetSummaries(ctx context.Context, groupID int, from, to *time.Time) ([]models.DebtSummary, error) {
+	query := `
+		WITH period AS (
+			SELECT from_user_id, to_user_id, amount, status
+			FROM debts
+			WHERE group_id = $1
+				AND ($2::timestamp IS NULL OR created_at >= $2)
+				AND ($3::timestamp IS NULL OR created_at < $3)
+		),
+		active AS (
+			SELECT * FROM period WHERE status = 'active'
+		),
+		people AS (
+			SELECT user_id FROM group_members WHERE group_id = $1
+			UNION SELECT from_user_id FROM period
+			UNION SELECT to_user_id FROM period
+		)
+		SELECT u.id, u.name, s.owed, s.owed_to, s.owed_to - s.owed
+		FROM people p
+		JOIN users u ON u.id = p.user_id
+		CROSS JOIN LATERAL (
+			SELECT
+				COALESCE((SELECT SUM(amount) FROM active WHERE from_user_id = u.id), 0) AS owed,
+				COALESCE((SELECT SUM(amount) FROM active WHERE to_user_id = u.id), 0) AS owed_to
+		) s
+		ORDER BY u.name, u.id`
+
+	rows, err := r.db.Query(ctx, query, groupID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get debt summaries: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []models.DebtSummary
+	for rows.Next() {
+		var s models.DebtSummary
+		if err := rows.Scan(&s.UserID, &s.UserName, &s.TotalOwed, &s.TotalOwedTo, &s.NetBalance); err != nil {
+			return nil, fmt.Errorf("failed to scan debt summary: %w", err)
+		}
+		summaries = append(summaries, s)
+	}
+
+	return summaries, rows.Err()
+}
+
+// GetOverdue возвращает активные долги пользователя (в обе стороны), срок которых уже прошел
+func (r *DebtRepository) GetOverdue(ctx context.Context, userID int) ([]*models.Debt, error) {
+	query := `
+		SELECT ` + debtColumns + `
+		FROM debts
+		WHERE (from_user_id = $1 OR to_user_id = $1)
+			AND status = 'active'
+			AND due_at < CURRENT_TIMESTAMP
+		ORDER BY due_at`
+
+	return r.queryDebts(ctx, query, userID)
+}
+
+// ClaimDueReminders отмечает напоминания со смещением offset от срока долга,
+// время которых наступило, и в той же транзакции пишет для каждого событие
+// debt.reminder в outbox. Возвращает долги, по которым напоминания отправлены.
+// Отметка и выборка выполняются одним запросом, поэтому каждое напоминание выдается
+// ровно один раз, даже если запущено несколько экземпляров сервера, а если событие
+// записать не удалось, отметка откатывается и напоминание выдается в следующий раз.
+// Напоминания, время которых наступило раньше создания долга, пропускаются
+func (r *DebtRepository) ClaimDueReminders(ctx context.Context, offset time.Duration) ([]*models.Debt, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		WITH claimed AS (
+			INSERT INTO debt_reminders (debt_id, offset_seconds)
+			SELECT id, $1::integer
+			FROM debts
+			WHERE status = 'active'
+				AND due_at IS NOT NULL
+				AND due_at + make_interval(secs => $1::integer) <= CURRENT_TIMESTAMP
+				AND due_at + make_interval(secs => $1::integer) > created_at
+			ON CONFLICT (debt_id, offset_seconds) DO NOTHING
+			RETURNING debt_id
+		)
+		SELECT ` + debtColumns + `
+		FROM debts
+		WHERE id IN (SELECT debt_id FROM claimed)`
+
+	rows, err := tx.Query(ctx, query, int(offset.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get debts: %w", err)
+	}
+	var debts []*models.Debt
+	for rows.Next() {
+		debt, err := scanDebt(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan debt: %w", err)
+		}
+		debts = append(debts, debt)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get debts: %w", err)
+	}
+
+	for _, debt := range debts {
+		payload := models.DebtReminderPayload{Debt: debt, OffsetSeconds: int(offset.Seconds())}
+		if err := insertEvent(ctx, tx, models.EventDebtReminder, payload, models.DebtEventScope(debt)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return debts, nil
+}
+
+func (r *DebtRepository) queryDebts(ctx context.Context, query string, args ...interface{}) ([]*models.Debt, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get debts: %w", err)
+	}
+	defer rows.Close()
+
+	var debts []*models.Debt
+	for rows.Next() {
+		debt, err := scanDebt(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan debt: %w", err)
+		}
+		debts = append(debts, debt)
+	}
+
+	return debts, rows.Err()
+}
+
+// UpdateStatus переводит долг из статуса from в статус to. Обновление выполняется
+// только если текущий статус в базе все еще равен from, поэтому два конкурентных
+// перехода не перезапишут друг друга. При погашении в той же транзакции
+// пишется событие debt.settled
+func (r *DebtRepository) UpdateStatus(ctx context.Context, id int, from, to, note string) (*models.Debt, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE debts
+		SET status = $3,
+			status_note = NULLIF($4, ''),
+			settled_at = CASE WHEN $3 = 'settled' THEN CURRENT_TIMESTAMP ELSE settled_at END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $2
+		RETURNING ` + debtColumns
+
+	debt, err := scanDebt(tx.QueryRow(ctx, query, id, from, to, note))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("debt status has changed, try again")
+		}
+		return nil, fmt.Errorf("failed to update debt status: %w", err)
+	}
+
+	if to == models.DebtStatusSettled {
+		if err := insertEvent(ctx, tx, models.EventDebtSettled, debt, models.DebtEventScope(debt)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return debt, nil
+}
+
+// EachInGroup вызывает fn для каждого долга группы в порядке создания, заполняя
+// FromUser и ToUser. Строки читаются из курсора по одной, поэтому журнал любой
+// длины не загружается в память целиком. Ошибка fn прерывает обход
+func (r *DebtRepository) EachInGroup(ctx context.Context, groupID int, fn func(*models.Debt) error) error {
+	query := `
+		SELECT ` + debtColumns + `, fu.name, COALESCE(fu.email, ''), tu.name, COALESCE(tu.email, '')
+		FROM debts
+		JOIN LATERAL (SELECT name, email FROM users WHERE users.id = debts.from_user_id) fu ON TRUE
+		JOIN LATERAL (SELECT name, email FROM users WHERE users.id = debts.to_user_id) tu ON TRUE
+		WHERE group_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := r.db.Query(ctx, query, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to get debts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		from, to := &models.User{}, &models.User{}
+		debt, err := scanDebt(rows, &from.Name, &from.Email, &to.Name, &to.Email)
+		if err != nil {
+			return fmt.Errorf("failed to scan debt: %w", err)
+		}
+		from.ID, to.ID = debt.FromUserID, debt.ToUserID
+		debt.FromUser, debt.ToUser = from, to
+
+		if err := fn(debt); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Import записывает долги одной транзакцией: либо все, либо ни одного.
+// Используются поля GroupID, FromUserID, ToUserID, Amount, Description, Status,
+// CreatedBy, DueAt, SettledAt и CreatedAt (нулевое значение - текущее время).
+// События в outbox не пишутся: импорт переносит историю, а не создает новые долги
+func (r *DebtRepository) Import(ctx context.Context, debts []*models.Debt) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, d := range debts {
+		if err := insertImportedDebt(ctx, tx, d); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// insertImportedDebt вставляет долг с заданными статусом и датами, см. Import
+func insertImportedDebt(ctx context.Context, db execer, d *models.Debt) error {
+	var createdAt *time.Time
+	if !d.CreatedAt.IsZero() {
+		createdAt = &d.CreatedAt
+	}
+
+	_, err := db.Exec(ctx, `
+		INSERT INTO debts (group_id, from_user_id, to_user_id, amount, description, status,
+			created_by, due_at, settled_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9,
+			COALESCE($10, CURRENT_TIMESTAMP), COALESCE($10, CURRENT_TIMESTAMP))`,
+		d.GroupID, d.FromUserID, d.ToUserID, d.Amount, d.Description, d.Status,
+		d.CreatedBy, d.DueAt, d.SettledAt, createdAt,
+	)
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return fmt.Errorf("group or user not found")
+		}
+		return fmt.Errorf("failed to import debts: %w", err)
+	}
+
+	return nil
+}
+
+// GetBalances возвращает чистые балансы пользователя userID с каждым, с кем у него
+// есть активные долги, с разбивкой по группам и прямым долгам. Если otherID не ноль, возвращается
+// только баланс с ним. Итоги по группам и по парам считаются в одном запросе
+// через GROUPING SETS: строка итога по паре отличается GROUPING(group_id) = 1
+func (r *DebtRepository) GetBalances(ctx context.Context, userID, otherID int) ([]*models.UserBalance, error) {
+	query := `
+		WITH pair AS (
+			SELECT
+				CASE WHEN from_user_id = $1 THEN to_user_id ELSE from_user_id END AS other_id,
+				group_id,
+				CASE WHEN to_user_id = $1 THEN amount ELSE -amount END AS amount
+			FROM debts
+			WHERE status = 'active' AND (from_user_id = $1 OR to_user_id = $1)
+		)
+		SELECT p.other_id, u.name, p.group_id, COALESCE(MIN(g.name), ''), SUM(p.amount),
+			GROUPING(p.group_id) = 1 AS is_total
+		FROM pair p
+		JOIN users u ON u.id = p.other_id
+		LEFT JOIN groups g ON g.id = p.group_id
+		WHERE $2 = 0 OR p.other_id = $2
+		GROUP BY GROUPING SETS ((p.other_id, u.name, p.group_id), (p.other_id, u.name))
+		ORDER BY u.name, p.other_id, is_total DESC, MIN(g.name)`
+
+	rows, err := r.db.Query(ctx, query, userID, otherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []*models.UserBalance
+	for rows.Next() {
+		var (
+			otherID   int
+			otherName string
+			groupID   *int
+			groupName string
+			amount    float64
+			isTotal   bool
+		)
+		if err := rows.Scan(&otherID, &otherName, &groupID, &groupName, &amount, &isTotal); err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %w", err)
+		}
+
+		// Строка итога идет первой для каждой пары, за ней - строки групп
+		if isTotal {
+			balances = append(balances, &models.UserBalance{
+				UserID:        userID,
+				OtherUserID:   otherID,
+				OtherUserName: otherName,
+				Balance:       amount,
+				Groups:        []models.GroupBalance{},
+			})
+			continue
+		}
+		if amount == 0 || len(balances) == 0 {
+			continue
+		}
+		current := balances[len(balances)-1]
+		// Прямые долги без группы попадают в строку с пустым group_id
+		if groupID == nil {
+			current.Direct = amount
+			continue
+		}
+		current.Groups = append(current.Groups, models.GroupBalance{
+			GroupID:   *groupID,
+			GroupName: groupName,
+			Balance:   amount,
+		})
+	}
+
+	return balances, rows.Err()
+}
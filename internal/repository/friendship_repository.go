@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// friendshipColumns читает пару с точки зрения пользователя $1: в первой колонке
+// всегда он, во второй - его друг
+const friendshipColumns = `
+	$1::integer,
+	CASE WHEN f.user_id = $1 THEN f.friend_id ELSE f.user_id END,
+	f.status, f.requested_by, f.created_at, f.accepted_at`
+
+type FriendshipRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFriendshipRepository(db *pgxpool.Pool) *FriendshipRepository {
+	return &FriendshipRepository{db: db}
+}
+
+func scanFriendship(row pgx.Row, extra ...interface{}) (*models.Friendship, error) {
+	var f models.Friendship
+	dest := []interface{}{&f.UserID, &f.FriendID, &f.Status, &f.RequestedBy, &f.CreatedAt, &f.AcceptedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// orderPair возвращает пару в том порядке, в котором она хранится: меньший ID первым
+func orderPair(a, b int) (int, int) {
+	if a < b {
+		return a, b
+	}
+	return b, a
+}
+
+// Create сохраняет запрос дружбы от userID к friendID
+func (r *FriendshipRepository) Create(ctx context.Context, userID, friendID int) (*models.Friendship, error) {
+	low, high := orderPair(userID, friendID)
+
+	query := `
+		INSERT INTO friendships AS f (user_id, friend_id, requested_by)
+		VALUES ($2, $3, $1)
+		RETURNING ` + friendshipColumns
+
+	friendship, err := scanFriendship(r.db.QueryRow(ctx, query, userID, low, high))
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok {
+			switch pgErr.Code {
+			case "23505":
+				return nil, fmt.Errorf("cannot send a friend request twice")
+			case "23503":
+				return nil, fmt.Errorf("user not found")
+			}
+		}
+		return nil, fmt.Errorf("failed to create friendship: %w", err)
+	}
+
+	return friendship, nil
+}
+
+// Get возвращает дружбу userID и friendID с точки зрения userID
+func (r *FriendshipRepository) Get(ctx context.Context, userID, friendID int) (*models.Friendship, error) {
+	low, high := orderPair(userID, friendID)
+
+	query := `SELECT ` + friendshipColumns + ` FROM friendships f WHERE f.user_id = $2 AND f.friend_id = $3`
+
+	friendship, err := scanFriendship(r.db.QueryRow(ctx, query, userID, low, high))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("friendship not found")
+		}
+		return nil, fmt.Errorf("failed to get friendship: %w", err)
+	}
+
+	return friendship, nil
+}
+
+// GetAll возвращает друзей пользователя и запросы дружбы с ним. Пустой status не фильтрует
+func (r *FriendshipRepository) GetAll(ctx context.Context, userID int, status string) ([]*models.Friendship, error) {
+	query := `
+		SELECT ` + friendshipColumns + `, u.id, u.name, COALESCE(u.email, ''), u.email_verified, u.is_guest, u.created_at, u.updated_at
+		FROM friendships f
+		JOIN users u ON u.id = CASE WHEN f.user_id = $1 THEN f.friend_id ELSE f.user_id END
+		WHERE (f.user_id = $1 OR f.friend_id = $1)
+			AND ($2 = '' OR f.status = $2)
+		ORDER BY u.name`
+
+	rows, err := r.db.Query(ctx, query, userID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get friends: %w", err)
+	}
+	defer rows.Close()
+
+	var friendships []*models.Friendship
+	for rows.Next() {
+		var u models.User
+		friendship, err := scanFriendship(rows, &u.ID, &u.Name, &u.Email, &u.EmailVerified, &u.Guest, &u.CreatedAt, &u.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan friendship: %w", err)
+		}
+		friendship.Friend = &u
+		friendships = append(friendships, friendship)
+	}
+
+	return friendships, rows.Err()
+}
+
+// Accept принимает запрос дружбы, отправленный пользователю userID пользователем friendID
+func (r *FriendshipRepository) Accept(ctx context.Context, userID, friendID int) (*models.Friendship, error) {
+	low, high := orderPair(userID, friendID)
+
+	query := `
+		UPDATE friendships f
+		SET status = 'accepted', accepted_at = CURRENT_TIMESTAMP
+		WHERE f.user_id = $2 AND f.friend_id = $3 AND f.status = 'pending' AND f.requested_by = $4
+		RETURNING ` + friendshipColumns
+
+	friendship, err := scanFriendship(r.db.QueryRow(ctx, query, userID, low, high, friendID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("friend request not found")
+		}
+		return nil, fmt.Errorf("failed to accept friendship: %w", err)
+	}
+
+	return friendship, nil
+}
+
+// Delete удаляет дружбу или запрос дружбы в любую сторону
+func (r *FriendshipRepository) Delete(ctx context.Context, userID, friendID int) error {
+	low, high := orderPair(userID, friendID)
+
+	result, err := r.db.Exec(ctx, `DELETE FROM friendships WHERE user_id = $1 AND friend_id = $2`, low, high)
+	if err != nil {
+		return fmt.Errorf("failed to delete friendship: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("friendship not found")
+	}
+
+	return nil
+}
+
+// AreFriends сообщает, что пользователи a и b - друзья (запрос принят)
+func (r *FriendshipRepository) AreFriends(ctx context.Context, a, b int) (bool, error) {
+	low, high := orderPair(a, b)
+
+	var exists bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM friendships
+			WHERE user_id = $1 AND friend_id = $2 AND status = 'accepted'
+		)`,
+		low, high,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check friendship: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const groupColumns = `g.id, g.name, COALESCE(g.description, ''), COALESCE(g.created_by, 0), g.created_at, g.updated_at`
+
+type GroupRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGroupRepository(db *pgxpool.Pool) *GroupRepository {
+	return &GroupRepository{db: db}
+}
+
+func scanGroup(row pgx.Row) (*models.Group, error) {
+	var group models.Group
+	err := row.Scan(&group.ID, &group.Name, &group.Description, &group.CreatedBy, &group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// Create создает группу и добавляет в нее создателя
+func (r *GroupRepository) Create(ctx context.Context, req *models.CreateGroupRequest) (*models.Group, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO groups AS g (name, description, created_by)
+		VALUES ($1, $2, $3)
+		RETURNING ` + groupColumns
+
+	group, err := scanGroup(tx.QueryRow(ctx, query, req.Name, req.Description, req.CreatedBy))
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to create group: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)`, group.ID, req.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add group creator: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return group, nil
+}
+
+// Import одной транзакцией создает новых пользователей, группу, ее участников
+// и долги из imp. ID созданных пользователей записываются в imp.NewUsers, ID
+// сторон долгов берутся из FromUser и ToUser. Как и DebtRepository.Import,
+// не пишет событий: импорт переносит существующую историю
+func (r *GroupRepository) Import(ctx context.Context, imp *models.GroupImport) (*models.Group, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, user := range imp.NewUsers {
+		err := tx.QueryRow(ctx, `
+			INSERT INTO users (name, email, is_guest)
+			VALUES ($1, NULLIF($2, ''), $3)
+			RETURNING id, created_at, updated_at`,
+			user.Name, user.Email, user.Guest,
+		).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+				return nil, fmt.Errorf("email %s already exists", user.Email)
+			}
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO groups AS g (name, description, created_by)
+		VALUES ($1, $2, $3)
+		RETURNING ` + groupColumns
+
+	group, err := scanGroup(tx.QueryRow(ctx, query, imp.Group.Name, imp.Group.Description, imp.Group.CreatedBy))
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to create group: %w", err)
+	}
+
+	for _, member := range imp.Members {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)
+			ON CONFLICT (group_id, user_id) DO NOTHING`,
+			group.ID, member.ID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add group member: %w", err)
+		}
+	}
+
+	for _, debt := range imp.Debts {
+		debt.GroupID = group.ID
+		debt.FromUserID = debt.FromUser.ID
+		debt.ToUserID = debt.ToUser.ID
+		if err := insertImportedDebt(ctx, tx, debt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return group, nil
+}
+
+func (r *GroupRepository) GetByID(ctx context.Context, id int) (*models.Group, error) {
+	query := `SELECT ` + groupColumns + ` FROM groups g WHERE g.id = $1`
+
+	group, err := scanGroup(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("group not found")
+		}
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	return group, nil
+}
+
+// GetAll возвращает страницу всех групп или, если userID не ноль, групп, в которых состоит пользователь
+func (r *GroupRepository) GetAll(ctx context.Context, userID int, page models.Page) ([]*models.Group, error) {
+	query := `SELECT ` + groupColumns + ` FROM groups g ORDER BY g.created_at DESC, g.id DESC`
+	args := []interface{}{}
+	if userID != 0 {
+		query = `
+			SELECT ` + groupColumns + `
+			FROM groups g
+			JOIN group_members gm ON gm.group_id = g.id
+			WHERE gm.user_id = $1
+			ORDER BY g.created_at DESC, g.id DESC`
+		args = append(args, userID)
+	}
+
+	clause, args := pageClause(page, args)
+	rows, err := r.db.Query(ctx, query+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*models.Group
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+// GetSummary возвращает сводку по группе для долгов, созданных в [from, to).
+// TotalDebts - сумма непогашенных (активных) долгов
+func (r *GroupRepository) GetSummary(ctx context.Context, groupID int, from, to *time.Time) (*models.GroupSummary, error) {
+	query := `
+		SELECT g.id, g.name,
+			COALESCE(SUM(d.amount) FILTER (WHERE d.status = 'active'), 0),
+			COUNT(d.id) FILTER (WHERE d.status = 'active'),
+			COUNT(d.id) FILTER (WHERE d.status = 'settled'),
+			(SELECT COUNT(*) FROM group_members gm WHERE gm.group_id = g.id)
+		FROM groups g
+		LEFT JOIN debts d ON d.group_id = g.id
+			AND ($2::timestamp IS NULL OR d.created_at >= $2)
+			AND ($3::timestamp IS NULL OR d.created_at < $3)
+		WHERE g.id = $1
+		GROUP BY g.id`
+
+	var summary models.GroupSummary
+	err := r.db.QueryRow(ctx, query, groupID, from, to).Scan(
+		&summary.GroupID, &summary.GroupName, &summary.TotalDebts,
+		&summary.ActiveDebts, &summary.SettledDebts, &summary.MemberCount,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("group not found")
+		}
+		return nil, fmt.Errorf("failed to get group summary: %w", err)
+	}
+
+	return &summary, nil
+}
+
+func (r *GroupRepository) GetMembers(ctx context.Context, groupID int) ([]*models.User, error) {
+	query := `
+		SELECT u.id, u.name, COALESCE(u.email, ''), u.email_verified, u.is_guest, u.created_at, u.updated_at
+		FROM users u
+		JOIN group_members gm ON gm.user_id = u.id
+		WHERE gm.group_id = $1
+		ORDER BY gm.joined_at`
+
+	rows, err := r.db.Query(ctx, query, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.EmailVerified, &user.Guest, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, &user)
+	}
+
+	return users, rows.Err()
+}
+
+// AddMember добавляет пользователя в группу и в той же транзакции пишет событие member.added
+func (r *GroupRepository) AddMember(ctx context.Context, groupID, userID int) (*models.GroupMember, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	member, err := addMember(ctx, tx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return member, nil
+}
+
+// addMember вставляет строку group_members и событие member.added в транзакции tx
+func addMember(ctx context.Context, tx pgx.Tx, groupID, userID int) (*models.GroupMember, error) {
+	query := `
+		INSERT INTO group_members (group_id, user_id)
+		VALUES ($1, $2)
+		RETURNING group_id, user_id, joined_at`
+
+	var member models.GroupMember
+	err := tx.QueryRow(ctx, query, groupID, userID).Scan(&member.GroupID, &member.UserID, &member.JoinedAt)
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok {
+			switch pgErr.Code {
+			case "23505":
+				return nil, fmt.Errorf("user is already a member of this group")
+			case "23503":
+				return nil, fmt.Errorf("group or user not found")
+			}
+		}
+		return nil, fmt.Errorf("failed to add member: %w", err)
+	}
+
+	var groupName string
+	if err := tx.QueryRow(ctx, `SELECT name FROM groups WHERE id = $1`, groupID).Scan(&groupName); err != nil {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	payload := models.MemberAddedPayload{GroupID: groupID, GroupName: groupName, UserID: userID}
+	scope := models.EventScope{GroupID: groupID, UserIDs: []int{userID}}
+	if err := insertEvent(ctx, tx, models.EventMemberAdded, payload, scope); err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+func (r *GroupRepository) RemoveMember(ctx context.Context, groupID, userID int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM group_members WHERE group_id = $1 AND user_id = $2`, groupID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("member not found")
+	}
+
+	return nil
+}
+
+func (r *GroupRepository) IsMember(ctx context.Context, groupID, userID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM group_members WHERE group_id = $1 AND user_id = $2)`,
+		groupID, userID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check membership: %w", err)
+	}
+	return exists, nil
+}
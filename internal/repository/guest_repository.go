@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GuestRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGuestRepository(db *pgxpool.Pool) *GuestRepository {
+	return &GuestRepository{db: db}
+}
+
+// Create создает гостя и в той же транзакции добавляет его в группу
+func (r *GuestRepository) Create(ctx context.Context, groupID int, name string) (*models.User, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	guest := models.User{Name: name, Guest: true}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO users (name, is_guest)
+		VALUES ($1, TRUE)
+		RETURNING id, created_at, updated_at`,
+		name,
+	).Scan(&guest.ID, &guest.CreatedAt, &guest.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create guest: %w", err)
+	}
+
+	if _, err := addMember(ctx, tx, groupID, guest.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &guest, nil
+}
+
+// CreateClaim сохраняет приглашение забрать гостя с хешем токена
+func (r *GuestRepository) CreateClaim(ctx context.Context, claim *models.GuestClaim, tokenHash string) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO guest_claims (guest_id, token_hash, email, created_by, expires_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+		RETURNING id, created_at`,
+		claim.GuestID, tokenHash, claim.Email, claim.CreatedBy, claim.ExpiresAt,
+	).Scan(&claim.ID, &claim.CreatedAt)
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to create claim invite: %w", err)
+	}
+
+	return nil
+}
+
+// Claim одной транзакцией передает гостя из приглашения с хешем tokenHash
+// пользователю userID: участие в группах, долги и повторяющиеся серии
+// переходят к пользователю, после чего гость удаляется вместе со своими
+// приглашениями. Если пользователь уже состоит в группе гостя, участие
+// гостя просто удаляется
+func (r *GuestRepository) Claim(ctx context.Context, tokenHash string, userID int) (*models.GuestClaimResult, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		guestID    int
+		claimEmail string
+		expired    bool
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT guest_id, COALESCE(email, ''), expires_at <= CURRENT_TIMESTAMP
+		FROM guest_claims
+		WHERE token_hash = $1
+		FOR UPDATE`,
+		tokenHash,
+	).Scan(&guestID, &claimEmail, &expired)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("claim invite not found")
+		}
+		return nil, fmt.Errorf("failed to get claim invite: %w", err)
+	}
+	if expired {
+		return nil, fmt.Errorf("claim invite has expired")
+	}
+
+	// Блокируем обоих, чтобы гостя не забрали дважды, а пользователя не удалили посреди переноса
+	var (
+		isGuest bool
+		email   string
+	)
+	err = tx.QueryRow(ctx, `SELECT is_guest, COALESCE(email, '') FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&isGuest, &email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if isGuest {
+		return nil, fmt.Errorf("cannot claim a guest with another guest")
+	}
+	// Приглашение по email, как и приглашение в группу, принимает только его адресат
+	if claimEmail != "" && !strings.EqualFold(email, claimEmail) {
+		return nil, fmt.Errorf("only the invited user can claim this guest")
+	}
+	err = tx.QueryRow(ctx, `SELECT is_guest FROM users WHERE id = $1 FOR UPDATE`, guestID).Scan(&isGuest)
+	if err != nil || !isGuest {
+		if err == nil || err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("guest not found")
+		}
+		return nil, fmt.Errorf("failed to get guest: %w", err)
+	}
+
+	// Долг гостя перед самим пользователем после переноса стал бы долгом самому себе
+	var shared bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM debts
+			WHERE (from_user_id = $1 AND to_user_id = $2) OR (from_user_id = $2 AND to_user_id = $1)
+		) OR EXISTS (
+			SELECT 1 FROM recurring_series s
+			JOIN recurring_shares sh ON sh.series_id = s.id
+			WHERE (s.to_user_id = $1 AND sh.from_user_id = $2) OR (s.to_user_id = $2 AND sh.from_user_id = $1)
+		) OR EXISTS (
+			SELECT 1 FROM recurring_shares a
+			JOIN recurring_shares b ON b.series_id = a.series_id
+			WHERE a.from_user_id = $1 AND b.from_user_id = $2
+		)`,
+		userID, guestID,
+	).Scan(&shared)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check guest debts: %w", err)
+	}
+	if shared {
+		return nil, fmt.Errorf("cannot claim a guest who shares debts with you")
+	}
+
+	result := &models.GuestClaimResult{UserID: userID, GuestID: guestID}
+
+	deleted, err := tx.Exec(ctx, `
+		DELETE FROM group_members gm
+		WHERE gm.user_id = $2
+			AND EXISTS (SELECT 1 FROM group_members own WHERE own.group_id = gm.group_id AND own.user_id = $1)`,
+		userID, guestID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move group members: %w", err)
+	}
+	moved, err := tx.Exec(ctx, `UPDATE group_members SET user_id = $1 WHERE user_id = $2`, userID, guestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move group members: %w", err)
+	}
+	result.Groups = int(deleted.RowsAffected() + moved.RowsAffected())
+
+	debts, err := tx.Exec(ctx, `
+		UPDATE debts
+		SET from_user_id = CASE WHEN from_user_id = $2 THEN $1 ELSE from_user_id END,
+			to_user_id = CASE WHEN to_user_id = $2 THEN $1 ELSE to_user_id END,
+			created_by = CASE WHEN created_by = $2 THEN $1 ELSE created_by END
+		WHERE from_user_id = $2 OR to_user_id = $2 OR created_by = $2`,
+		userID, guestID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move debts: %w", err)
+	}
+	result.Debts = int(debts.RowsAffected())
+
+	series, err := tx.Exec(ctx, `
+		UPDATE recurring_series
+		SET to_user_id = CASE WHEN to_user_id = $2 THEN $1 ELSE to_user_id END,
+			created_by = CASE WHEN created_by = $2 THEN $1 ELSE created_by END
+		WHERE to_user_id = $2 OR created_by = $2`,
+		userID, guestID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move recurring series: %w", err)
+	}
+	shares, err := tx.Exec(ctx, `UPDATE recurring_shares SET from_user_id = $1 WHERE from_user_id = $2`, userID, guestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move recurring shares: %w", err)
+	}
+	result.RecurringSeries = int(series.RowsAffected() + shares.RowsAffected())
+
+	if _, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, guestID); err != nil {
+		return nil, fmt.Errorf("failed to delete guest: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
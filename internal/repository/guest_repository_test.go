@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"balance/internal/database/dbtest"
+	"balance/internal/models"
+)
+
+func TestGuestClaimRequiresInvitedEmail(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	guests := NewGuestRepository(db)
+
+	owner, err := users.Create(ctx, &models.CreateUserRequest{Name: "Ivan", Email: dbtest.Email("ivan")}, "hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	petya, err := users.Create(ctx, &models.CreateUserRequest{Name: "Petya", Email: dbtest.Email("petya")}, "hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	stranger, err := users.Create(ctx, &models.CreateUserRequest{Name: "Oleg", Email: dbtest.Email("oleg")}, "hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	group, err := NewGroupRepository(db).Create(ctx, &models.CreateGroupRequest{Name: "Trip", CreatedBy: owner.ID})
+	if err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	guest, err := guests.Create(ctx, group.ID, "Petya")
+	if err != nil {
+		t.Fatalf("failed to create guest: %v", err)
+	}
+
+	token := "claim-" + dbtest.Email("hash")
+	claim := &models.GuestClaim{
+		GuestID:   guest.ID,
+		Email:     strings.ToUpper(petya.Email),
+		CreatedBy: owner.ID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := guests.CreateClaim(ctx, claim, token); err != nil {
+		t.Fatalf("CreateClaim: %v", err)
+	}
+
+	if _, err := guests.Claim(ctx, token, stranger.ID); err == nil || !strings.HasPrefix(err.Error(), "only the ") {
+		t.Fatalf("Claim by another user: got %v, want a forbidden error", err)
+	}
+	// Email сравнивается без учета регистра
+	if _, err := guests.Claim(ctx, token, petya.ID); err != nil {
+		t.Fatalf("Claim by the invited user: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IdentityRepository хранит привязки пользователей к аккаунтам провайдера OpenID Connect
+// и незавершенные попытки входа через него
+type IdentityRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewIdentityRepository(db *pgxpool.Pool) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// CreateLogin сохраняет попытку входа: хеш state, nonce и PKCE code verifier.
+// Заодно удаляются истекшие попытки
+func (r *IdentityRepository) CreateLogin(ctx context.Context, stateHash, nonce, verifier string, expiresAt time.Time) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM oidc_logins WHERE expires_at <= CURRENT_TIMESTAMP`); err != nil {
+		return fmt.Errorf("failed to create login: %w", err)
+	}
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO oidc_logins (state_hash, nonce, code_verifier, expires_at) VALUES ($1, $2, $3, $4)`,
+		stateHash, nonce, verifier, expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create login: %w", err)
+	}
+
+	return nil
+}
+
+// TakeLogin удаляет попытку входа по хешу state и возвращает ее nonce и code verifier.
+// Каждый state можно использовать один раз
+func (r *IdentityRepository) TakeLogin(ctx context.Context, stateHash string) (nonce, verifier string, err error) {
+	var expired bool
+	err = r.db.QueryRow(ctx, `
+		DELETE FROM oidc_logins WHERE state_hash = $1
+		RETURNING nonce, code_verifier, expires_at <= CURRENT_TIMESTAMP`,
+		stateHash,
+	).Scan(&nonce, &verifier, &expired)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", "", fmt.Errorf("login not found")
+		}
+		return "", "", fmt.Errorf("failed to get login: %w", err)
+	}
+	if expired {
+		return "", "", fmt.Errorf("login has expired, try again")
+	}
+
+	return nonce, verifier, nil
+}
+
+// Login находит пользователя по аккаунту провайдера. Аккаунт без привязки
+// привязывается к пользователю с тем же email, если провайдер подтвердил адрес;
+// если такого пользователя нет, он создается. У пользователя, который свой email
+// не подтвердил, при привязке сбрасываются пароль, 2FA, сессии, API ключи и
+// другие привязки
+func (r *IdentityRepository) Login(ctx context.Context, identity *models.OIDCIdentity) (*models.User, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int
+	err = tx.QueryRow(ctx, `
+		UPDATE user_identities
+		SET email = NULLIF($3, ''), last_login_at = CURRENT_TIMESTAMP
+		WHERE issuer = $1 AND subject = $2
+		RETURNING user_id`,
+		identity.Issuer, identity.Subject, identity.Email,
+	).Scan(&userID)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+
+	if err == pgx.ErrNoRows {
+		// Привязка по email допустима, только если провайдер подтвердил, что адрес принадлежит пользователю
+		if identity.Email == "" || !identity.EmailVerified {
+			return nil, fmt.Errorf("identity provider did not return a verified email")
+		}
+
+		var verified bool
+		err = tx.QueryRow(ctx, `
+			SELECT id, email_verified FROM users WHERE LOWER(email) = LOWER($1) FOR UPDATE`,
+			identity.Email,
+		).Scan(&userID, &verified)
+		if err == nil {
+			// Неподтвержденный email мог указать кто угодно, в том числе заранее
+			// зарегистрировав чужой адрес. Владельцем аккаунта становится тот, кто
+			// подтвердил адрес у провайдера, поэтому прежние способы входа удаляются
+			if !verified {
+				err = resetCredentials(ctx, tx, userID)
+			}
+			if err == nil {
+				_, err = tx.Exec(ctx, `
+					UPDATE users SET email_verified = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+					userID,
+				)
+			}
+		} else if err == pgx.ErrNoRows {
+			err = tx.QueryRow(ctx, `
+				INSERT INTO users (name, email, email_verified) VALUES ($1, $2, TRUE)
+				RETURNING id`,
+				identity.Name, identity.Email,
+			).Scan(&userID)
+		}
+		if err != nil {
+			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+				return nil, fmt.Errorf("user has changed, try again")
+			}
+			return nil, fmt.Errorf("failed to link identity: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO user_identities (user_id, issuer, subject, email, last_login_at)
+			VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP)`,
+			userID, identity.Issuer, identity.Subject, identity.Email,
+		)
+		if err != nil {
+			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+				return nil, fmt.Errorf("identity has changed, try again")
+			}
+			return nil, fmt.Errorf("failed to link identity: %w", err)
+		}
+	}
+
+	var user models.User
+	err = tx.QueryRow(ctx, `
+		SELECT id, name, COALESCE(email, ''), email_verified, is_guest, created_at, updated_at
+		FROM users WHERE id = $1`,
+		userID,
+	).Scan(&user.ID, &user.Name, &user.Email, &user.EmailVerified, &user.Guest, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &user, nil
+}
+
+// resetCredentials удаляет все способы войти от имени пользователя
+func resetCredentials(ctx context.Context, tx pgx.Tx, userID int) error {
+	queries := []string{
+		`UPDATE users SET password_hash = NULL, totp_secret = NULL, totp_enabled = FALSE, totp_last_step = NULL WHERE id = $1`,
+		`DELETE FROM sessions WHERE user_id = $1`,
+		`DELETE FROM login_challenges WHERE user_id = $1`,
+		`DELETE FROM recovery_codes WHERE user_id = $1`,
+		`DELETE FROM api_keys WHERE user_id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(ctx, query, userID); err != nil {
+			return fmt.Errorf("failed to reset credentials: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"balance/internal/database/dbtest"
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newUserWithSession создает пользователя с паролем и открытой сессией
+func newUserWithSession(t *testing.T, db *pgxpool.Pool, verified bool) (*models.User, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	user, err := NewUserRepository(db).Create(ctx, &models.CreateUserRequest{Name: "Ivan", Email: dbtest.Email("ivan")}, "old-hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := db.Exec(ctx, `UPDATE users SET email_verified = $2 WHERE id = $1`, user.ID, verified); err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+
+	session := "session-" + dbtest.Email("hash")
+	if err := NewSessionRepository(db).Create(ctx, user.ID, session, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	return user, session
+}
+
+func hasPassword(t *testing.T, db *pgxpool.Pool, userID int) bool {
+	t.Helper()
+	var has bool
+	if err := db.QueryRow(context.Background(), `SELECT password_hash IS NOT NULL FROM users WHERE id = $1`, userID).Scan(&has); err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	return has
+}
+
+func TestIdentityLoginLinksVerifiedUser(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+
+	user, session := newUserWithSession(t, db, true)
+	linked, err := NewIdentityRepository(db).Login(ctx, &models.OIDCIdentity{
+		Issuer: "https://idp.example.com", Subject: user.Email, Email: user.Email, EmailVerified: true,
+	})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if linked.ID != user.ID {
+		t.Fatalf("Login returned user %d, want %d", linked.ID, user.ID)
+	}
+	if !hasPassword(t, db, user.ID) {
+		t.Error("linking a verified user removed the password")
+	}
+	if _, err := NewSessionRepository(db).GetUser(ctx, session); err != nil {
+		t.Errorf("linking a verified user closed the session: %v", err)
+	}
+}
+
+// Аккаунт с чужим неподтвержденным email после входа владельца адреса
+// больше недоступен тому, кто его зарегистрировал
+func TestIdentityLoginResetsUnverifiedUser(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+
+	user, session := newUserWithSession(t, db, false)
+	linked, err := NewIdentityRepository(db).Login(ctx, &models.OIDCIdentity{
+		Issuer: "https://idp.example.com", Subject: user.Email, Email: user.Email, EmailVerified: true,
+	})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if linked.ID != user.ID || !linked.EmailVerified {
+		t.Fatalf("Login returned %+v, want verified user %d", linked, user.ID)
+	}
+	if hasPassword(t, db, user.ID) {
+		t.Error("the password of an unverified user was kept")
+	}
+	if _, err := NewSessionRepository(db).GetUser(ctx, session); err == nil {
+		t.Error("the session of an unverified user was kept")
+	}
+}
+
+func TestResetPasswordClosesSessions(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+
+	user, session := newUserWithSession(t, db, true)
+	tokens := NewUserTokenRepository(db)
+	token := "reset-" + dbtest.Email("hash")
+	if err := tokens.Create(ctx, user.ID, models.TokenPurposeResetPassword, user.Email, token, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	if err := tokens.ResetPassword(ctx, token, "new-hash"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if _, err := NewSessionRepository(db).GetUser(ctx, session); err == nil {
+		t.Error("the session survived a password reset")
+	}
+}
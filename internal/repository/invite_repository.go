@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const inviteColumns = `id, group_id, COALESCE(email, ''), COALESCE(created_by, 0), max_uses, uses, status,
+	created_at, expires_at, revoked_at`
+
+type InviteRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewInviteRepository(db *pgxpool.Pool) *InviteRepository {
+	return &InviteRepository{db: db}
+}
+
+func scanInvite(row pgx.Row, extra ...interface{}) (*models.GroupInvite, error) {
+	var i models.GroupInvite
+	dest := []interface{}{
+		&i.ID, &i.GroupID, &i.Email, &i.CreatedBy, &i.MaxUses, &i.Uses, &i.Status,
+		&i.CreatedAt, &i.ExpiresAt, &i.RevokedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// Create сохраняет приглашение с хешем токена и заполняет ID, статус и время создания
+func (r *InviteRepository) Create(ctx context.Context, invite *models.GroupInvite, tokenHash string) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO group_invites (group_id, token_hash, email, created_by, max_uses, expires_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+		RETURNING id, status, uses, created_at`,
+		invite.GroupID, tokenHash, invite.Email, invite.CreatedBy, invite.MaxUses, invite.ExpiresAt,
+	).Scan(&invite.ID, &invite.Status, &invite.Uses, &invite.CreatedAt)
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return fmt.Errorf("group or user not found")
+		}
+		return fmt.Errorf("failed to create invite: %w", err)
+	}
+
+	return nil
+}
+
+func (r *InviteRepository) GetByID(ctx context.Context, id int) (*models.GroupInvite, error) {
+	query := `SELECT ` + inviteColumns + ` FROM group_invites WHERE id = $1`
+
+	invite, err := scanInvite(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("invite not found")
+		}
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+
+	return invite, nil
+}
+
+// GetByGroup возвращает приглашения группы, новые первыми
+func (r *InviteRepository) GetByGroup(ctx context.Context, groupID int) ([]*models.GroupInvite, error) {
+	query := `SELECT ` + inviteColumns + ` FROM group_invites WHERE group_id = $1 ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.db.Query(ctx, query, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invites: %w", err)
+	}
+	defer rows.Close()
+
+	var invites []*models.GroupInvite
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invite: %w", err)
+		}
+		invites = append(invites, invite)
+	}
+
+	return invites, rows.Err()
+}
+
+// lockByToken блокирует приглашение с хешем tokenHash и проверяет, что им еще можно воспользоваться
+func lockByToken(ctx context.Context, tx pgx.Tx, tokenHash string) (*models.GroupInvite, error) {
+	var expired bool
+	query := `SELECT ` + inviteColumns + `, expires_at <= CURRENT_TIMESTAMP FROM group_invites WHERE token_hash = $1 FOR UPDATE`
+
+	invite, err := scanInvite(tx.QueryRow(ctx, query, tokenHash), &expired)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("invite not found")
+		}
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+
+	switch {
+	case invite.Status == models.InviteStatusRevoked:
+		return nil, fmt.Errorf("invite has been revoked")
+	case invite.Status == models.InviteStatusDeclined:
+		return nil, fmt.Errorf("invite has been declined")
+	case expired:
+		return nil, fmt.Errorf("invite has expired")
+	case invite.Uses >= invite.MaxUses:
+		return nil, fmt.Errorf("invite has already been used")
+	}
+
+	return invite, nil
+}
+
+// Accept по приглашению с хешем tokenHash добавляет userID в группу и в той же
+// транзакции засчитывает использование. Приглашение по email может принять
+// только пользователь с этим email
+func (r *InviteRepository) Accept(ctx context.Context, tokenHash string, userID int) (*models.GroupMember, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	invite, err := lockByToken(ctx, tx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if invite.Email != "" {
+		var email string
+		err := tx.QueryRow(ctx, `SELECT COALESCE(email, '') FROM users WHERE id = $1`, userID).Scan(&email)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return nil, fmt.Errorf("user not found")
+			}
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if !strings.EqualFold(email, invite.Email) {
+			return nil, fmt.Errorf("only the invited user can accept this invite")
+		}
+	}
+
+	member, err := addMember(ctx, tx, invite.GroupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE group_invites SET uses = uses + 1 WHERE id = $1`, invite.ID); err != nil {
+		return nil, fmt.Errorf("failed to update invite: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return member, nil
+}
+
+// Decline отклоняет приглашение по email с хешем tokenHash
+func (r *InviteRepository) Decline(ctx context.Context, tokenHash string) (*models.GroupInvite, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	invite, err := lockByToken(ctx, tx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if invite.Email == "" {
+		return nil, fmt.Errorf("cannot decline an invite link")
+	}
+
+	query := `UPDATE group_invites SET status = 'declined' WHERE id = $1 RETURNING ` + inviteColumns
+	invite, err = scanInvite(tx.QueryRow(ctx, query, invite.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decline invite: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return invite, nil
+}
+
+// Revoke отзывает активное приглашение
+func (r *InviteRepository) Revoke(ctx context.Context, id int) (*models.GroupInvite, error) {
+	query := `
+		UPDATE group_invites
+		SET status = 'revoked', revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'active'
+		RETURNING ` + inviteColumns
+
+	invite, err := scanInvite(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("cannot revoke an invite that is not active")
+		}
+		return nil, fmt.Errorf("failed to revoke invite: %w", err)
+	}
+
+	return invite, nil
+}
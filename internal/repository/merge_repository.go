@@ -0,0 +1,373 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MergeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMergeRepository(db *pgxpool.Pool) *MergeRepository {
+	return &MergeRepository{db: db}
+}
+
+// Merge одной транзакцией переносит все данные пользователя sourceID к targetID,
+// удаляет sourceID и пишет запись в журнал user_merges. При dryRun выполняются
+// те же запросы, но транзакция откатывается: отчет точно совпадает с тем, что
+// произойдет при настоящем слиянии
+func (r *MergeRepository) Merge(ctx context.Context, targetID, sourceID int, dryRun bool) (*models.UserMerge, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	merge := &models.UserMerge{TargetUserID: targetID, SourceUserID: sourceID, DryRun: dryRun}
+
+	// Блокируем обоих в порядке ID, чтобы встречные слияния не взаимоблокировались
+	rows, err := tx.Query(ctx, `
+		SELECT id, name, COALESCE(email, ''), is_guest
+		FROM users
+		WHERE id IN ($1, $2)
+		ORDER BY id
+		FOR UPDATE`,
+		targetID, sourceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock users: %w", err)
+	}
+	found := make(map[int]*models.User, 2)
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Guest); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		found[u.ID] = &u
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock users: %w", err)
+	}
+	target, source := found[targetID], found[sourceID]
+	if target == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if source == nil {
+		return nil, fmt.Errorf("source user not found")
+	}
+	if target.Guest {
+		return nil, fmt.Errorf("cannot merge into a guest")
+	}
+	merge.SourceName, merge.SourceEmail = source.Name, source.Email
+
+	summary, err := mergeUserData(ctx, tx, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	merge.Summary = *summary
+
+	if dryRun {
+		merge.CreatedAt = time.Now().UTC()
+		return merge, nil
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, sourceID); err != nil {
+		return nil, fmt.Errorf("failed to delete source user: %w", err)
+	}
+
+	summaryJSON, err := json.Marshal(merge.Summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merge summary: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO user_merges (target_user_id, source_user_id, source_name, source_email, summary)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		RETURNING id, created_at`,
+		targetID, sourceID, merge.SourceName, merge.SourceEmail, summaryJSON,
+	).Scan(&merge.ID, &merge.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write merge record: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return merge, nil
+}
+
+// mergeUserData переносит ссылки на sourceID к targetID во всех таблицах в транзакции tx
+func mergeUserData(ctx context.Context, tx pgx.Tx, targetID, sourceID int) (*models.UserMergeSummary, error) {
+	summary := &models.UserMergeSummary{SelfDebtsDeleted: []int{}, SelfDebts: []models.DeletedDebt{}, SeriesEnded: []int{}}
+
+	exec := func(what, query string) (int, error) {
+		result, err := tx.Exec(ctx, query, targetID, sourceID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to move %s: %w", what, err)
+		}
+		return int(result.RowsAffected()), nil
+	}
+	collect := func(what, query string) ([]int, error) {
+		rows, err := tx.Query(ctx, query, targetID, sourceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to move %s: %w", what, err)
+		}
+		defer rows.Close()
+
+		ids := []int{}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				return nil, fmt.Errorf("failed to move %s: %w", what, err)
+			}
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		return ids, rows.Err()
+	}
+
+	var err error
+
+	// Долги между двумя аккаунтами нарушили бы CHECK (from_user_id != to_user_id).
+	// Удаление необратимо, поэтому в отчете остаются суммы, стороны и статусы
+	rows, err := tx.Query(ctx, `
+		DELETE FROM debts
+		WHERE (from_user_id = $1 AND to_user_id = $2) OR (from_user_id = $2 AND to_user_id = $1)
+		RETURNING id, COALESCE(group_id, 0), from_user_id, to_user_id, amount, COALESCE(description, ''), status`,
+		targetID, sourceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete debts between the users: %w", err)
+	}
+	for rows.Next() {
+		var d models.DeletedDebt
+		if err := rows.Scan(&d.ID, &d.GroupID, &d.FromUserID, &d.ToUserID, &d.Amount, &d.Description, &d.Status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan deleted debt: %w", err)
+		}
+		summary.SelfDebts = append(summary.SelfDebts, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete debts between the users: %w", err)
+	}
+	sort.Slice(summary.SelfDebts, func(i, j int) bool { return summary.SelfDebts[i].ID < summary.SelfDebts[j].ID })
+	for _, d := range summary.SelfDebts {
+		summary.SelfDebtsDeleted = append(summary.SelfDebtsDeleted, d.ID)
+	}
+
+	// Если оба аккаунта платили в одной серии, их долги за одно повторение
+	// столкнулись бы в уникальном индексе; долг исходного аккаунта отвязываем от серии
+	if _, err = exec("debts", `
+		UPDATE debts d
+		SET series_id = NULL
+		WHERE d.from_user_id = $2 AND d.series_id IS NOT NULL
+			AND EXISTS (
+				SELECT 1 FROM debts own
+				WHERE own.series_id = d.series_id AND own.occurrence_at = d.occurrence_at AND own.from_user_id = $1
+			)`); err != nil {
+		return nil, err
+	}
+
+	if summary.DebtsMoved, err = exec("debts", `
+		UPDATE debts
+		SET from_user_id = CASE WHEN from_user_id = $2 THEN $1 ELSE from_user_id END,
+			to_user_id = CASE WHEN to_user_id = $2 THEN $1 ELSE to_user_id END,
+			created_by = CASE WHEN created_by = $2 THEN $1 ELSE created_by END
+		WHERE from_user_id = $2 OR to_user_id = $2 OR created_by = $2`); err != nil {
+		return nil, err
+	}
+
+	// UNIQUE(group_id, user_id): в общих группах остается участие целевого
+	// пользователя с более ранней датой вступления
+	if _, err = exec("group members", `
+		UPDATE group_members own
+		SET joined_at = LEAST(own.joined_at, other.joined_at)
+		FROM group_members other
+		WHERE own.user_id = $1 AND other.user_id = $2 AND other.group_id = own.group_id`); err != nil {
+		return nil, err
+	}
+	if summary.MembershipsMerged, err = exec("group members", `
+		DELETE FROM group_members gm
+		WHERE gm.user_id = $2
+			AND EXISTS (SELECT 1 FROM group_members own WHERE own.group_id = gm.group_id AND own.user_id = $1)`); err != nil {
+		return nil, err
+	}
+	if summary.MembershipsMoved, err = exec("group members", `
+		UPDATE group_members SET user_id = $1 WHERE user_id = $2`); err != nil {
+		return nil, err
+	}
+
+	// Доли, которые после слияния платились бы самому себе
+	if summary.SelfSharesDeleted, err = exec("recurring shares", `
+		DELETE FROM recurring_shares sh
+		USING recurring_series s
+		WHERE sh.series_id = s.id
+			AND ((sh.from_user_id = $2 AND s.to_user_id = $1) OR (sh.from_user_id = $1 AND s.to_user_id = $2))`); err != nil {
+		return nil, err
+	}
+	// PRIMARY KEY (series_id, from_user_id): доли обоих аккаунтов в одной серии складываем
+	if _, err = exec("recurring shares", `
+		UPDATE recurring_shares own
+		SET amount = own.amount + other.amount
+		FROM recurring_shares other
+		WHERE own.from_user_id = $1 AND other.from_user_id = $2 AND other.series_id = own.series_id`); err != nil {
+		return nil, err
+	}
+	if summary.SharesMerged, err = exec("recurring shares", `
+		DELETE FROM recurring_shares sh
+		WHERE sh.from_user_id = $2
+			AND EXISTS (SELECT 1 FROM recurring_shares own WHERE own.series_id = sh.series_id AND own.from_user_id = $1)`); err != nil {
+		return nil, err
+	}
+	if summary.SharesMoved, err = exec("recurring shares", `
+		UPDATE recurring_shares SET from_user_id = $1 WHERE from_user_id = $2`); err != nil {
+		return nil, err
+	}
+	if summary.SeriesMoved, err = exec("recurring series", `
+		UPDATE recurring_series
+		SET to_user_id = CASE WHEN to_user_id = $2 THEN $1 ELSE to_user_id END,
+			created_by = CASE WHEN created_by = $2 THEN $1 ELSE created_by END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE to_user_id = $2 OR created_by = $2`); err != nil {
+		return nil, err
+	}
+	if summary.SeriesEnded, err = collect("recurring series", `
+		UPDATE recurring_series s
+		SET status = 'ended', next_run_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE s.to_user_id IN ($1, $2) AND s.status != 'ended'
+			AND NOT EXISTS (SELECT 1 FROM recurring_shares sh WHERE sh.series_id = s.id)
+		RETURNING s.id`); err != nil {
+		return nil, err
+	}
+
+	// Дружба хранится парой user_id < friend_id, поэтому пару пересобираем.
+	// Если дружба с тем же человеком есть у обоих, сохраняется принятая
+	if summary.FriendshipsMoved, err = exec("friendships", `
+		INSERT INTO friendships AS f (user_id, friend_id, requested_by, status, created_at, accepted_at)
+		SELECT LEAST($1, other.id), GREATEST($1, other.id),
+			CASE WHEN old.requested_by = $2 THEN $1 ELSE old.requested_by END,
+			old.status, old.created_at, old.accepted_at
+		FROM friendships old
+		CROSS JOIN LATERAL (
+			SELECT CASE WHEN old.user_id = $2 THEN old.friend_id ELSE old.user_id END AS id
+		) other
+		WHERE (old.user_id = $2 OR old.friend_id = $2) AND other.id != $1
+		ON CONFLICT (user_id, friend_id) DO UPDATE
+		SET status = CASE WHEN EXCLUDED.status = 'accepted' THEN 'accepted' ELSE f.status END,
+			accepted_at = COALESCE(f.accepted_at, EXCLUDED.accepted_at)`); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM friendships WHERE user_id = $1 OR friend_id = $1`, sourceID); err != nil {
+		return nil, fmt.Errorf("failed to move friendships: %w", err)
+	}
+
+	if summary.GroupsMoved, err = exec("groups", `
+		UPDATE groups SET created_by = $1 WHERE created_by = $2`); err != nil {
+		return nil, err
+	}
+	if summary.WebhooksMoved, err = exec("webhooks", `
+		UPDATE webhooks
+		SET user_id = CASE WHEN user_id = $2 THEN $1 ELSE user_id END,
+			created_by = CASE WHEN created_by = $2 THEN $1 ELSE created_by END
+		WHERE user_id = $2 OR created_by = $2`); err != nil {
+		return nil, err
+	}
+	// UNIQUE(event_id, user_id, channel): если о событии уведомлены оба аккаунта,
+	// остается уведомление целевого пользователя
+	if summary.NotificationsMerged, err = exec("notifications", `
+		DELETE FROM notifications n
+		WHERE n.user_id = $2
+			AND EXISTS (
+				SELECT 1 FROM notifications own
+				WHERE own.user_id = $1 AND own.event_id = n.event_id AND own.channel = n.channel
+			)`); err != nil {
+		return nil, err
+	}
+	if summary.NotificationsMoved, err = exec("notifications", `
+		UPDATE notifications SET user_id = $1 WHERE user_id = $2`); err != nil {
+		return nil, err
+	}
+	// Настройки целевого пользователя важнее: переносятся только те, которых у него нет.
+	// Остальные удалятся вместе с исходным пользователем
+	if summary.PreferencesMoved, err = exec("notification preferences", `
+		UPDATE notification_preferences p
+		SET user_id = $1
+		WHERE p.user_id = $2
+			AND NOT EXISTS (
+				SELECT 1 FROM notification_preferences own
+				WHERE own.user_id = $1 AND own.channel = p.channel AND own.event_type = p.event_type
+			)`); err != nil {
+		return nil, err
+	}
+	if _, err = exec("guest claims", `
+		UPDATE guest_claims SET created_by = $1 WHERE created_by = $2`); err != nil {
+		return nil, err
+	}
+	if _, err = exec("group invites", `
+		UPDATE group_invites SET created_by = $1 WHERE created_by = $2`); err != nil {
+		return nil, err
+	}
+	// API ключи продолжают работать от имени целевого пользователя: интеграции
+	// не ломаются после слияния, а ключи не удаляются вместе с исходным
+	if summary.APIKeysMoved, err = exec("api keys", `
+		UPDATE api_keys SET user_id = $1 WHERE user_id = $2`); err != nil {
+		return nil, err
+	}
+	// Аккаунты провайдера входа переходят к целевому пользователю, иначе вход
+	// через них создал бы дубликат заново. Сессии исходного удалятся вместе с ним
+	if summary.IdentitiesMoved, err = exec("identities", `
+		UPDATE user_identities SET user_id = $1 WHERE user_id = $2`); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// GetAll возвращает журнал слияний, новые первыми. Если userID не ноль -
+// только слияния в этого пользователя
+func (r *MergeRepository) GetAll(ctx context.Context, userID int) ([]*models.UserMerge, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, COALESCE(target_user_id, 0), source_user_id, source_name, COALESCE(source_email, ''),
+			summary, created_at
+		FROM user_merges
+		WHERE $1 = 0 OR target_user_id = $1
+		ORDER BY created_at DESC, id DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merges: %w", err)
+	}
+	defer rows.Close()
+
+	var merges []*models.UserMerge
+	for rows.Next() {
+		var (
+			m       models.UserMerge
+			summary []byte
+		)
+		err := rows.Scan(&m.ID, &m.TargetUserID, &m.SourceUserID, &m.SourceName, &m.SourceEmail,
+			&summary, &m.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan merge: %w", err)
+		}
+		if err := json.Unmarshal(summary, &m.Summary); err != nil {
+			return nil, fmt.Errorf("failed to decode merge summary: %w", err)
+		}
+		merges = append(merges, &m)
+	}
+
+	return merges, rows.Err()
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"balance/internal/database/dbtest"
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// countRows возвращает число строк запроса query с аргументами args
+func countRows(t *testing.T, db *pgxpool.Pool, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(context.Background(), `SELECT COUNT(*) FROM (`+query+`) q`, args...).Scan(&n); err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	return n
+}
+
+func TestMergeOverlappingGroupsAndNotifications(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	groups := NewGroupRepository(db)
+
+	target, err := users.Create(ctx, &models.CreateUserRequest{Name: "Ivan", Email: dbtest.Email("ivan")}, "hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	source, err := users.Create(ctx, &models.CreateUserRequest{Name: "Ivan 2", Email: dbtest.Email("ivan2")}, "hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	// В shared состоят оба аккаунта, в own - только исходный
+	shared, err := groups.Create(ctx, &models.CreateGroupRequest{Name: "Shared", CreatedBy: target.ID})
+	if err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	if _, err := groups.AddMember(ctx, shared.ID, source.ID); err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+	own, err := groups.Create(ctx, &models.CreateGroupRequest{Name: "Own", CreatedBy: source.ID})
+	if err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+
+	debt, err := NewDebtRepository(db).Create(ctx, &models.CreateDebtRequest{
+		GroupID: shared.ID, FromUserID: source.ID, ToUserID: target.ID, Amount: 12.5, CreatedBy: source.ID,
+	}, models.DebtStatusActive)
+	if err != nil {
+		t.Fatalf("failed to create debt: %v", err)
+	}
+
+	// О первом событии уведомлены оба аккаунта, о втором - только исходный
+	var events [2]int64
+	for i := range events {
+		err := db.QueryRow(ctx, `INSERT INTO outbox_events (event_type, payload) VALUES ($1, '{}') RETURNING id`,
+			models.EventDebtCreated).Scan(&events[i])
+		if err != nil {
+			t.Fatalf("failed to create event: %v", err)
+		}
+	}
+	for _, n := range []struct {
+		event  int64
+		userID int
+	}{{events[0], target.ID}, {events[0], source.ID}, {events[1], source.ID}} {
+		_, err := db.Exec(ctx, `
+			INSERT INTO notifications (event_id, user_id, channel, event_type, subject, body)
+			VALUES ($1, $2, 'log', $3, 'Debt', 'New debt')`,
+			n.event, n.userID, models.EventDebtCreated)
+		if err != nil {
+			t.Fatalf("failed to create notification: %v", err)
+		}
+	}
+
+	notifications := NewNotificationRepository(db)
+	err = notifications.SavePreferences(ctx, target.ID, []models.NotificationPreference{
+		{Channel: "log", EventType: models.EventDebtCreated, Enabled: false},
+	})
+	if err != nil {
+		t.Fatalf("failed to save preferences: %v", err)
+	}
+	err = notifications.SavePreferences(ctx, source.ID, []models.NotificationPreference{
+		{Channel: "log", EventType: models.EventDebtCreated, Enabled: true},
+		{Channel: "email", EventType: models.EventDebtCreated, Enabled: false},
+	})
+	if err != nil {
+		t.Fatalf("failed to save preferences: %v", err)
+	}
+
+	merge, err := NewMergeRepository(db).Merge(ctx, target.ID, source.ID, false)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	s := merge.Summary
+	if s.MembershipsMerged != 1 || s.MembershipsMoved != 1 {
+		t.Errorf("memberships merged %d, moved %d; want 1 and 1", s.MembershipsMerged, s.MembershipsMoved)
+	}
+	for _, g := range []int{shared.ID, own.ID} {
+		if n := countRows(t, db, `SELECT 1 FROM group_members WHERE group_id = $1 AND user_id = $2`, g, target.ID); n != 1 {
+			t.Errorf("target is a member of group %d %d times, want once", g, n)
+		}
+	}
+
+	if len(s.SelfDebts) != 1 || len(s.SelfDebtsDeleted) != 1 {
+		t.Fatalf("self debts %+v (IDs %v), want the debt %d", s.SelfDebts, s.SelfDebtsDeleted, debt.ID)
+	}
+	deleted := s.SelfDebts[0]
+	if deleted.ID != debt.ID || deleted.Amount != 12.5 || deleted.FromUserID != source.ID ||
+		deleted.ToUserID != target.ID || deleted.Status != models.DebtStatusActive || deleted.GroupID != shared.ID {
+		t.Errorf("deleted debt %+v does not match %+v", deleted, debt)
+	}
+
+	if s.NotificationsMerged != 1 || s.NotificationsMoved != 1 {
+		t.Errorf("notifications merged %d, moved %d; want 1 and 1", s.NotificationsMerged, s.NotificationsMoved)
+	}
+	if n := countRows(t, db, `SELECT 1 FROM notifications WHERE user_id = $1`, target.ID); n != 2 {
+		t.Errorf("target has %d notifications, want 2", n)
+	}
+
+	if s.PreferencesMoved != 1 {
+		t.Errorf("preferences moved %d, want 1", s.PreferencesMoved)
+	}
+	preferences, err := notifications.GetPreferences(ctx, target.ID)
+	if err != nil {
+		t.Fatalf("GetPreferences: %v", err)
+	}
+	// Своя настройка log целевого пользователя сохраняется, email приходит от исходного
+	if len(preferences) != 2 {
+		t.Errorf("target has preferences %+v, want log and email", preferences)
+	}
+	for _, p := range preferences {
+		if p.Enabled {
+			t.Errorf("preference %+v is enabled, want both disabled", p)
+		}
+	}
+
+	// Журнал читается обратно с подробностями удаленных долгов
+	merges, err := NewMergeRepository(db).GetAll(ctx, target.ID)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(merges) != 1 || len(merges[0].Summary.SelfDebts) != 1 {
+		t.Errorf("GetAll returned %+v, want one merge with the deleted debt", merges)
+	}
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type NotificationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationRepository(db *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// ClaimDue выбирает до limit уведомлений, которые пора отправить, и сдвигает их
+// next_attempt_at на lease вперед. Пока отправка идет, уведомление не возьмет другой
+// экземпляр сервера, а если процесс упадет, оно вернется в работу после истечения lease
+func (r *NotificationRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.Notification, error) {
+	query := `
+		WITH due AS (
+			SELECT id FROM notifications
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE notifications n
+		SET attempts = n.attempts + 1,
+			next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2::integer)
+		FROM due, users u
+		WHERE n.id = due.id AND u.id = n.user_id
+		RETURNING n.id, n.event_id, n.user_id, n.channel, n.event_type, n.subject, n.body,
+			n.status, n.attempts, n.next_attempt_at, COALESCE(n.last_error, ''), n.created_at,
+			u.id, u.name, COALESCE(u.email, ''), u.is_guest`
+
+	rows, err := r.db.Query(ctx, query, limit, int(lease.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		n := models.Notification{User: &models.User{}}
+		err := rows.Scan(
+			&n.ID, &n.EventID, &n.UserID, &n.Channel, &n.EventType, &n.Subject, &n.Body,
+			&n.Status, &n.Attempts, &n.NextAttemptAt, &n.LastError, &n.CreatedAt,
+			&n.User.ID, &n.User.Name, &n.User.Email, &n.User.Guest,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, &n)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (r *NotificationRepository) MarkSent(ctx context.Context, id int) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE notifications
+		SET status = 'sent', sent_at = CURRENT_TIMESTAMP, last_error = NULL
+		WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification sent: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed сохраняет ошибку отправки. Если retryIn > 0, уведомление будет отправлено
+// повторно через retryIn, иначе оно окончательно помечается как failed
+func (r *NotificationRepository) MarkFailed(ctx context.Context, id int, sendErr error, retryIn time.Duration) error {
+	status := models.NotificationStatusPending
+	if retryIn <= 0 {
+		status = models.NotificationStatusFailed
+	}
+
+	_, err := r.db.Exec(ctx, `
+		UPDATE notifications
+		SET status = $2, last_error = $3,
+			next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $4::integer)
+		WHERE id = $1`, id, status, sendErr.Error(), int(retryIn.Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to mark notification failed: %w", err)
+	}
+	return nil
+}
+
+// GetPreferences возвращает явно сохраненные настройки уведомлений пользователя
+func (r *NotificationRepository) GetPreferences(ctx context.Context, userID int) ([]models.NotificationPreference, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT channel, event_type, enabled
+		FROM notification_preferences
+		WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	var preferences []models.NotificationPreference
+	for rows.Next() {
+		var p models.NotificationPreference
+		if err := rows.Scan(&p.Channel, &p.EventType, &p.Enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		preferences = append(preferences, p)
+	}
+
+	return preferences, rows.Err()
+}
+
+func (r *NotificationRepository) SavePreferences(ctx context.Context, userID int, preferences []models.NotificationPreference) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, p := range preferences {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO notification_preferences (user_id, channel, event_type, enabled)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, channel, event_type) DO UPDATE SET enabled = EXCLUDED.enabled`,
+			userID, p.Channel, p.EventType, p.Enabled,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save notification preference: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
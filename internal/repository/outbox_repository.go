@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// execer - общее подмножество методов pgxpool.Pool и pgx.Tx для записи
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// insertEvent пишет событие в outbox и создает доставки для подходящих подписок вебхуков.
+// Вызывается внутри транзакции доменного изменения, поэтому событие и доставки
+// появляются тогда и только тогда, когда изменение закоммичено
+func insertEvent(ctx context.Context, db execer, eventType string, payload interface{}, scope models.EventScope) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	userIDs := scope.UserIDs
+	if userIDs == nil {
+		userIDs = []int{}
+	}
+
+	_, err = db.Exec(ctx, `
+		WITH event AS (
+			INSERT INTO outbox_events (event_type, payload)
+			VALUES ($1, $2)
+			RETURNING id
+		)
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type)
+		SELECT w.id, event.id, $1
+		FROM webhooks w, event
+		WHERE w.active
+			AND $1 = ANY(w.event_types)
+			AND (w.group_id = $3 OR w.user_id = ANY($4))`,
+		eventType, data, scope.GroupID, userIDs,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write outbox event: %w", err)
+	}
+	return nil
+}
+
+type OutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Enqueue пишет событие, которое не связано с доменной транзакцией (например, напоминание)
+func (r *OutboxRepository) Enqueue(ctx context.Context, eventType string, payload interface{}, scope models.EventScope) error {
+	return insertEvent(ctx, r.db, eventType, payload, scope)
+}
+
+// ProcessEvents берет до limit необработанных событий и для каждого в одной транзакции
+// сохраняет уведомления, которые вернул expand, и отмечает событие обработанным.
+// События блокируются через FOR UPDATE SKIP LOCKED, поэтому несколько экземпляров
+// сервера не обработают одно событие дважды. Возвращает количество обработанных событий
+func (r *OutboxRepository) ProcessEvents(ctx context.Context, limit int,
+	expand func(ctx context.Context, event *models.Event) ([]*models.Notification, error)) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, event_type, payload, created_at
+		FROM outbox_events
+		WHERE processed_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get outbox events: %w", err)
+	}
+	var events []*models.Event
+	for rows.Next() {
+		var event models.Event
+		if err := rows.Scan(&event.ID, &event.Type, &event.Payload, &event.CreatedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, &event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get outbox events: %w", err)
+	}
+
+	for _, event := range events {
+		notifications, err := expand(ctx, event)
+		if err != nil {
+			return 0, fmt.Errorf("failed to expand event %d: %w", event.ID, err)
+		}
+
+		for _, n := range notifications {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO notifications (event_id, user_id, channel, event_type, subject, body)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT (event_id, user_id, channel) DO NOTHING`,
+				event.ID, n.UserID, n.Channel, event.Type, n.Subject, n.Body,
+			)
+			if err != nil {
+				return 0, fmt.Errorf("failed to create notification: %w", err)
+			}
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE outbox_events SET processed_at = CURRENT_TIMESTAMP WHERE id = $1`, event.ID); err != nil {
+			return 0, fmt.Errorf("failed to mark outbox event processed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(events), nil
+}
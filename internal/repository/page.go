@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"fmt"
+
+	"balance/internal/models"
+)
+
+// pageClause возвращает LIMIT и OFFSET страницы page для конца запроса и добавляет их в args
+func pageClause(page models.Page, args []interface{}) (string, []interface{}) {
+	var clause string
+	if page.Limit > 0 {
+		args = append(args, page.Limit)
+		clause += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if page.Offset > 0 {
+		args = append(args, page.Offset)
+		clause += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return clause, args
+}
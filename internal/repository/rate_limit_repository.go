@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"balance/internal/ratelimit"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// refilledTokens - токены в корзине b к текущему моменту: за каждую секунду
+// простоя добавляется $3 токенов, но не больше емкости $2
+const refilledTokens = `LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at)::float8, 0) * $3::float8)`
+
+// RateLimitRepository хранит корзины ограничения частоты запросов в Postgres,
+// чтобы лимиты были общими для всех экземпляров сервера. Реализует ratelimit.Store
+type RateLimitRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRateLimitRepository(db *pgxpool.Pool) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Take забирает токен из корзины key одним запросом под блокировкой строки.
+// Время берется из базы, чтобы часы экземпляров не влияли на лимиты. Нужен
+// clock_timestamp(): CURRENT_TIMESTAMP - время начала транзакции, и запрос,
+// дождавшийся блокировки, записал бы время раньше уже сохраненного
+func (r *RateLimitRepository) Take(ctx context.Context, key string, limit ratelimit.Limit) (float64, bool, error) {
+	var tokens float64
+	var allowed bool
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO rate_limits AS b (key, tokens, allowed, updated_at, expires_at)
+		VALUES ($1, $2::float8 - 1, TRUE, clock_timestamp(), clock_timestamp() + make_interval(secs => $4))
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = CASE WHEN `+refilledTokens+` >= 1 THEN `+refilledTokens+` - 1 ELSE `+refilledTokens+` END,
+			allowed = `+refilledTokens+` >= 1,
+			updated_at = clock_timestamp(),
+			expires_at = clock_timestamp() + make_interval(secs => $4)
+		RETURNING tokens, allowed`,
+		key, float64(limit.Requests), limit.Rate(), limit.Period.Seconds(),
+	).Scan(&tokens, &allowed)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	return tokens, allowed, nil
+}
+
+// DeleteExpired удаляет корзины, которые уже наполнились: новая корзина будет такой же
+func (r *RateLimitRepository) DeleteExpired(ctx context.Context) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM rate_limits WHERE expires_at <= clock_timestamp()`); err != nil {
+		return fmt.Errorf("failed to delete expired rate limits: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// seriesColumns - список колонок, которые читаются в models.RecurringSeries через scanSeries
+const seriesColumns = `id, COALESCE(group_id, 0), to_user_id, COALESCE(description, ''), frequency, repeat_interval,
+	COALESCE(month_day, 0), start_at, until_at, COALESCE(max_count, 0), status, occurrences,
+	last_run_at, next_run_at, created_by, created_at, updated_at`
+
+type RecurringRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRecurringRepository(db *pgxpool.Pool) *RecurringRepository {
+	return &RecurringRepository{db: db}
+}
+
+func scanSeries(row pgx.Row) (*models.RecurringSeries, error) {
+	var s models.RecurringSeries
+	err := row.Scan(
+		&s.ID, &s.GroupID, &s.ToUserID, &s.Description, &s.Frequency, &s.Interval,
+		&s.MonthDay, &s.StartAt, &s.Until, &s.Count, &s.Status, &s.Occurrences,
+		&s.LastRunAt, &s.NextRunAt, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *RecurringRepository) Create(ctx context.Context, req *models.CreateRecurringSeriesRequest, nextRunAt time.Time) (*models.RecurringSeries, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO recurring_series (group_id, to_user_id, description, frequency, repeat_interval,
+			month_day, start_at, until_at, max_count, next_run_at, created_by)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, NULLIF($6, 0), $7, $8, NULLIF($9, 0), $10, $11)
+		RETURNING ` + seriesColumns
+
+	series, err := scanSeries(tx.QueryRow(ctx, query,
+		req.GroupID, req.ToUserID, req.Description, req.Frequency, req.Interval,
+		req.MonthDay, req.StartAt, req.Until, req.Count, nextRunAt, req.CreatedBy,
+	))
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return nil, fmt.Errorf("group or user not found")
+		}
+		return nil, fmt.Errorf("failed to create recurring series: %w", err)
+	}
+
+	if err := insertShares(ctx, tx, series.ID, req.Shares); err != nil {
+		return nil, err
+	}
+	series.Shares = req.Shares
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return series, nil
+}
+
+func (r *RecurringRepository) GetByID(ctx context.Context, id int) (*models.RecurringSeries, error) {
+	query := `SELECT ` + seriesColumns + ` FROM recurring_series WHERE id = $1`
+
+	series, err := scanSeries(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("recurring series not found")
+		}
+		return nil, fmt.Errorf("failed to get recurring series: %w", err)
+	}
+
+	if err := loadShares(ctx, r.db, []*models.RecurringSeries{series}); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// GetAll возвращает серии группы и/или серии, в которых участвует пользователь.
+// Нулевые groupID и userID не фильтруют
+func (r *RecurringRepository) GetAll(ctx context.Context, groupID, userID int) ([]*models.RecurringSeries, error) {
+	var conditions []string
+	var args []interface{}
+
+	if groupID != 0 {
+		args = append(args, groupID)
+		conditions = append(conditions, fmt.Sprintf("group_id = $%d", len(args)))
+	}
+	if userID != 0 {
+		args = append(args, userID)
+		conditions = append(conditions, fmt.Sprintf(`(to_user_id = $%d OR id IN (
+			SELECT series_id FROM recurring_shares WHERE from_user_id = $%d))`, len(args), len(args)))
+	}
+
+	query := `SELECT ` + seriesColumns + ` FROM recurring_series`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recurring series: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*models.RecurringSeries
+	for rows.Next() {
+		series, err := scanSeries(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recurring series: %w", err)
+		}
+		result = append(result, series)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get recurring series: %w", err)
+	}
+
+	if err := loadShares(ctx, r.db, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Update сохраняет редактируемые поля серии и заменяет ее доли
+func (r *RecurringRepository) Update(ctx context.Context, series *models.RecurringSeries) (*models.RecurringSeries, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE recurring_series
+		SET description = $2, frequency = $3, repeat_interval = $4, month_day = NULLIF($5, 0),
+			start_at = $6, until_at = $7, max_count = NULLIF($8, 0), status = $9, next_run_at = $10,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + seriesColumns
+
+	updated, err := scanSeries(tx.QueryRow(ctx, query,
+		series.ID, series.Description, series.Frequency, series.Interval, series.MonthDay,
+		series.StartAt, series.Until, series.Count, series.Status, series.NextRunAt,
+	))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("recurring series not found")
+		}
+		return nil, fmt.Errorf("failed to update recurring series: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recurring_shares WHERE series_id = $1`, series.ID); err != nil {
+		return nil, fmt.Errorf("failed to update shares: %w", err)
+	}
+	if err := insertShares(ctx, tx, series.ID, series.Shares); err != nil {
+		return nil, err
+	}
+	updated.Shares = series.Shares
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return updated, nil
+}
+
+// RunDue создает долги для одного наступившего повторения каждой из не более чем limit серий.
+// plan вычисляет, какое повторение создать и когда следующее.
+//
+// Серии блокируются через FOR UPDATE SKIP LOCKED, а долги создаются в той же транзакции,
+// что и сдвиг next_run_at, поэтому каждое повторение создается ровно один раз - и после
+// перезапуска, и при нескольких экземплярах сервера. Уникальный индекс
+// (series_id, occurrence_at, from_user_id) дополнительно защищает от дублей.
+// Возвращает количество обработанных серий
+func (r *RecurringRepository) RunDue(ctx context.Context, now time.Time, limit int,
+	plan func(*models.RecurringSeries) models.SeriesRun) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT ` + seriesColumns + `
+		FROM recurring_series
+		WHERE status = 'active' AND next_run_at <= $1
+		ORDER BY next_run_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.Query(ctx, query, now, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due series: %w", err)
+	}
+	var due []*models.RecurringSeries
+	for rows.Next() {
+		series, err := scanSeries(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan recurring series: %w", err)
+		}
+		due = append(due, series)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get due series: %w", err)
+	}
+
+	if err := loadShares(ctx, tx, due); err != nil {
+		return 0, err
+	}
+
+	for _, series := range due {
+		run := plan(series)
+
+		for _, share := range series.Shares {
+			// Как и в DebtService.CreateDebt: подтверждения не ждут долги, записанные
+			// самим должником, и долги гостей, которые подтвердить их не могут
+			status := models.DebtStatusProposed
+			if series.CreatedBy == share.FromUserID {
+				status = models.DebtStatusActive
+			} else {
+				var guest bool
+				err := tx.QueryRow(ctx, `SELECT is_guest FROM users WHERE id = $1`, share.FromUserID).Scan(&guest)
+				if err != nil {
+					return 0, fmt.Errorf("failed to get debtor of series %d: %w", series.ID, err)
+				}
+				if guest {
+					status = models.DebtStatusActive
+				}
+			}
+
+			debt, err := scanDebt(tx.QueryRow(ctx, `
+				INSERT INTO debts (group_id, from_user_id, to_user_id, amount, description, status,
+					created_by, series_id, occurrence_at)
+				VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8, $9)
+				ON CONFLICT (series_id, occurrence_at, from_user_id) WHERE series_id IS NOT NULL DO NOTHING
+				RETURNING `+debtColumns,
+				series.GroupID, share.FromUserID, series.ToUserID, share.Amount, series.Description,
+				status, series.CreatedBy, series.ID, run.OccurrenceAt,
+			))
+			if err == pgx.ErrNoRows {
+				// Долг для этого повторения уже создан
+				continue
+			}
+			if err != nil {
+				return 0, fmt.Errorf("failed to create debt for series %d: %w", series.ID, err)
+			}
+
+			if err := insertEvent(ctx, tx, models.EventDebtCreated, debt, models.DebtEventScope(debt)); err != nil {
+				return 0, err
+			}
+		}
+
+		status := models.SeriesStatusActive
+		if run.NextRunAt == nil {
+			status = models.SeriesStatusEnded
+		}
+
+		_, err := tx.Exec(ctx, `
+			UPDATE recurring_series
+			SET occurrences = occurrences + 1, last_run_at = $2, next_run_at = $3, status = $4,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1`,
+			series.ID, run.OccurrenceAt, run.NextRunAt, status,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to advance series %d: %w", series.ID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(due), nil
+}
+
+// querier - общее подмножество методов pgxpool.Pool и pgx.Tx
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+func loadShares(ctx context.Context, q querier, series []*models.RecurringSeries) error {
+	if len(series) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(series))
+	byID := make(map[int]*models.RecurringSeries, len(series))
+	for i, s := range series {
+		ids[i] = s.ID
+		byID[s.ID] = s
+		s.Shares = []models.RecurringShare{}
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT series_id, from_user_id, amount
+		FROM recurring_shares
+		WHERE series_id = ANY($1)
+		ORDER BY series_id, from_user_id`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get shares: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seriesID int
+		var share models.RecurringShare
+		if err := rows.Scan(&seriesID, &share.FromUserID, &share.Amount); err != nil {
+			return fmt.Errorf("failed to scan share: %w", err)
+		}
+		byID[seriesID].Shares = append(byID[seriesID].Shares, share)
+	}
+
+	return rows.Err()
+}
+
+func insertShares(ctx context.Context, tx pgx.Tx, seriesID int, shares []models.RecurringShare) error {
+	for _, share := range shares {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO recurring_shares (series_id, from_user_id, amount)
+			VALUES ($1, $2, $3)`,
+			seriesID, share.FromUserID, share.Amount,
+		)
+		if err != nil {
+			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+				return fmt.Errorf("group or user not found")
+			}
+			return fmt.Errorf("failed to create share: %w", err)
+		}
+	}
+	return nil
+}
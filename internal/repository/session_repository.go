@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SessionRepository хранит сессии пользователей. В базе хранится только хеш токена
+type SessionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepository(db *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Create сохраняет сессию пользователя userID. Заодно удаляются истекшие сессии
+func (r *SessionRepository) Create(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM sessions WHERE expires_at <= CURRENT_TIMESTAMP`); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO sessions (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		userID, tokenHash, expiresAt,
+	)
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+// GetUser возвращает владельца действующей сессии и отмечает время ее использования
+func (r *SessionRepository) GetUser(ctx context.Context, tokenHash string) (*models.User, error) {
+	query := `
+		WITH s AS (
+			UPDATE sessions SET last_used_at = CURRENT_TIMESTAMP
+			WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+			RETURNING user_id
+		)
+		SELECT u.id, u.name, COALESCE(u.email, ''), u.email_verified, u.is_guest, u.created_at, u.updated_at
+		FROM users u
+		JOIN s ON s.user_id = u.id`
+
+	var user models.User
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&user.ID, &user.Name, &user.Email, &user.EmailVerified, &user.Guest, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("session not found")
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return &user, nil
+}
+
+// Delete удаляет сессию (выход)
+func (r *SessionRepository) Delete(ctx context.Context, tokenHash string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM sessions WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("session not found")
+	}
+
+	return nil
+}
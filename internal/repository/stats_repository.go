@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// StatsRepository считает сводные показатели для метрик
+type StatsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewStatsRepository(db *pgxpool.Pool) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// Get возвращает текущие показатели
+func (r *StatsRepository) Get(ctx context.Context) (*models.Stats, error) {
+	stats := &models.Stats{
+		Debts:       make(map[string]int),
+		DebtAmounts: make(map[string]float64),
+	}
+
+	err := r.db.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM users WHERE NOT is_guest),
+			(SELECT COUNT(*) FROM users WHERE is_guest),
+			(SELECT COUNT(*) FROM groups),
+			(SELECT COUNT(*) FROM recurring_series WHERE status = 'active'),
+			(SELECT COUNT(*) FROM notifications WHERE status = 'pending'),
+			(SELECT COUNT(*) FROM webhook_deliveries WHERE status = 'pending')`,
+	).Scan(&stats.Users, &stats.Guests, &stats.Groups, &stats.ActiveSeries,
+		&stats.PendingNotifications, &stats.PendingDeliveries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT status, COUNT(*), COALESCE(SUM(amount), 0)::float8 FROM debts GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get debt stats: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var count int
+		var amount float64
+		if err := rows.Scan(&status, &count, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan debt stats: %w", err)
+		}
+		stats.Debts[status] = count
+		stats.DebtAmounts[status] = amount
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxChallengeAttempts - сколько кодов можно ввести по одному токену второго шага входа
+const maxChallengeAttempts = 5
+
+// RecoveryCode - хеш кода восстановления
+type RecoveryCode struct {
+	ID   int
+	Hash string
+}
+
+// TwoFactorRepository хранит секреты TOTP, коды восстановления и незавершенные
+// входы, ожидающие второго фактора
+type TwoFactorRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTwoFactorRepository(db *pgxpool.Pool) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// Get возвращает секрет TOTP пользователя (пустой, если не задан) и включена ли двухфакторная аутентификация
+func (r *TwoFactorRepository) Get(ctx context.Context, userID int) (secret string, enabled bool, err error) {
+	err = r.db.QueryRow(ctx, `
+		SELECT COALESCE(totp_secret, ''), totp_enabled FROM users WHERE id = $1`,
+		userID,
+	).Scan(&secret, &enabled)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", false, fmt.Errorf("user not found")
+		}
+		return "", false, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	return secret, enabled, nil
+}
+
+// SetSecret сохраняет новый секрет, пока двухфакторная аутентификация не включена
+func (r *TwoFactorRepository) SetSecret(ctx context.Context, userID int, secret string) error {
+	var enabled bool
+	err := r.db.QueryRow(ctx, `
+		UPDATE users
+		SET totp_secret = CASE WHEN totp_enabled THEN totp_secret ELSE $2 END,
+			totp_last_step = CASE WHEN totp_enabled THEN totp_last_step END
+		WHERE id = $1
+		RETURNING totp_enabled`,
+		userID, secret,
+	).Scan(&enabled)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to set two-factor secret: %w", err)
+	}
+	if enabled {
+		return fmt.Errorf("cannot enroll while two-factor authentication is enabled")
+	}
+	return nil
+}
+
+// Enable включает двухфакторную аутентификацию с секретом secret и заменяет коды восстановления.
+// step - шаг кода, которым пользователь подтвердил секрет
+func (r *TwoFactorRepository) Enable(ctx context.Context, userID int, secret string, step int64, recoveryHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Секрет сверяется, чтобы не включить 2FA с секретом, который успели заменить
+	result, err := tx.Exec(ctx, `
+		UPDATE users SET totp_enabled = TRUE, totp_last_step = $3
+		WHERE id = $1 AND totp_secret = $2 AND NOT totp_enabled`,
+		userID, secret, step,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("two-factor secret has changed, try again")
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Disable выключает двухфакторную аутентификацию и удаляет секрет и коды восстановления
+func (r *TwoFactorRepository) Disable(ctx context.Context, userID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users SET totp_enabled = FALSE, totp_secret = NULL, totp_last_step = NULL
+		WHERE id = $1`,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes заменяет коды восстановления пользователя новыми
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, recoveryHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int, recoveryHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO recovery_codes (user_id, code_hash)
+		SELECT $1, unnest($2::text[])`,
+		userID, recoveryHashes,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+// UseStep принимает код TOTP с шагом step, только если шаг больше последнего
+// принятого: один и тот же код нельзя использовать дважды
+func (r *TwoFactorRepository) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND totp_enabled AND (totp_last_step IS NULL OR totp_last_step < $2)`,
+		userID, step,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to use two-factor code: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// GetRecoveryCodes возвращает неиспользованные коды восстановления пользователя
+func (r *TwoFactorRepository) GetRecoveryCodes(ctx context.Context, userID int) ([]RecoveryCode, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, code_hash FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL ORDER BY id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recovery codes: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []RecoveryCode
+	for rows.Next() {
+		var c RecoveryCode
+		if err := rows.Scan(&c.ID, &c.Hash); err != nil {
+			return nil, fmt.Errorf("failed to scan recovery code: %w", err)
+		}
+		codes = append(codes, c)
+	}
+
+	return codes, rows.Err()
+}
+
+// UseRecoveryCode отмечает код восстановления использованным. false - код уже использован
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, id int) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL`,
+		id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// CreateChallenge сохраняет вход, ожидающий второго фактора. Заодно удаляются истекшие
+func (r *TwoFactorRepository) CreateChallenge(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM login_challenges WHERE expires_at <= CURRENT_TIMESTAMP`); err != nil {
+		return fmt.Errorf("failed to create login challenge: %w", err)
+	}
+
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO login_challenges (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		userID, tokenHash, expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create login challenge: %w", err)
+	}
+
+	return nil
+}
+
+// AttemptChallenge засчитывает попытку ввода кода и возвращает пользователя.
+// После maxChallengeAttempts попыток или по истечении срока токен не принимается
+func (r *TwoFactorRepository) AttemptChallenge(ctx context.Context, tokenHash string) (int, error) {
+	var userID int
+	err := r.db.QueryRow(ctx, `
+		UPDATE login_challenges SET attempts = attempts + 1
+		WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP AND attempts < $2
+		RETURNING user_id`,
+		tokenHash, maxChallengeAttempts,
+	).Scan(&userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, fmt.Errorf("login challenge not found")
+		}
+		return 0, fmt.Errorf("failed to get login challenge: %w", err)
+	}
+	return userID, nil
+}
+
+// DeleteChallenge удаляет вход после успешного второго шага. Сессию получает
+// только тот запрос, который удалил токен
+func (r *TwoFactorRepository) DeleteChallenge(ctx context.Context, tokenHash string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM login_challenges WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete login challenge: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("login challenge not found")
+	}
+	return nil
+}
@@ -8,26 +8,28 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UserRepository struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
-func NewUserRepository(db *pgx.Conn) *UserRepository {
+func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) Create(ctx context.Context, user *models.CreateUserRequest) (*models.User, error) {
+// Create создает пользователя. passwordHash - bcrypt хеш пароля или пустая строка
+func (r *UserRepository) Create(ctx context.Context, user *models.CreateUserRequest, passwordHash string) (*models.User, error) {
 	query := `
-		INSERT INTO users (name, email) 
-		VALUES ($1, $2) 
-		RETURNING id, name, email, created_at, updated_at
+		INSERT INTO users (name, email, password_hash) 
+		VALUES ($1, $2, NULLIF($3, '')) 
+		RETURNING id, name, COALESCE(email, ''), email_verified, is_guest, created_at, updated_at
 	`
 	
 	var result models.User
-	err := r.db.QueryRow(ctx, query, user.Name, user.Email).Scan(
-		&result.ID, &result.Name, &result.Email, &result.CreatedAt, &result.UpdatedAt,
+	err := r.db.QueryRow(ctx, query, user.Name, user.Email, passwordHash).Scan(
+		&result.ID, &result.Name, &result.Email, &result.EmailVerified, &result.Guest, &result.CreatedAt, &result.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -42,14 +44,14 @@ func (r *UserRepository) Create(ctx context.Context, user *models.CreateUserRequ
 
 func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
 	query := `
-		SELECT id, name, email, created_at, updated_at 
+		SELECT id, name, COALESCE(email, ''), email_verified, is_guest, created_at, updated_at 
 		FROM users 
 		WHERE id = $1
 	`
 	
 	var user models.User
 	err := r.db.QueryRow(ctx, query, id).Scan(
-		&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Name, &user.Email, &user.EmailVerified, &user.Guest, &user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -64,14 +66,14 @@ func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, err
 
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, name, email, created_at, updated_at 
+		SELECT id, name, COALESCE(email, ''), email_verified, is_guest, created_at, updated_at 
 		FROM users 
 		WHERE email = $1
 	`
 	
 	var user models.User
 	err := r.db.QueryRow(ctx, query, email).Scan(
-		&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Name, &user.Email, &user.EmailVerified, &user.Guest, &user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -84,14 +86,15 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 	return &user, nil
 }
 
-func (r *UserRepository) GetAll(ctx context.Context) ([]*models.User, error) {
+// GetAll возвращает страницу всех пользователей
+func (r *UserRepository) GetAll(ctx context.Context, page models.Page) ([]*models.User, error) {
 	query := `
-		SELECT id, name, email, created_at, updated_at 
+		SELECT id, name, COALESCE(email, ''), email_verified, is_guest, created_at, updated_at 
 		FROM users 
-		ORDER BY created_at DESC
-	`
+		ORDER BY created_at DESC, id DESC`
 	
-	rows, err := r.db.Query(ctx, query)
+	clause, args := pageClause(page, nil)
+	rows, err := r.db.Query(ctx, query+clause, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get users: %w", err)
 	}
@@ -100,7 +103,7 @@ func (r *UserRepository) GetAll(ctx context.Context) ([]*models.User, error) {
 	var users []*models.User
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.EmailVerified, &user.Guest, &user.CreatedAt, &user.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
 		users = append(users, &user)
@@ -112,14 +115,15 @@ func (r *UserRepository) GetAll(ctx context.Context) ([]*models.User, error) {
 func (r *UserRepository) Update(ctx context.Context, id int, user *models.UpdateUserRequest) (*models.User, error) {
 	query := `
 		UPDATE users 
-		SET name = $1, email = $2, updated_at = CURRENT_TIMESTAMP 
+		SET name = $1, email = $2, updated_at = CURRENT_TIMESTAMP,
+			email_verified = email_verified AND email IS NOT DISTINCT FROM $2
 		WHERE id = $3 
-		RETURNING id, name, email, created_at, updated_at
+		RETURNING id, name, COALESCE(email, ''), email_verified, is_guest, created_at, updated_at
 	`
 	
 	var result models.User
 	err := r.db.QueryRow(ctx, query, user.Name, user.Email, id).Scan(
-		&result.ID, &result.Name, &result.Email, &result.CreatedAt, &result.UpdatedAt,
+		&result.ID, &result.Name, &result.Email, &result.EmailVerified, &result.Guest, &result.CreatedAt, &result.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -148,4 +152,17 @@ func (r *UserRepository) Delete(ctx context.Context, id int) error {
 	}
 	
 	return nil
-} 
\ No newline at end of file
+} 
+// GetPasswordHash возвращает bcrypt хеш пароля пользователя или пустую строку, если пароль не задан
+func (r *UserRepository) GetPasswordHash(ctx context.Context, id int) (string, error) {
+	var hash string
+	err := r.db.QueryRow(ctx, `SELECT COALESCE(password_hash, '') FROM users WHERE id = $1`, id).Scan(&hash)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", fmt.Errorf("user not found")
+		}
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return hash, nil
+}
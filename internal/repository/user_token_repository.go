@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UserTokenRepository хранит одноразовые токены пользователей: подтверждение
+// email и сброс пароля. В базе хранится только хеш токена
+type UserTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUserTokenRepository(db *pgxpool.Pool) *UserTokenRepository {
+	return &UserTokenRepository{db: db}
+}
+
+// Create сохраняет токен для userID. Неиспользованные токены с тем же назначением
+// удаляются: действует только последняя отправленная ссылка.
+// email - адрес, на который ушло письмо
+func (r *UserTokenRepository) Create(ctx context.Context, userID int, purpose, email, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		userID, purpose,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, email, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		userID, purpose, tokenHash, email, expiresAt,
+	)
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// useToken блокирует токен с хешем tokenHash, проверяет его и отмечает использованным.
+// Возвращает пользователя и адрес, на который ушло письмо
+func useToken(ctx context.Context, tx pgx.Tx, tokenHash, purpose string) (userID int, email string, err error) {
+	var (
+		id      int
+		used    bool
+		expired bool
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT id, user_id, email, used_at IS NOT NULL, expires_at <= CURRENT_TIMESTAMP
+		FROM user_tokens
+		WHERE token_hash = $1 AND purpose = $2
+		FOR UPDATE`,
+		tokenHash, purpose,
+	).Scan(&id, &userID, &email, &used, &expired)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, "", fmt.Errorf("token not found")
+		}
+		return 0, "", fmt.Errorf("failed to get token: %w", err)
+	}
+	if used {
+		return 0, "", fmt.Errorf("token has already been used")
+	}
+	if expired {
+		return 0, "", fmt.Errorf("token has expired")
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1`, id); err != nil {
+		return 0, "", fmt.Errorf("failed to use token: %w", err)
+	}
+
+	return userID, email, nil
+}
+
+// VerifyEmail по токену отмечает email пользователя подтвержденным. Если после
+// отправки письма пользователь сменил адрес, токен не подходит
+func (r *UserTokenRepository) VerifyEmail(ctx context.Context, tokenHash string) (*models.User, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	userID, email, err := useToken(ctx, tx, tokenHash, models.TokenPurposeVerifyEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err = tx.QueryRow(ctx, `
+		UPDATE users
+		SET email_verified = TRUE, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND email = $2
+		RETURNING id, name, COALESCE(email, ''), email_verified, is_guest, created_at, updated_at`,
+		userID, email,
+	).Scan(&user.ID, &user.Name, &user.Email, &user.EmailVerified, &user.Guest, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("token was issued for a different email")
+		}
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &user, nil
+}
+
+// ResetPassword по токену задает пользователю новый хеш пароля. Остальные
+// неиспользованные токены сброса и все сессии пользователя перестают действовать. Письмо
+// дошло до адресата, поэтому email заодно считается подтвержденным
+func (r *UserTokenRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	userID, email, err := useToken(ctx, tx, tokenHash, models.TokenPurposeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET password_hash = $2,
+			email_verified = email_verified OR email IS NOT DISTINCT FROM $3,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`,
+		userID, passwordHash, email,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		userID, models.TokenPurposeResetPassword,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	// Сброс пароля означает, что старый мог утечь, поэтому открытые сессии закрываются
+	if _, err := tx.Exec(ctx, `DELETE FROM sessions WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"balance/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const webhookColumns = `id, group_id, user_id, url, secret, event_types, active, created_by, created_at`
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.status, d.attempts, d.next_attempt_at,
+	d.response_status, COALESCE(d.response_body, ''), COALESCE(d.last_error, ''), d.duration_ms,
+	d.created_at, d.delivered_at`
+
+type WebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func scanWebhook(row pgx.Row) (*models.Webhook, error) {
+	var w models.Webhook
+	err := row.Scan(&w.ID, &w.GroupID, &w.UserID, &w.URL, &w.Secret, &w.EventTypes, &w.Active, &w.CreatedBy, &w.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func scanDelivery(row pgx.Row, extra ...interface{}) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	dest := []interface{}{
+		&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.ResponseStatus, &d.ResponseBody, &d.LastError, &d.DurationMs,
+		&d.CreatedAt, &d.DeliveredAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, req *models.CreateWebhookRequest, secret string) (*models.Webhook, error) {
+	query := `
+		INSERT INTO webhooks (group_id, user_id, url, secret, event_types, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + webhookColumns
+
+	webhook, err := scanWebhook(r.db.QueryRow(ctx, query,
+		req.GroupID, req.UserID, req.URL, secret, req.EventTypes, req.CreatedBy,
+	))
+	if err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23503" {
+			return nil, fmt.Errorf("group or user not found")
+		}
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id int) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+
+	webhook, err := scanWebhook(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("webhook not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// GetAll возвращает подписки группы и/или пользователя. Нулевые groupID и userID не фильтруют
+func (r *WebhookRepository) GetAll(ctx context.Context, groupID, userID int) ([]*models.Webhook, error) {
+	var conditions []string
+	var args []interface{}
+
+	if groupID != 0 {
+		args = append(args, groupID)
+		conditions = append(conditions, fmt.Sprintf("group_id = $%d", len(args)))
+	}
+	if userID != 0 {
+		args = append(args, userID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+
+	query := `SELECT ` + webhookColumns + ` FROM webhooks`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []*models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+
+	return nil
+}
+
+// GetDeliveries возвращает журнал доставок вебхука, новые первыми
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, webhookID, limit int) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries d
+		WHERE d.webhook_id = $1
+		ORDER BY d.id DESC
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Redeliver ставит событие доставки deliveryID в очередь повторно новой записью журнала
+func (r *WebhookRepository) Redeliver(ctx context.Context, webhookID, deliveryID int) (*models.WebhookDelivery, error) {
+	query := `
+		INSERT INTO webhook_deliveries AS d (webhook_id, event_id, event_type)
+		SELECT webhook_id, event_id, event_type
+		FROM webhook_deliveries
+		WHERE id = $1 AND webhook_id = $2
+		RETURNING ` + deliveryColumns
+
+	delivery, err := scanDelivery(r.db.QueryRow(ctx, query, deliveryID, webhookID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("delivery not found")
+		}
+		return nil, fmt.Errorf("failed to redeliver: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// ClaimDue выбирает до limit доставок, которые пора отправить, и резервирует их на lease
+// так же, как NotificationRepository.ClaimDue. Вместе с доставкой читаются подписка и событие
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= CURRENT_TIMESTAMP AND w.active
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1,
+			next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2::integer)
+		FROM due, webhooks w, outbox_events e
+		WHERE d.id = due.id AND w.id = d.webhook_id AND e.id = d.event_id
+		RETURNING ` + deliveryColumns + `, w.url, w.secret, e.payload, e.created_at`
+
+	rows, err := r.db.Query(ctx, query, limit, int(lease.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		webhook := &models.Webhook{}
+		event := &models.Event{}
+		delivery, err := scanDelivery(rows, &webhook.URL, &webhook.Secret, &event.Payload, &event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		webhook.ID = delivery.WebhookID
+		event.ID = delivery.EventID
+		event.Type = delivery.EventType
+		delivery.Webhook = webhook
+		delivery.Event = event
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// SaveAttempt сохраняет результат попытки доставки. Если d.Status равен pending,
+// следующая попытка будет через retryIn
+func (r *WebhookRepository) SaveAttempt(ctx context.Context, d *models.WebhookDelivery, retryIn time.Duration) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, response_status = $3, response_body = NULLIF($4, ''), last_error = NULLIF($5, ''),
+			duration_ms = $6,
+			next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $7::integer),
+			delivered_at = CASE WHEN $2 = 'delivered' THEN CURRENT_TIMESTAMP ELSE delivered_at END
+		WHERE id = $1`,
+		d.ID, d.Status, d.ResponseStatus, d.ResponseBody, d.LastError, d.DurationMs, int(retryIn.Seconds()),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save delivery attempt: %w", err)
+	}
+	return nil
+}
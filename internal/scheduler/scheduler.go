@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"balance/internal/logging"
+	"balance/internal/tracing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Job - периодическая фоновая задача
+type Job func(ctx context.Context) error
+
+type task struct {
+	name     string
+	interval time.Duration
+	job      Job
+	// lastStart - когда последний раз начался запуск, в UnixNano
+	lastStart atomic.Int64
+}
+
+// minStaleAfter - задача считается зависшей не раньше чем через столько после начала запуска
+const minStaleAfter = time.Minute
+
+// Scheduler запускает зарегистрированные задачи в фоне внутри процесса сервера.
+// Каждая задача выполняется в своей горутине с собственным интервалом
+type Scheduler struct {
+	tasks   []*task
+	started atomic.Bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Every регистрирует задачу, которая выполняется сразу после Start и затем раз в interval.
+// Задачи нужно регистрировать до вызова Start. Задачи с неположительным интервалом отключены
+func (s *Scheduler) Every(name string, interval time.Duration, job Job) {
+	if interval <= 0 {
+		slog.Info("Scheduler job is disabled", "job", name, "interval", interval)
+		return
+	}
+	s.tasks = append(s.tasks, &task{name: name, interval: interval, job: job})
+}
+
+// Start запускает все задачи. Они работают, пока не будет вызван Stop
+// или не будет отменен ctx
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.started.Store(true)
+
+	now := time.Now().UnixNano()
+	for _, t := range s.tasks {
+		t.lastStart.Store(now)
+		s.wg.Add(1)
+		go func(t *task) {
+			defer s.wg.Done()
+			s.run(ctx, t)
+		}(t)
+	}
+}
+
+// Stop останавливает задачи и ждет завершения тех, что выполняются в данный момент,
+// но не дольше, чем позволяет ctx
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.started.Store(false)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Check возвращает ошибку, если планировщик не запущен или какая-то задача не
+// запускалась дольше трех своих интервалов (но не меньше минуты): ее запуск завис
+// или горутина остановилась
+func (s *Scheduler) Check(ctx context.Context) error {
+	if !s.started.Load() {
+		return fmt.Errorf("scheduler is not running")
+	}
+
+	var stale []string
+	for _, t := range s.tasks {
+		since := time.Since(time.Unix(0, t.lastStart.Load()))
+		if since > max(3*t.interval, minStaleAfter) {
+			stale = append(stale, fmt.Sprintf("%s (last run started %s ago)", t.name, since.Round(time.Second)))
+		}
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("stale jobs: %s", strings.Join(stale, ", "))
+	}
+	return nil
+}
+
+func (s *Scheduler) run(ctx context.Context, t *task) {
+	// Логи задачи и всего, что она вызывает, помечены ее именем
+	ctx = logging.With(ctx, "job", t.name)
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, t)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce выполняет задачу один раз в собственной трассе
+func (s *Scheduler) runOnce(ctx context.Context, t *task) {
+	t.lastStart.Store(time.Now().UnixNano())
+
+	ctx, span := tracing.Start(ctx, "job "+t.name, trace.WithNewRoot())
+	defer span.End()
+
+	if err := t.job(ctx); err != nil && ctx.Err() == nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logging.FromContext(ctx).Error("Scheduler job failed", "error", err)
+	}
+}
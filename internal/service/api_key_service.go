@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"balance/internal/models"
+	"balance/internal/repository"
+	"balance/internal/token"
+	"balance/internal/tracing"
+)
+
+// apiKeyPrefixLength - сколько первых символов ключа хранится открыто для списка ключей
+const apiKeyPrefixLength = len(models.APIKeyPrefix) + 8
+
+// APIKeyService выпускает и проверяет персональные API ключи
+type APIKeyService struct {
+	apiKeyRepo *repository.APIKeyRepository
+}
+
+func NewAPIKeyService(apiKeyRepo *repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+// CreateKey выпускает ключ пользователю userID. Сам ключ возвращается только здесь
+func (s *APIKeyService) CreateKey(ctx context.Context, userID int, req *models.CreateAPIKeyRequest) (*models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.CreateKey")
+	defer span.End()
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if len(name) > 100 {
+		return nil, fmt.Errorf("name must be no more than 100 characters long")
+	}
+
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, _, err := token.New()
+	if err != nil {
+		return nil, err
+	}
+	secret = models.APIKeyPrefix + secret
+
+	key, err := s.apiKeyRepo.Create(ctx, userID, name, secret[:apiKeyPrefixLength], token.Hash(secret), scopes)
+	if err != nil {
+		return nil, err
+	}
+	key.Key = secret
+
+	return key, nil
+}
+
+// GetKeys возвращает ключи пользователя без самих ключей
+func (s *APIKeyService) GetKeys(ctx context.Context, userID int) ([]*models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.GetKeys")
+	defer span.End()
+
+	return s.apiKeyRepo.GetAll(ctx, userID)
+}
+
+// RevokeKey отзывает ключ пользователя. Отозванный ключ сразу перестает действовать
+func (s *APIKeyService) RevokeKey(ctx context.Context, userID, id int) (*models.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.RevokeKey")
+	defer span.End()
+
+	return s.apiKeyRepo.Revoke(ctx, id, userID)
+}
+
+// Authenticate возвращает действующий ключ и его владельца
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*models.APIKey, *models.User, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Authenticate")
+	defer span.End()
+
+	return s.apiKeyRepo.Authenticate(ctx, token.Hash(key))
+}
+
+// normalizeScopes проверяет права ключа и убирает повторы
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	var result []string
+	for _, scope := range scopes {
+		switch scope {
+		case models.ScopeRead, models.ScopeWriteDebts, models.ScopeAdmin:
+		default:
+			return nil, fmt.Errorf("unknown scope %q, use %s, %s or %s",
+				scope, models.ScopeRead, models.ScopeWriteDebts, models.ScopeAdmin)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
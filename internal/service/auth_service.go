@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"balance/internal/models"
+	"balance/internal/oidc"
+	"balance/internal/repository"
+	"balance/internal/token"
+	"balance/internal/tracing"
+)
+
+const (
+	// oidcLoginTTL - сколько ждем возврата пользователя от провайдера
+	oidcLoginTTL = 10 * time.Minute
+	// challengeTTL - сколько ждем код второго фактора после пароля
+	challengeTTL = 5 * time.Minute
+)
+
+// AuthService выдает и проверяет сессии пользователей, в том числе после входа
+// через провайдера OpenID Connect и второго фактора
+type AuthService struct {
+	userRepo      *repository.UserRepository
+	sessionRepo   *repository.SessionRepository
+	identityRepo  *repository.IdentityRepository
+	twoFactorRepo *repository.TwoFactorRepository
+	twoFactor     *TwoFactorService
+	provider      *oidc.Provider
+	sessionTTL    time.Duration
+	now           func() time.Time
+}
+
+// NewAuthService создает сервис входа. provider равен nil, если вход через OpenID Connect не настроен
+func NewAuthService(
+	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	identityRepo *repository.IdentityRepository,
+	twoFactorRepo *repository.TwoFactorRepository,
+	twoFactor *TwoFactorService,
+	provider *oidc.Provider,
+	sessionTTL time.Duration,
+) *AuthService {
+	return &AuthService{
+		userRepo:      userRepo,
+		sessionRepo:   sessionRepo,
+		identityRepo:  identityRepo,
+		twoFactorRepo: twoFactorRepo,
+		twoFactor:     twoFactor,
+		provider:      provider,
+		sessionTTL:    sessionTTL,
+		now:           time.Now,
+	}
+}
+
+// OIDCEnabled сообщает, что вход через провайдера настроен
+func (s *AuthService) OIDCEnabled() bool {
+	return s.provider != nil
+}
+
+// StartOIDCLogin начинает вход через провайдера: сохраняет state, nonce и PKCE
+// code verifier и возвращает адрес страницы входа
+func (s *AuthService) StartOIDCLogin(ctx context.Context) (*models.OIDCLogin, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.StartOIDCLogin")
+	defer span.End()
+
+	state, err := oidc.NewVerifier()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.NewVerifier()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := s.provider.AuthCodeURL(ctx, state, nonce, oidc.Challenge(verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := s.now().UTC().Add(oidcLoginTTL)
+	if err := s.identityRepo.CreateLogin(ctx, token.Hash(state), nonce, verifier, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &models.OIDCLogin{
+		AuthorizationURL: authURL,
+		State:            state,
+		ExpiresAt:        expiresAt,
+	}, nil
+}
+
+// FinishOIDCLogin завершает вход: обменивает код на ID токен, проверяет его,
+// находит или создает пользователя и выдает сессию или запрашивает второй фактор
+func (s *AuthService) FinishOIDCLogin(ctx context.Context, req *models.OIDCCallbackRequest) (*models.LoginResult, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.FinishOIDCLogin")
+	defer span.End()
+
+	if req.Code == "" {
+		return nil, fmt.Errorf("code is required")
+	}
+	if req.State == "" {
+		return nil, fmt.Errorf("state is required")
+	}
+
+	nonce, verifier, err := s.identityRepo.TakeLogin(ctx, token.Hash(req.State))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.provider.Exchange(ctx, req.Code, verifier, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.identityRepo.Login(ctx, &models.OIDCIdentity{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         strings.TrimSpace(claims.Email),
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          displayName(claims.Name, claims.Email),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Login(ctx, user)
+}
+
+// Login выдает сессию пользователю, который подтвердил себя паролем или у провайдера.
+// Если включена двухфакторная аутентификация, вместо сессии выдается токен второго шага
+func (s *AuthService) Login(ctx context.Context, user *models.User) (*models.LoginResult, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	enabled, err := s.twoFactor.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !enabled {
+		session, err := s.CreateSession(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResult{Session: session}, nil
+	}
+
+	secret, hash, err := token.New()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := s.now().UTC().Add(challengeTTL)
+	if err := s.twoFactorRepo.CreateChallenge(ctx, user.ID, hash, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &models.LoginResult{
+		TwoFactorRequired: true,
+		ChallengeToken:    secret,
+		ChallengeExpires:  &expiresAt,
+	}, nil
+}
+
+// CompleteTwoFactorLogin завершает вход кодом второго фактора. По одному токену
+// можно ввести ограниченное число кодов
+func (s *AuthService) CompleteTwoFactorLogin(ctx context.Context, req *models.TwoFactorLoginRequest) (*models.LoginResult, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.CompleteTwoFactorLogin")
+	defer span.End()
+
+	if req.ChallengeToken == "" {
+		return nil, fmt.Errorf("challenge token is required")
+	}
+
+	hash := token.Hash(req.ChallengeToken)
+	userID, err := s.twoFactorRepo.AttemptChallenge(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactor.Verify(ctx, userID, req.Code); err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.DeleteChallenge(ctx, hash); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.CreateSession(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LoginResult{Session: session}, nil
+}
+
+// CreateSession выдает пользователю новую сессию
+func (s *AuthService) CreateSession(ctx context.Context, user *models.User) (*models.Session, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.CreateSession")
+	defer span.End()
+
+	secret, hash, err := token.New()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := s.now().UTC().Add(s.sessionTTL)
+	if err := s.sessionRepo.Create(ctx, user.ID, hash, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &models.Session{Token: secret, ExpiresAt: expiresAt, User: user}, nil
+}
+
+// Authenticate возвращает владельца сессии по токену
+func (s *AuthService) Authenticate(ctx context.Context, sessionToken string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Authenticate")
+	defer span.End()
+
+	return s.sessionRepo.GetUser(ctx, token.Hash(sessionToken))
+}
+
+// Logout завершает сессию
+func (s *AuthService) Logout(ctx context.Context, sessionToken string) error {
+	ctx, span := tracing.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
+	return s.sessionRepo.Delete(ctx, token.Hash(sessionToken))
+}
+
+// displayName выбирает имя нового пользователя: имя из профиля провайдера или
+// часть email до @. Имя должно быть от 2 до 100 символов, как при обычной регистрации
+func displayName(name, email string) string {
+	name = strings.TrimSpace(name)
+	if len([]rune(name)) < 2 {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	if len([]rune(name)) < 2 {
+		name = "User"
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	return name
+}
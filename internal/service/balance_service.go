@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"balance/internal/models"
+	"balance/internal/repository"
+	"balance/internal/tracing"
+)
+
+// BalanceService считает, кто кому должен, по всем общим группам
+type BalanceService struct {
+	debtRepo *repository.DebtRepository
+	userRepo *repository.UserRepository
+}
+
+func NewBalanceService(debtRepo *repository.DebtRepository, userRepo *repository.UserRepository) *BalanceService {
+	return &BalanceService{
+		debtRepo: debtRepo,
+		userRepo: userRepo,
+	}
+}
+
+// GetBalances возвращает балансы пользователя со всеми, с кем у него есть активные долги
+func (s *BalanceService) GetBalances(ctx context.Context, userID int) ([]*models.UserBalance, error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.GetBalances")
+	defer span.End()
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	balances, err := s.debtRepo.GetBalances(ctx, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if balances == nil {
+		balances = []*models.UserBalance{}
+	}
+
+	return balances, nil
+}
+
+// GetBalance возвращает баланс пользователя с другим пользователем.
+// Если долгов между ними нет, баланс нулевой
+func (s *BalanceService) GetBalance(ctx context.Context, userID, otherID int) (*models.UserBalance, error) {
+	ctx, span := tracing.Start(ctx, "BalanceService.GetBalance")
+	defer span.End()
+
+	if userID == otherID {
+		return nil, fmt.Errorf("cannot get a balance with yourself")
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	other, err := s.userRepo.GetByID(ctx, otherID)
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := s.debtRepo.GetBalances(ctx, userID, otherID)
+	if err != nil {
+		return nil, err
+	}
+	if len(balances) > 0 {
+		return balances[0], nil
+	}
+
+	return &models.UserBalance{
+		UserID:        userID,
+		OtherUserID:   otherID,
+		OtherUserName: other.Name,
+		Groups:        []models.GroupBalance{},
+	}, nil
+}
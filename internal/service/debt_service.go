@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"balance/internal/models"
+	"balance/internal/repository"
+	"balance/internal/tracing"
+)
+
+// Действия над долгом, которые меняют его статус
+const (
+	DebtActionConfirm = "confirm"
+	DebtActionReject  = "reject"
+	DebtActionDispute = "dispute"
+	DebtActionSettle  = "settle"
+	DebtActionCancel  = "cancel"
+)
+
+// debtTransition описывает один допустимый переход конечного автомата долга
+type debtTransition struct {
+	from   string
+	to     string
+	debtor bool // true - переход выполняет должник, false - кредитор
+}
+
+// debtTransitions - конечный автомат статусов долга:
+//
+//	proposed -> active (confirm, должник), cancelled (reject - должник, cancel - кредитор), disputed (dispute, должник)
+//	active   -> settled (settle, кредитор), disputed (dispute, должник), cancelled (cancel, кредитор)
+//	disputed -> active (confirm, должник), cancelled (cancel, кредитор)
+//
+// settled и cancelled - конечные статусы. Переходы, которых нет в таблице, запрещены
+var debtTransitions = map[string][]debtTransition{
+	DebtActionConfirm: {
+		{from: models.DebtStatusProposed, to: models.DebtStatusActive, debtor: true},
+		{from: models.DebtStatusDisputed, to: models.DebtStatusActive, debtor: true},
+	},
+	DebtActionReject: {
+		{from: models.DebtStatusProposed, to: models.DebtStatusCancelled, debtor: true},
+	},
+	DebtActionDispute: {
+		{from: models.DebtStatusProposed, to: models.DebtStatusDisputed, debtor: true},
+		{from: models.DebtStatusActive, to: models.DebtStatusDisputed, debtor: true},
+	},
+	DebtActionSettle: {
+		{from: models.DebtStatusActive, to: models.DebtStatusSettled, debtor: false},
+	},
+	DebtActionCancel: {
+		{from: models.DebtStatusProposed, to: models.DebtStatusCancelled, debtor: false},
+		{from: models.DebtStatusActive, to: models.DebtStatusCancelled, debtor: false},
+		{from: models.DebtStatusDisputed, to: models.DebtStatusCancelled, debtor: false},
+	},
+}
+
+type DebtService struct {
+	debtRepo       *repository.DebtRepository
+	friendshipRepo *repository.FriendshipRepository
+	groupRepo      *repository.GroupRepository
+	userRepo       *repository.UserRepository
+}
+
+func NewDebtService(
+	debtRepo *repository.DebtRepository,
+	friendshipRepo *repository.FriendshipRepository,
+	groupRepo *repository.GroupRepository,
+	userRepo *repository.UserRepository,
+) *DebtService {
+	return &DebtService{
+		debtRepo:       debtRepo,
+		friendshipRepo: friendshipRepo,
+		groupRepo:      groupRepo,
+		userRepo:       userRepo,
+	}
+}
+
+func (s *DebtService) CreateDebt(ctx context.Context, req *models.CreateDebtRequest) (*models.Debt, error) {
+	ctx, span := tracing.Start(ctx, "DebtService.CreateDebt")
+	defer span.End()
+
+	if err := s.validateCreateDebtRequest(req); err != nil {
+		return nil, err
+	}
+
+	// Долг без группы можно записать только между друзьями
+	if req.GroupID == 0 {
+		friends, err := s.friendshipRepo.AreFriends(ctx, req.FromUserID, req.ToUserID)
+		if err != nil {
+			return nil, err
+		}
+		if !friends {
+			return nil, fmt.Errorf("only the friends can have direct debts")
+		}
+	}
+
+	// Долг в группе - только между ее участниками
+	if req.GroupID != 0 {
+		if _, err := s.groupRepo.GetByID(ctx, req.GroupID); err != nil {
+			return nil, err
+		}
+		for _, userID := range []int{req.FromUserID, req.ToUserID} {
+			isMember, err := s.groupRepo.IsMember(ctx, req.GroupID, userID)
+			if err != nil {
+				return nil, err
+			}
+			if !isMember {
+				return nil, fmt.Errorf("only the group members can have debts in the group")
+			}
+		}
+	}
+
+	// due_at хранится без часового пояса: приводим срок к UTC, иначе смещение
+	// из запроса потерялось бы и срок сдвинулся на разницу с UTC
+	if req.DueAt != nil {
+		dueAt := req.DueAt.UTC()
+		req.DueAt = &dueAt
+	}
+
+	debtor, err := s.userRepo.GetByID(ctx, req.FromUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	debt, err := s.debtRepo.Create(ctx, req, initialDebtStatus(req, debtor))
+	if err != nil {
+		return nil, err
+	}
+
+	return debt, nil
+}
+
+// initialDebtStatus - статус нового долга. Если долг записывает сам должник,
+// подтверждение не нужно, иначе долг ждет, пока должник его подтвердит.
+// Гость войти не может и подтвердить долг не сможет никогда, поэтому
+// за него отвечает записавший долг участник группы
+func initialDebtStatus(req *models.CreateDebtRequest, debtor *models.User) string {
+	if req.CreatedBy == req.FromUserID || debtor.Guest {
+		return models.DebtStatusActive
+	}
+	return models.DebtStatusProposed
+}
+
+func (s *DebtService) GetDebt(ctx context.Context, id int) (*models.Debt, error) {
+	ctx, span := tracing.Start(ctx, "DebtService.GetDebt")
+	defer span.End()
+
+	return s.debtRepo.GetByID(ctx, id)
+}
+
+func (s *DebtService) GetDebts(ctx context.Context, filter models.DebtFilter) ([]*models.Debt, error) {
+	ctx, span := tracing.Start(ctx, "DebtService.GetDebts")
+	defer span.End()
+
+	if filter.Status != "" && !isValidDebtStatus(filter.Status) {
+		return nil, fmt.Errorf("invalid debt status")
+	}
+
+	debts, err := s.debtRepo.GetAll(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get debts: %w", err)
+	}
+
+	return debts, nil
+}
+
+// GetOverdueDebts возвращает активные просроченные долги, в которых участвует пользователь
+func (s *DebtService) GetOverdueDebts(ctx context.Context, userID int) ([]*models.Debt, error) {
+	ctx, span := tracing.Start(ctx, "DebtService.GetOverdueDebts")
+	defer span.End()
+
+	debts, err := s.debtRepo.GetOverdue(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overdue debts: %w", err)
+	}
+
+	return debts, nil
+}
+
+// ApplyAction выполняет действие над долгом от имени пользователя req.UserID.
+// Возвращает ошибку, если действие недопустимо из текущего статуса
+// или пользователь не является нужной стороной долга
+func (s *DebtService) ApplyAction(ctx context.Context, id int, action string, req *models.DebtActionRequest) (*models.Debt, error) {
+	ctx, span := tracing.Start(ctx, "DebtService.ApplyAction")
+	defer span.End()
+
+	transitions, ok := debtTransitions[action]
+	if !ok {
+		return nil, fmt.Errorf("unknown debt action")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	if len(req.Note) > 500 {
+		return nil, fmt.Errorf("note must be no more than 500 characters long")
+	}
+
+	debt, err := s.debtRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	transition, err := findTransition(transitions, action, debt, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.debtRepo.UpdateStatus(ctx, id, transition.from, transition.to, req.Note)
+}
+
+// findTransition выбирает переход action из текущего статуса долга и проверяет,
+// что его выполняет нужная сторона долга userID
+func findTransition(transitions []debtTransition, action string, debt *models.Debt, userID int) (*debtTransition, error) {
+	var transition *debtTransition
+	for i := range transitions {
+		if transitions[i].from == debt.Status {
+			transition = &transitions[i]
+			break
+		}
+	}
+	if transition == nil {
+		return nil, fmt.Errorf("cannot %s a debt in status %s", action, debt.Status)
+	}
+
+	actor := debt.ToUserID
+	if transition.debtor {
+		actor = debt.FromUserID
+	}
+	if userID != actor {
+		if transition.debtor {
+			return nil, fmt.Errorf("only the debtor can %s this debt", action)
+		}
+		return nil, fmt.Errorf("only the creditor can %s this debt", action)
+	}
+
+	return transition, nil
+}
+
+func (s *DebtService) validateCreateDebtRequest(req *models.CreateDebtRequest) error {
+	if req.FromUserID == 0 || req.ToUserID == 0 {
+		return fmt.Errorf("from_user_id and to_user_id are required")
+	}
+	if req.FromUserID == req.ToUserID {
+		return fmt.Errorf("from_user_id and to_user_id must be different")
+	}
+	if req.Amount <= 0 {
+		return fmt.Errorf("amount must be greater than 0")
+	}
+	if len(req.Description) > 500 {
+		return fmt.Errorf("description must be no more than 500 characters long")
+	}
+	if req.CreatedBy != req.FromUserID && req.CreatedBy != req.ToUserID {
+		return fmt.Errorf("created_by must be one of the debt parties")
+	}
+
+	return nil
+}
+
+func isValidDebtStatus(status string) bool {
+	switch status {
+	case models.DebtStatusProposed, models.DebtStatusActive, models.DebtStatusSettled,
+		models.DebtStatusCancelled, models.DebtStatusDisputed:
+		return true
+	}
+	return false
+}
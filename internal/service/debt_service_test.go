@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"balance/internal/database/dbtest"
+	"balance/internal/models"
+	"balance/internal/repository"
+)
+
+func TestInitialDebtStatus(t *testing.T) {
+	const debtor, creditor = 1, 2
+
+	tests := []struct {
+		name      string
+		createdBy int
+		guest     bool
+		want      string
+	}{
+		{"recorded by the debtor", debtor, false, models.DebtStatusActive},
+		{"recorded by the creditor", creditor, false, models.DebtStatusProposed},
+		{"guest debtor", creditor, true, models.DebtStatusActive},
+	}
+	for _, tt := range tests {
+		req := &models.CreateDebtRequest{FromUserID: debtor, ToUserID: creditor, CreatedBy: tt.createdBy}
+		got := initialDebtStatus(req, &models.User{ID: debtor, Guest: tt.guest})
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Срок с часовым поясом сохраняется тем же моментом времени
+func TestCreateDebtKeepsDueAtInstant(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	users := repository.NewUserRepository(db)
+	groups := repository.NewGroupRepository(db)
+
+	debtor, err := users.Create(ctx, &models.CreateUserRequest{Name: "Ivan", Email: dbtest.Email("ivan")}, "hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	creditor, err := users.Create(ctx, &models.CreateUserRequest{Name: "Petya", Email: dbtest.Email("petya")}, "hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	group, err := groups.Create(ctx, &models.CreateGroupRequest{Name: "Trip", CreatedBy: debtor.ID})
+	if err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	if _, err := groups.AddMember(ctx, group.ID, creditor.ID); err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+
+	svc := NewDebtService(repository.NewDebtRepository(db), repository.NewFriendshipRepository(db), groups, users)
+	dueAt := time.Date(2030, 5, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	debt, err := svc.CreateDebt(ctx, &models.CreateDebtRequest{
+		GroupID: group.ID, FromUserID: debtor.ID, ToUserID: creditor.ID, Amount: 10, CreatedBy: debtor.ID, DueAt: &dueAt,
+	})
+	if err != nil {
+		t.Fatalf("CreateDebt: %v", err)
+	}
+
+	got, err := svc.GetDebt(ctx, debt.ID)
+	if err != nil {
+		t.Fatalf("GetDebt: %v", err)
+	}
+	if got.DueAt == nil || !got.DueAt.Equal(dueAt) {
+		t.Errorf("due_at = %v, want %v", got.DueAt, dueAt)
+	}
+}
+
+func TestFindTransition(t *testing.T) {
+	const debtor, creditor = 1, 2
+
+	tests := []struct {
+		action string
+		status string
+		userID int
+		want   string // новый статус или текст ошибки
+	}{
+		{DebtActionConfirm, models.DebtStatusProposed, debtor, models.DebtStatusActive},
+		{DebtActionConfirm, models.DebtStatusDisputed, debtor, models.DebtStatusActive},
+		{DebtActionConfirm, models.DebtStatusProposed, creditor, "only the debtor can confirm this debt"},
+		{DebtActionConfirm, models.DebtStatusActive, debtor, "cannot confirm a debt in status active"},
+		{DebtActionReject, models.DebtStatusProposed, debtor, models.DebtStatusCancelled},
+		{DebtActionReject, models.DebtStatusProposed, creditor, "only the debtor can reject this debt"},
+		{DebtActionReject, models.DebtStatusActive, debtor, "cannot reject a debt in status active"},
+		{DebtActionDispute, models.DebtStatusProposed, debtor, models.DebtStatusDisputed},
+		{DebtActionDispute, models.DebtStatusActive, debtor, models.DebtStatusDisputed},
+		{DebtActionDispute, models.DebtStatusActive, creditor, "only the debtor can dispute this debt"},
+		{DebtActionDispute, models.DebtStatusSettled, debtor, "cannot dispute a debt in status settled"},
+		{DebtActionSettle, models.DebtStatusActive, creditor, models.DebtStatusSettled},
+		{DebtActionSettle, models.DebtStatusActive, debtor, "only the creditor can settle this debt"},
+		{DebtActionSettle, models.DebtStatusProposed, creditor, "cannot settle a debt in status proposed"},
+		{DebtActionCancel, models.DebtStatusProposed, creditor, models.DebtStatusCancelled},
+		{DebtActionCancel, models.DebtStatusActive, creditor, models.DebtStatusCancelled},
+		{DebtActionCancel, models.DebtStatusDisputed, creditor, models.DebtStatusCancelled},
+		{DebtActionCancel, models.DebtStatusActive, debtor, "only the creditor can cancel this debt"},
+		{DebtActionCancel, models.DebtStatusCancelled, creditor, "cannot cancel a debt in status cancelled"},
+		{DebtActionCancel, models.DebtStatusActive, 3, "only the creditor can cancel this debt"},
+	}
+	for _, tt := range tests {
+		debt := &models.Debt{FromUserID: debtor, ToUserID: creditor, Status: tt.status}
+		transition, err := findTransition(debtTransitions[tt.action], tt.action, debt, tt.userID)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		} else {
+			got = transition.to
+		}
+		if got != tt.want {
+			t.Errorf("%s from %s by %d: got %q, want %q", tt.action, tt.status, tt.userID, got, tt.want)
+		}
+	}
+}
+
+func TestCreateDebtRequiresGroupMembers(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	users := repository.NewUserRepository(db)
+	groups := repository.NewGroupRepository(db)
+
+	member, err := users.Create(ctx, &models.CreateUserRequest{Name: "Ivan", Email: dbtest.Email("ivan")}, "hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	outsider, err := users.Create(ctx, &models.CreateUserRequest{Name: "Petya", Email: dbtest.Email("petya")}, "hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	group, err := groups.Create(ctx, &models.CreateGroupRequest{Name: "Trip", CreatedBy: member.ID})
+	if err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+
+	svc := NewDebtService(repository.NewDebtRepository(db), repository.NewFriendshipRepository(db), groups, users)
+	for _, req := range []*models.CreateDebtRequest{
+		{GroupID: group.ID, FromUserID: member.ID, ToUserID: outsider.ID, Amount: 10, CreatedBy: member.ID},
+		{GroupID: group.ID, FromUserID: outsider.ID, ToUserID: member.ID, Amount: 10, CreatedBy: member.ID},
+	} {
+		_, err := svc.CreateDebt(ctx, req)
+		if err == nil || err.Error() != "only the group members can have debts in the group" {
+			t.Errorf("CreateDebt from %d to %d: got %v, want membership error", req.FromUserID, req.ToUserID, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"balance/internal/models"
+	"balance/internal/repository"
+	"balance/internal/tracing"
+)
+
+type FriendshipService struct {
+	friendshipRepo *repository.FriendshipRepository
+	userRepo       *repository.UserRepository
+}
+
+func NewFriendshipService(friendshipRepo *repository.FriendshipRepository, userRepo *repository.UserRepository) *FriendshipService {
+	return &FriendshipService{
+		friendshipRepo: friendshipRepo,
+		userRepo:       userRepo,
+	}
+}
+
+// AddFriend отправляет запрос дружбы от userID. Если встречный запрос уже есть,
+// он сразу принимается
+func (s *FriendshipService) AddFriend(ctx context.Context, userID int, req *models.AddFriendRequest) (*models.Friendship, error) {
+	ctx, span := tracing.Start(ctx, "FriendshipService.AddFriend")
+	defer span.End()
+
+	if req.FriendID == 0 {
+		return nil, fmt.Errorf("friend_id is required")
+	}
+	if req.FriendID == userID {
+		return nil, fmt.Errorf("cannot add yourself as a friend")
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	friend, err := s.userRepo.GetByID(ctx, req.FriendID)
+	if err != nil {
+		return nil, err
+	}
+	if friend.Guest {
+		return nil, fmt.Errorf("cannot add a guest as a friend")
+	}
+
+	existing, err := s.friendshipRepo.Get(ctx, userID, req.FriendID)
+	if err == nil && existing.Status == models.FriendshipStatusPending && existing.RequestedBy == req.FriendID {
+		return s.AcceptFriend(ctx, userID, req.FriendID)
+	}
+
+	friendship, err := s.friendshipRepo.Create(ctx, userID, req.FriendID)
+	if err != nil {
+		return nil, err
+	}
+	friendship.Friend = friend
+
+	return friendship, nil
+}
+
+// AcceptFriend принимает запрос дружбы, который friendID отправил userID
+func (s *FriendshipService) AcceptFriend(ctx context.Context, userID, friendID int) (*models.Friendship, error) {
+	ctx, span := tracing.Start(ctx, "FriendshipService.AcceptFriend")
+	defer span.End()
+
+	existing, err := s.friendshipRepo.Get(ctx, userID, friendID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Status == models.FriendshipStatusAccepted {
+		return nil, fmt.Errorf("cannot accept a friendship that is already accepted")
+	}
+	if existing.RequestedBy == userID {
+		return nil, fmt.Errorf("only the invited user can accept a friend request")
+	}
+
+	friendship, err := s.friendshipRepo.Accept(ctx, userID, friendID)
+	if err != nil {
+		return nil, err
+	}
+
+	if friend, err := s.userRepo.GetByID(ctx, friendID); err == nil {
+		friendship.Friend = friend
+	}
+
+	return friendship, nil
+}
+
+// RemoveFriend удаляет дружбу, отклоняет входящий или отменяет исходящий запрос
+func (s *FriendshipService) RemoveFriend(ctx context.Context, userID, friendID int) error {
+	ctx, span := tracing.Start(ctx, "FriendshipService.RemoveFriend")
+	defer span.End()
+
+	return s.friendshipRepo.Delete(ctx, userID, friendID)
+}
+
+// GetFriends возвращает друзей пользователя. status - pending, accepted или пустой (все)
+func (s *FriendshipService) GetFriends(ctx context.Context, userID int, status string) ([]*models.Friendship, error) {
+	ctx, span := tracing.Start(ctx, "FriendshipService.GetFriends")
+	defer span.End()
+
+	if status != "" && status != models.FriendshipStatusPending && status != models.FriendshipStatusAccepted {
+		return nil, fmt.Errorf("invalid friendship status")
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	friendships, err := s.friendshipRepo.GetAll(ctx, userID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get friends: %w", err)
+	}
+
+	return friendships, nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"balance/internal/models"
+	"balance/internal/repository"
+	"balance/internal/tracing"
+)
+
+type GroupService struct {
+	groupRepo *repository.GroupRepository
+}
+
+func NewGroupService(groupRepo *repository.GroupRepository) *GroupService {
+	return &GroupService{
+		groupRepo: groupRepo,
+	}
+}
+
+func (s *GroupService) CreateGroup(ctx context.Context, req *models.CreateGroupRequest) (*models.Group, error) {
+	ctx, span := tracing.Start(ctx, "GroupService.CreateGroup")
+	defer span.End()
+
+	if err := s.validateCreateGroupRequest(req); err != nil {
+		return nil, err
+	}
+
+	group, err := s.groupRepo.Create(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// GetGroup возвращает группу вместе со списком участников
+func (s *GroupService) GetGroup(ctx context.Context, id int) (*models.Group, error) {
+	ctx, span := tracing.Start(ctx, "GroupService.GetGroup")
+	defer span.End()
+
+	group, err := s.groupRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.groupRepo.GetMembers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+	group.Members = members
+
+	return group, nil
+}
+
+func (s *GroupService) GetGroups(ctx context.Context, userID int, page models.Page) ([]*models.Group, error) {
+	ctx, span := tracing.Start(ctx, "GroupService.GetGroups")
+	defer span.End()
+
+	groups, err := s.groupRepo.GetAll(ctx, userID, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+// AddMember добавляет участника. Добавлять может участник группы actorID
+func (s *GroupService) AddMember(ctx context.Context, groupID, actorID int, req *models.AddMemberRequest) (*models.GroupMember, error) {
+	ctx, span := tracing.Start(ctx, "GroupService.AddMember")
+	defer span.End()
+
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	if _, err := s.groupRepo.GetByID(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.groupRepo.IsMember(ctx, groupID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, fmt.Errorf("only the group members can add members")
+	}
+
+	return s.groupRepo.AddMember(ctx, groupID, req.UserID)
+}
+
+// RemoveMember удаляет участника. Выйти из группы может сам участник, удалить
+// другого - участник группы actorID
+func (s *GroupService) RemoveMember(ctx context.Context, groupID, userID, actorID int) error {
+	ctx, span := tracing.Start(ctx, "GroupService.RemoveMember")
+	defer span.End()
+
+	if userID != actorID {
+		isMember, err := s.groupRepo.IsMember(ctx, groupID, actorID)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			return fmt.Errorf("only the group members can remove members")
+		}
+	}
+
+	return s.groupRepo.RemoveMember(ctx, groupID, userID)
+}
+
+func (s *GroupService) validateCreateGroupRequest(req *models.CreateGroupRequest) error {
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(req.Name) < 2 {
+		return fmt.Errorf("name must be at least 2 characters long")
+	}
+	if len(req.Name) > 100 {
+		return fmt.Errorf("name must be no more than 100 characters long")
+	}
+	if len(req.Description) > 500 {
+		return fmt.Errorf("description must be no more than 500 characters long")
+	}
+	if req.CreatedBy == 0 {
+		return fmt.Errorf("created_by is required")
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"balance/internal/models"
+	"balance/internal/notification"
+	"balance/internal/repository"
+	"balance/internal/token"
+	"balance/internal/tracing"
+)
+
+// GuestService ведет гостей - участников групп без аккаунта - и их передачу
+// настоящим пользователям по приглашению
+type GuestService struct {
+	guestRepo *repository.GuestRepository
+	groupRepo *repository.GroupRepository
+	userRepo  *repository.UserRepository
+	mailer    notification.Mailer
+	publicURL string
+	claimTTL  time.Duration
+	now       func() time.Time
+}
+
+func NewGuestService(
+	guestRepo *repository.GuestRepository,
+	groupRepo *repository.GroupRepository,
+	userRepo *repository.UserRepository,
+	mailer notification.Mailer,
+	publicURL string,
+	claimTTL time.Duration,
+) *GuestService {
+	return &GuestService{
+		guestRepo: guestRepo,
+		groupRepo: groupRepo,
+		userRepo:  userRepo,
+		mailer:    mailer,
+		publicURL: publicURL,
+		claimTTL:  claimTTL,
+		now:       time.Now,
+	}
+}
+
+// AddGuest добавляет в группу гостя. Добавить гостя может любой участник группы
+func (s *GuestService) AddGuest(ctx context.Context, groupID int, req *models.CreateGuestRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "GuestService.AddGuest")
+	defer span.End()
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if len(req.Name) < 2 {
+		return nil, fmt.Errorf("name must be at least 2 characters long")
+	}
+	if len(req.Name) > 100 {
+		return nil, fmt.Errorf("name must be no more than 100 characters long")
+	}
+	if req.CreatedBy == 0 {
+		return nil, fmt.Errorf("created_by is required")
+	}
+
+	if _, err := s.groupRepo.GetByID(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.groupRepo.IsMember(ctx, groupID, req.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, fmt.Errorf("only the group members can add guests")
+	}
+
+	return s.guestRepo.Create(ctx, groupID, req.Name)
+}
+
+// CreateClaim создает приглашение забрать гостя. Пригласить может участник
+// любой группы гостя. Токен и ссылка возвращаются один раз; если указан email,
+// ссылка отправляется письмом
+func (s *GuestService) CreateClaim(ctx context.Context, guestID int, req *models.CreateGuestClaimRequest) (*models.GuestClaim, error) {
+	ctx, span := tracing.Start(ctx, "GuestService.CreateClaim")
+	defer span.End()
+
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if req.CreatedBy == 0 {
+		return nil, fmt.Errorf("created_by is required")
+	}
+	if req.Email != "" && !strings.Contains(req.Email, "@") {
+		return nil, fmt.Errorf("invalid email format")
+	}
+
+	guest, err := s.userRepo.GetByID(ctx, guestID)
+	if err != nil {
+		return nil, err
+	}
+	if !guest.Guest {
+		return nil, fmt.Errorf("cannot invite to claim a registered user")
+	}
+
+	inviter, err := s.userRepo.GetByID(ctx, req.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.groupRepo.GetAll(ctx, guestID, models.Page{})
+	if err != nil {
+		return nil, err
+	}
+	shared := false
+	for _, group := range groups {
+		if shared, err = s.groupRepo.IsMember(ctx, group.ID, req.CreatedBy); err != nil {
+			return nil, err
+		}
+		if shared {
+			break
+		}
+	}
+	if !shared {
+		return nil, fmt.Errorf("only the guest's group members can invite to claim a guest")
+	}
+
+	secret, hash, err := token.New()
+	if err != nil {
+		return nil, err
+	}
+
+	claim := &models.GuestClaim{
+		GuestID:   guestID,
+		Email:     req.Email,
+		CreatedBy: req.CreatedBy,
+		ExpiresAt: s.now().UTC().Add(s.claimTTL),
+	}
+	if err := s.guestRepo.CreateClaim(ctx, claim, hash); err != nil {
+		return nil, err
+	}
+	claim.Token = secret
+	claim.URL = s.publicURL + "/claim?token=" + url.QueryEscape(secret)
+
+	if claim.Email != "" {
+		msg := notification.Message{
+			Subject: fmt.Sprintf("%s invited you to Balance", inviter.Name),
+			Body: fmt.Sprintf(
+				"%s has been keeping track of shared expenses for you as %q.\n\n"+
+					"Open the link to take over your groups and debts:\n%s\n\n"+
+					"The link expires on %s.",
+				inviter.Name, guest.Name, claim.URL, claim.ExpiresAt.Format("2006-01-02 15:04 MST"),
+			),
+		}
+		if err := s.mailer.Mail(ctx, claim.Email, msg); err != nil {
+			return nil, fmt.Errorf("failed to send claim invite: %w", err)
+		}
+	}
+
+	return claim, nil
+}
+
+// ClaimGuest передает пользователю гостя из приглашения вместе с участием
+// в группах и долгами. Перенос выполняется одной транзакцией
+func (s *GuestService) ClaimGuest(ctx context.Context, req *models.ClaimGuestRequest) (*models.GuestClaimResult, error) {
+	ctx, span := tracing.Start(ctx, "GuestService.ClaimGuest")
+	defer span.End()
+
+	if req.Token == "" {
+		return nil, fmt.Errorf("token is required")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	return s.guestRepo.Claim(ctx, token.Hash(req.Token), req.UserID)
+}
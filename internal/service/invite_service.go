@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"balance/internal/models"
+	"balance/internal/notification"
+	"balance/internal/repository"
+	"balance/internal/token"
+	"balance/internal/tracing"
+)
+
+// InviteService ведет приглашения в группы по email и ссылкам
+type InviteService struct {
+	inviteRepo *repository.InviteRepository
+	groupRepo  *repository.GroupRepository
+	userRepo   *repository.UserRepository
+	mailer     notification.Mailer
+	publicURL  string
+	ttl        time.Duration
+	now        func() time.Time
+}
+
+func NewInviteService(
+	inviteRepo *repository.InviteRepository,
+	groupRepo *repository.GroupRepository,
+	userRepo *repository.UserRepository,
+	mailer notification.Mailer,
+	publicURL string,
+	ttl time.Duration,
+) *InviteService {
+	return &InviteService{
+		inviteRepo: inviteRepo,
+		groupRepo:  groupRepo,
+		userRepo:   userRepo,
+		mailer:     mailer,
+		publicURL:  publicURL,
+		ttl:        ttl,
+		now:        time.Now,
+	}
+}
+
+// CreateInvite создает приглашение в группу. Пригласить может любой участник.
+// Токен и ссылка возвращаются один раз; приглашение по email уходит письмом
+func (s *InviteService) CreateInvite(ctx context.Context, groupID int, req *models.CreateInviteRequest) (*models.GroupInvite, error) {
+	ctx, span := tracing.Start(ctx, "InviteService.CreateInvite")
+	defer span.End()
+
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if err := s.validateCreateInviteRequest(req); err != nil {
+		return nil, err
+	}
+
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.groupRepo.IsMember(ctx, groupID, req.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, fmt.Errorf("only the group members can invite")
+	}
+
+	inviter, err := s.userRepo.GetByID(ctx, req.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &models.GroupInvite{
+		GroupID:   groupID,
+		Email:     req.Email,
+		CreatedBy: req.CreatedBy,
+		MaxUses:   1,
+		ExpiresAt: s.now().UTC().Add(s.ttl),
+	}
+	if req.Email == "" && req.MaxUses > 0 {
+		invite.MaxUses = req.MaxUses
+	}
+	if req.ExpiresAt != nil {
+		invite.ExpiresAt = req.ExpiresAt.UTC()
+	}
+
+	secret, hash, err := token.New()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.inviteRepo.Create(ctx, invite, hash); err != nil {
+		return nil, err
+	}
+	invite.Token = secret
+	invite.URL = s.publicURL + "/invite?token=" + url.QueryEscape(secret)
+
+	if invite.Email != "" {
+		msg := notification.Message{
+			Subject: fmt.Sprintf("%s invited you to the group %s", inviter.Name, group.Name),
+			Body: fmt.Sprintf(
+				"%s invited you to share expenses in the group %s.\n\n"+
+					"Open the link to accept or decline the invitation:\n%s\n\n"+
+					"The link expires on %s.",
+				inviter.Name, group.Name, invite.URL, invite.ExpiresAt.Format("2006-01-02 15:04 MST"),
+			),
+		}
+		if err := s.mailer.Mail(ctx, invite.Email, msg); err != nil {
+			return nil, fmt.Errorf("failed to send invite: %w", err)
+		}
+	}
+
+	return invite, nil
+}
+
+// GetInvites возвращает приглашения группы без токенов. Их видят только участники группы
+func (s *InviteService) GetInvites(ctx context.Context, groupID, actorID int) ([]*models.GroupInvite, error) {
+	ctx, span := tracing.Start(ctx, "InviteService.GetInvites")
+	defer span.End()
+
+	if _, err := s.groupRepo.GetByID(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.groupRepo.IsMember(ctx, groupID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, fmt.Errorf("only the group members can see its invites")
+	}
+
+	return s.inviteRepo.GetByGroup(ctx, groupID)
+}
+
+// AcceptInvite добавляет пользователя в группу по токену приглашения
+func (s *InviteService) AcceptInvite(ctx context.Context, req *models.InviteTokenRequest) (*models.GroupMember, error) {
+	ctx, span := tracing.Start(ctx, "InviteService.AcceptInvite")
+	defer span.End()
+
+	if req.Token == "" {
+		return nil, fmt.Errorf("token is required")
+	}
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	return s.inviteRepo.Accept(ctx, token.Hash(req.Token), req.UserID)
+}
+
+// DeclineInvite отклоняет приглашение по email. Ссылку отклонить нельзя - ее можно просто не открывать
+func (s *InviteService) DeclineInvite(ctx context.Context, req *models.InviteTokenRequest) (*models.GroupInvite, error) {
+	ctx, span := tracing.Start(ctx, "InviteService.DeclineInvite")
+	defer span.End()
+
+	if req.Token == "" {
+		return nil, fmt.Errorf("token is required")
+	}
+
+	return s.inviteRepo.Decline(ctx, token.Hash(req.Token))
+}
+
+// RevokeInvite отзывает приглашение. Отозвать может его автор или создатель группы
+func (s *InviteService) RevokeInvite(ctx context.Context, groupID, inviteID int, req *models.RevokeInviteRequest) (*models.GroupInvite, error) {
+	ctx, span := tracing.Start(ctx, "InviteService.RevokeInvite")
+	defer span.End()
+
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	invite, err := s.inviteRepo.GetByID(ctx, inviteID)
+	if err != nil {
+		return nil, err
+	}
+	if invite.GroupID != groupID {
+		return nil, fmt.Errorf("invite not found")
+	}
+
+	if req.UserID != invite.CreatedBy {
+		group, err := s.groupRepo.GetByID(ctx, groupID)
+		if err != nil {
+			return nil, err
+		}
+		if req.UserID != group.CreatedBy {
+			return nil, fmt.Errorf("only the inviter or the group creator can revoke an invite")
+		}
+	}
+
+	return s.inviteRepo.Revoke(ctx, inviteID)
+}
+
+func (s *InviteService) validateCreateInviteRequest(req *models.CreateInviteRequest) error {
+	if req.CreatedBy == 0 {
+		return fmt.Errorf("created_by is required")
+	}
+	if req.Email != "" && !strings.Contains(req.Email, "@") {
+		return fmt.Errorf("invalid email format")
+	}
+	if req.Email != "" && req.MaxUses > 1 {
+		return fmt.Errorf("max_uses is only allowed for invite links")
+	}
+	if req.MaxUses < 0 || req.MaxUses > 1000 {
+		return fmt.Errorf("max_uses must be between 1 and 1000")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return fmt.Errorf("expires_at must be in the future")
+	}
+
+	return nil
+}
@@ -0,0 +1,423 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"balance/internal/ledger"
+	"balance/internal/logging"
+	"balance/internal/models"
+	"balance/internal/repository"
+	"balance/internal/splitwise"
+	"balance/internal/tracing"
+)
+
+// LedgerService переносит журнал долгов группы в CSV и обратно
+// и импортирует группы из Splitwise
+type LedgerService struct {
+	debtRepo      *repository.DebtRepository
+	groupRepo     *repository.GroupRepository
+	userRepo      *repository.UserRepository
+	inviteService *InviteService
+}
+
+// NewLedgerService создает сервис. Через inviteService импорт из Splitwise
+// приглашает в новую группу уже зарегистрированных участников
+func NewLedgerService(
+	debtRepo *repository.DebtRepository,
+	groupRepo *repository.GroupRepository,
+	userRepo *repository.UserRepository,
+	inviteService *InviteService,
+) *LedgerService {
+	return &LedgerService{
+		debtRepo:      debtRepo,
+		groupRepo:     groupRepo,
+		userRepo:      userRepo,
+		inviteService: inviteService,
+	}
+}
+
+// ExportGroup пишет долги группы в w в формате CSV. Выгрузить их может только
+// участник группы actorID. Группа и доступ проверяются до того, как в w что-либо
+// записано, поэтому их ошибки еще можно отдать клиенту обычным ответом
+func (s *LedgerService) ExportGroup(ctx context.Context, groupID, actorID int, w io.Writer) error {
+	ctx, span := tracing.Start(ctx, "LedgerService.ExportGroup")
+	defer span.End()
+
+	if _, err := s.groupRepo.GetByID(ctx, groupID); err != nil {
+		return err
+	}
+
+	isMember, err := s.groupRepo.IsMember(ctx, groupID, actorID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return fmt.Errorf("only the group members can export its debts")
+	}
+
+	writer := ledger.NewWriter(w)
+	if err := writer.WriteHeader(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	err = s.debtRepo.EachInGroup(ctx, groupID, func(debt *models.Debt) error {
+		return writer.Write(debt)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	return nil
+}
+
+// ImportGroup читает CSV и записывает долги в группу от имени userID.
+// Стороны долга ищутся по email среди участников группы. Если хотя бы одна
+// строка содержит ошибку, ничего не записывается и все ошибки возвращаются
+// в результате. При dryRun файл только проверяется
+func (s *LedgerService) ImportGroup(ctx context.Context, groupID, userID int, r io.Reader, dryRun bool) (*models.ImportResult, error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.ImportGroup")
+	defer span.End()
+
+	if userID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	if _, err := s.groupRepo.GetByID(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	members, err := s.groupRepo.GetMembers(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+
+	isMember := false
+	byEmail := make(map[string]int, len(members))
+	for _, m := range members {
+		byEmail[strings.ToLower(m.Email)] = m.ID
+		isMember = isMember || m.ID == userID
+	}
+	if !isMember {
+		return nil, fmt.Errorf("only the group members can import debts")
+	}
+
+	rows, rowErrors, err := ledger.Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ImportResult{
+		DryRun: dryRun,
+		Rows:   len(rows) + len(rowErrors),
+		Errors: rowErrors,
+	}
+
+	var debts []*models.Debt
+	for _, row := range rows {
+		debt, err := importedDebt(row, groupID, userID, byEmail)
+		if err != nil {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: row.Line, Error: err.Error()})
+			continue
+		}
+		debts = append(debts, debt)
+	}
+	// Ошибки разбора и проверки собираются в разных проходах
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Row < result.Errors[j].Row
+	})
+
+	if dryRun || len(result.Errors) > 0 || len(debts) == 0 {
+		return result, nil
+	}
+
+	if err := s.debtRepo.Import(ctx, debts); err != nil {
+		return nil, err
+	}
+	result.Imported = len(debts)
+
+	return result, nil
+}
+
+// ImportSplitwise создает группу из CSV-экспорта Splitwise. Участники из файла
+// сопоставляются пользователям по email из req.Members; если пользователя с таким
+// email нет, он создается, а для участников без email создаются гости. Уже
+// зарегистрированные пользователи получают приглашение в группу.
+// Каждая строка превращается в долги должников плательщикам (см. splitwise.Transfers).
+// Как и в CreateDebt, без подтверждения активен только долг самого импортирующего.
+// Как и ImportGroup, при ошибках в строках ничего не записывается. Результат
+// содержит сверку итоговых балансов с файлом
+func (s *LedgerService) ImportSplitwise(ctx context.Context, req *models.SplitwiseImportRequest) (*models.SplitwiseImportResult, error) {
+	ctx, span := tracing.Start(ctx, "LedgerService.ImportSplitwise")
+	defer span.End()
+
+	if req.UserID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+	if len(req.GroupName) < 2 || len(req.GroupName) > 100 {
+		return nil, fmt.Errorf("group_name must be between 2 and 100 characters long")
+	}
+	if req.CSV == "" {
+		return nil, fmt.Errorf("csv is required")
+	}
+
+	importer, err := s.userRepo.GetByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	export, rowErrors, err := splitwise.Read(strings.NewReader(req.CSV))
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &models.GroupImport{
+		Group: models.CreateGroupRequest{
+			Name:        req.GroupName,
+			Description: "Imported from Splitwise",
+			CreatedBy:   req.UserID,
+		},
+		Members: []*models.User{importer},
+	}
+	result := &models.SplitwiseImportResult{
+		DryRun: req.DryRun,
+		Rows:   len(export.Rows) + len(rowErrors),
+		Errors: rowErrors,
+	}
+
+	// Сопоставляем участников пользователям
+	users := make([]*models.User, len(export.Participants))
+	byID := map[int]string{}
+	for i, name := range export.Participants {
+		user, match, err := s.matchParticipant(ctx, name, req.Members[name])
+		if err != nil {
+			return nil, err
+		}
+		if user.ID != 0 {
+			if other, ok := byID[user.ID]; ok {
+				return nil, fmt.Errorf("participants %s and %s are the same user", other, name)
+			}
+			byID[user.ID] = name
+		}
+
+		users[i] = user
+		if user.ID == 0 {
+			imp.NewUsers = append(imp.NewUsers, user)
+			imp.Members = append(imp.Members, user)
+		}
+		result.Participants = append(result.Participants, &models.ImportParticipant{
+			Name:    name,
+			Email:   user.Email,
+			Match:   match,
+			Invited: user.ID != 0 && user.ID != importer.ID,
+		})
+	}
+	for name := range req.Members {
+		if !containsString(export.Participants, name) {
+			return nil, fmt.Errorf("participant %s is not in the file", name)
+		}
+	}
+
+	// Строки превращаем в долги. Все строки должны быть в одной валюте
+	var currency string
+	actual := make([]int64, len(users))
+	expected := make([]int64, len(users))
+	for _, row := range export.Rows {
+		if currency == "" {
+			currency = row.Currency
+		} else if row.Currency != currency {
+			result.Errors = append(result.Errors, models.ImportRowError{
+				Row:   row.Line,
+				Error: fmt.Sprintf("currency %s differs from %s, mixed currencies are not supported", row.Currency, currency),
+			})
+			continue
+		}
+
+		transfers := splitwise.Transfers(row.Shares)
+		if len(transfers) == 0 {
+			continue
+		}
+		if row.IsPayment() {
+			result.Payments++
+		} else {
+			result.Expenses++
+		}
+
+		for i, share := range row.Shares {
+			expected[i] += share
+		}
+		for _, t := range transfers {
+			imp.Debts = append(imp.Debts, &models.Debt{
+				FromUser:    users[t.From],
+				ToUser:      users[t.To],
+				Amount:      float64(t.Amount) / 100,
+				Description: row.Description,
+				Status:      splitwiseDebtStatus(importer, users[t.From]),
+				CreatedBy:   req.UserID,
+				CreatedAt:   row.Date,
+			})
+			actual[t.From] -= t.Amount
+			actual[t.To] += t.Amount
+		}
+	}
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Row < result.Errors[j].Row
+	})
+	result.Debts = len(imp.Debts)
+
+	// Если в файле есть итоговая строка, сверяемся с ней, иначе - с суммой по строкам
+	if export.Totals != nil {
+		expected = export.Totals
+	}
+
+	if !req.DryRun && len(result.Errors) == 0 {
+		group, err := s.groupRepo.Import(ctx, imp)
+		if err != nil {
+			return nil, err
+		}
+		result.Group = group
+
+		// Группа уже создана, поэтому неотправленное приглашение ее не отменяет:
+		// пригласить можно повторно
+		for _, participant := range result.Participants {
+			if !participant.Invited {
+				continue
+			}
+			_, err := s.inviteService.CreateInvite(ctx, group.ID, &models.CreateInviteRequest{
+				CreatedBy: importer.ID,
+				Email:     participant.Email,
+			})
+			if err != nil {
+				logging.FromContext(ctx).Error("Failed to invite imported participant",
+					"group_id", group.ID, "error", err)
+				participant.Invited = false
+			}
+		}
+	}
+
+	result.Balanced = true
+	for i, user := range users {
+		result.Participants[i].UserID = user.ID
+		result.Reconciliation = append(result.Reconciliation, models.ReconciliationLine{
+			Name:       export.Participants[i],
+			UserID:     user.ID,
+			Expected:   float64(expected[i]) / 100,
+			Actual:     float64(actual[i]) / 100,
+			Difference: float64(actual[i]-expected[i]) / 100,
+		})
+		if actual[i] != expected[i] {
+			result.Balanced = false
+		}
+	}
+
+	return result, nil
+}
+
+// splitwiseDebtStatus - статус импортированного долга: без подтверждения
+// активны только долги самого импортирующего и гостей (см. initialDebtStatus)
+func splitwiseDebtStatus(importer, debtor *models.User) string {
+	if debtor == importer || debtor.Guest {
+		return models.DebtStatusActive
+	}
+	return models.DebtStatusProposed
+}
+
+// matchParticipant находит пользователя для участника из файла. Если его нужно
+// создать, возвращается пользователь с нулевым ID
+func (s *LedgerService) matchParticipant(ctx context.Context, name, email string) (*models.User, string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return &models.User{Name: name, Guest: true}, models.ParticipantPlaceholder, nil
+	}
+
+	if !strings.Contains(email, "@") {
+		return nil, "", fmt.Errorf("invalid email %q for participant %s", email, name)
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err == nil {
+		return user, models.ParticipantExisting, nil
+	}
+	if err.Error() != "user not found" {
+		return nil, "", err
+	}
+
+	return &models.User{Name: name, Email: email}, models.ParticipantCreated, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// importedDebt проверяет строку импорта и превращает ее в долг группы
+func importedDebt(row *ledger.Row, groupID, userID int, byEmail map[string]int) (*models.Debt, error) {
+	fromID, ok := byEmail[row.FromEmail]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a member of the group", row.FromEmail)
+	}
+	toID, ok := byEmail[row.ToEmail]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a member of the group", row.ToEmail)
+	}
+
+	debt := &models.Debt{
+		GroupID:     groupID,
+		FromUserID:  fromID,
+		ToUserID:    toID,
+		Amount:      row.Amount,
+		Description: row.Description,
+		Status:      row.Status,
+		CreatedBy:   userID,
+		DueAt:       row.DueAt,
+	}
+
+	// Как и в CreateDebt, без подтверждения активен только долг самого
+	// импортирующего. Остальные ждут подтверждения должника: иначе кредитор или
+	// любой участник группы мог бы записать долг на другого без его согласия
+	debtor := userID == fromID
+	if debt.Status == "" {
+		debt.Status = models.DebtStatusActive
+	}
+	if !isValidDebtStatus(debt.Status) {
+		return nil, fmt.Errorf("invalid status %q", row.Status)
+	}
+	if !debtor && (debt.Status == models.DebtStatusActive || debt.Status == models.DebtStatusDisputed) {
+		debt.Status = models.DebtStatusProposed
+	}
+
+	if row.Date != nil {
+		debt.CreatedAt = *row.Date
+	}
+	if debt.Status == models.DebtStatusSettled {
+		debt.SettledAt = row.SettledAt
+		if debt.SettledAt == nil {
+			debt.SettledAt = row.Date
+		}
+	}
+
+	// Импортирующий может не быть стороной долга, поэтому проверки CreateDebt
+	// про created_by здесь не применяются
+	if debt.FromUserID == debt.ToUserID {
+		return nil, fmt.Errorf("from_email and to_email must be different")
+	}
+	if debt.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be greater than 0")
+	}
+	if len(debt.Description) > 500 {
+		return nil, fmt.Errorf("description must be no more than 500 characters long")
+	}
+
+	return debt, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+
+	"balance/internal/ledger"
+	"balance/internal/models"
+)
+
+func TestImportedDebtStatus(t *testing.T) {
+	const groupID, alice, bob, carol = 1, 10, 20, 30
+	byEmail := map[string]int{"alice@example.com": alice, "bob@example.com": bob, "carol@example.com": carol}
+
+	tests := []struct {
+		importer int
+		status   string
+		want     string
+	}{
+		{alice, "", models.DebtStatusActive},
+		{bob, "", models.DebtStatusProposed},
+		{bob, models.DebtStatusDisputed, models.DebtStatusProposed},
+		{bob, models.DebtStatusSettled, models.DebtStatusSettled},
+		{carol, "", models.DebtStatusProposed},
+		{carol, models.DebtStatusActive, models.DebtStatusProposed},
+		{carol, models.DebtStatusDisputed, models.DebtStatusProposed},
+		{carol, models.DebtStatusSettled, models.DebtStatusSettled},
+		{alice, models.DebtStatusDisputed, models.DebtStatusDisputed},
+	}
+	for _, tt := range tests {
+		row := &ledger.Row{Amount: 10, FromEmail: "alice@example.com", ToEmail: "bob@example.com", Status: tt.status}
+		debt, err := importedDebt(row, groupID, tt.importer, byEmail)
+		if err != nil {
+			t.Fatalf("importedDebt by %d with status %q: %v", tt.importer, tt.status, err)
+		}
+		if debt.Status != tt.want {
+			t.Errorf("importedDebt by %d with status %q: got %s, want %s", tt.importer, tt.status, debt.Status, tt.want)
+		}
+		if debt.CreatedBy != tt.importer {
+			t.Errorf("created_by = %d, want %d", debt.CreatedBy, tt.importer)
+		}
+	}
+}
+
+func TestSplitwiseDebtStatus(t *testing.T) {
+	importer := &models.User{ID: 1}
+	other := &models.User{ID: 2}
+	created := &models.User{Name: "Carol"}
+	guest := &models.User{Name: "Dave", Guest: true}
+
+	tests := []struct {
+		debtor *models.User
+		want   string
+	}{
+		{importer, models.DebtStatusActive},
+		{other, models.DebtStatusProposed},
+		{created, models.DebtStatusProposed},
+		{guest, models.DebtStatusActive},
+	}
+	for _, tt := range tests {
+		if got := splitwiseDebtStatus(importer, tt.debtor); got != tt.want {
+			t.Errorf("debt of %+v: got %s, want %s", tt.debtor, got, tt.want)
+		}
+	}
+}
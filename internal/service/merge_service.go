@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"balance/internal/models"
+	"balance/internal/repository"
+	"balance/internal/tracing"
+)
+
+// MergeService сливает дубликаты аккаунтов. Операция административная
+type MergeService struct {
+	mergeRepo *repository.MergeRepository
+}
+
+func NewMergeService(mergeRepo *repository.MergeRepository) *MergeService {
+	return &MergeService{
+		mergeRepo: mergeRepo,
+	}
+}
+
+// MergeUsers сливает пользователя req.SourceUserID в targetID: участие в группах,
+// долги, серии, дружба и подписки переходят к targetID, исходный пользователь
+// удаляется. При req.DryRun возвращает отчет, ничего не меняя
+func (s *MergeService) MergeUsers(ctx context.Context, targetID int, req *models.MergeUsersRequest) (*models.UserMerge, error) {
+	ctx, span := tracing.Start(ctx, "MergeService.MergeUsers")
+	defer span.End()
+
+	if req.SourceUserID == 0 {
+		return nil, fmt.Errorf("source_user_id is required")
+	}
+	if req.SourceUserID == targetID {
+		return nil, fmt.Errorf("cannot merge a user into itself")
+	}
+
+	return s.mergeRepo.Merge(ctx, targetID, req.SourceUserID, req.DryRun)
+}
+
+// GetMerges возвращает журнал слияний; userID фильтрует по целевому пользователю
+func (s *MergeService) GetMerges(ctx context.Context, userID int) ([]*models.UserMerge, error) {
+	ctx, span := tracing.Start(ctx, "MergeService.GetMerges")
+	defer span.End()
+
+	return s.mergeRepo.GetAll(ctx, userID)
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"balance/internal/logging"
+	"balance/internal/models"
+	"balance/internal/notification"
+	"balance/internal/repository"
+	"balance/internal/tracing"
+)
+
+const (
+	// notificationBatchSize - сколько событий или уведомлений обрабатывается за раз
+	notificationBatchSize = 100
+	// notificationLease - на сколько уведомление резервируется за отправителем
+	notificationLease = 5 * time.Minute
+	// notificationBaseBackoff и notificationMaxBackoff задают экспоненциальную паузу между попытками
+	notificationBaseBackoff = 30 * time.Second
+	notificationMaxBackoff  = time.Hour
+)
+
+// NotificationService разбирает outbox в уведомления с учетом настроек пользователей
+// и доставляет их через подключенные каналы с повторными попытками
+type NotificationService struct {
+	outboxRepo       *repository.OutboxRepository
+	notificationRepo *repository.NotificationRepository
+	userRepo         *repository.UserRepository
+	notifiers        map[string]notification.Notifier
+	channels         []string
+	maxAttempts      int
+}
+
+func NewNotificationService(
+	outboxRepo *repository.OutboxRepository,
+	notificationRepo *repository.NotificationRepository,
+	userRepo *repository.UserRepository,
+	maxAttempts int,
+	notifiers ...notification.Notifier,
+) *NotificationService {
+	s := &NotificationService{
+		outboxRepo:       outboxRepo,
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		notifiers:        make(map[string]notification.Notifier, len(notifiers)),
+		maxAttempts:      maxAttempts,
+	}
+	for _, n := range notifiers {
+		s.notifiers[n.Channel()] = n
+		s.channels = append(s.channels, n.Channel())
+	}
+	return s
+}
+
+// Dispatch разбирает новые события outbox и отправляет уведомления, которым пришло время.
+// Вызывается планировщиком периодически
+func (s *NotificationService) Dispatch(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "NotificationService.Dispatch")
+	defer span.End()
+
+	for {
+		processed, err := s.outboxRepo.ProcessEvents(ctx, notificationBatchSize, s.expandEvent)
+		if err != nil {
+			return err
+		}
+		if processed < notificationBatchSize {
+			break
+		}
+	}
+
+	notifications, err := s.notificationRepo.ClaimDue(ctx, notificationBatchSize, notificationLease)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range notifications {
+		s.deliver(ctx, n)
+	}
+
+	return nil
+}
+
+// GetPreferences возвращает настройки пользователя для всех каналов и типов событий,
+// подставляя значение по умолчанию (включено) там, где настройка не сохранена
+func (s *NotificationService) GetPreferences(ctx context.Context, userID int) ([]models.NotificationPreference, error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.GetPreferences")
+	defer span.End()
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.loadPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var preferences []models.NotificationPreference
+	for _, channel := range s.channels {
+		for _, eventType := range models.EventTypes {
+			preferences = append(preferences, models.NotificationPreference{
+				Channel:   channel,
+				EventType: eventType,
+				Enabled:   enabled(channel, eventType),
+			})
+		}
+	}
+
+	return preferences, nil
+}
+
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID int, req *models.UpdateNotificationPreferencesRequest) ([]models.NotificationPreference, error) {
+	ctx, span := tracing.Start(ctx, "NotificationService.UpdatePreferences")
+	defer span.End()
+
+	if len(req.Preferences) == 0 {
+		return nil, fmt.Errorf("preferences are required")
+	}
+	for _, p := range req.Preferences {
+		if _, ok := s.notifiers[p.Channel]; !ok {
+			return nil, fmt.Errorf("unknown channel %q", p.Channel)
+		}
+		if !isValidEventType(p.EventType) {
+			return nil, fmt.Errorf("unknown event type %q", p.EventType)
+		}
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.notificationRepo.SavePreferences(ctx, userID, req.Preferences); err != nil {
+		return nil, err
+	}
+
+	return s.GetPreferences(ctx, userID)
+}
+
+// expandEvent решает, кого и о чем уведомить по событию outbox
+func (s *NotificationService) expandEvent(ctx context.Context, event *models.Event) ([]*models.Notification, error) {
+	recipients, msg, err := s.describeEvent(ctx, event)
+	if err != nil {
+		// Испорченное событие не должно блокировать очередь: пропускаем его
+		logging.FromContext(ctx).Warn("Skipping outbox event", "event_id", event.ID, "event_type", event.Type, "error", err)
+		return nil, nil
+	}
+
+	var notifications []*models.Notification
+	for _, userID := range recipients {
+		enabled, err := s.loadPreferences(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, channel := range s.channels {
+			if !enabled(channel, event.Type) {
+				continue
+			}
+			notifications = append(notifications, &models.Notification{
+				UserID:  userID,
+				Channel: channel,
+				Subject: msg.Subject,
+				Body:    msg.Body,
+			})
+		}
+	}
+
+	return notifications, nil
+}
+
+// describeEvent возвращает получателей и текст уведомления для события
+func (s *NotificationService) describeEvent(ctx context.Context, event *models.Event) ([]int, notification.Message, error) {
+	switch event.Type {
+	case models.EventDebtCreated:
+		var debt models.Debt
+		if err := json.Unmarshal(event.Payload, &debt); err != nil {
+			return nil, notification.Message{}, err
+		}
+
+		// Уведомляем стороны долга, кроме той, что его записала
+		var recipients []int
+		for _, userID := range []int{debt.FromUserID, debt.ToUserID} {
+			if userID != debt.CreatedBy {
+				recipients = append(recipients, userID)
+			}
+		}
+
+		msg := notification.Message{
+			Subject: fmt.Sprintf("New debt: %.2f", debt.Amount),
+			Body: fmt.Sprintf("%s owes %s %.2f (%s). Status: %s.",
+				s.userName(ctx, debt.FromUserID), s.userName(ctx, debt.ToUserID),
+				debt.Amount, debt.Description, debt.Status),
+		}
+		if debt.Status == models.DebtStatusProposed {
+			msg.Body += " The debtor needs to confirm it."
+		}
+		return recipients, msg, nil
+
+	case models.EventDebtSettled:
+		var debt models.Debt
+		if err := json.Unmarshal(event.Payload, &debt); err != nil {
+			return nil, notification.Message{}, err
+		}
+
+		msg := notification.Message{
+			Subject: fmt.Sprintf("Debt settled: %.2f", debt.Amount),
+			Body: fmt.Sprintf("%s marked your debt of %.2f (%s) as settled.",
+				s.userName(ctx, debt.ToUserID), debt.Amount, debt.Description),
+		}
+		return []int{debt.FromUserID}, msg, nil
+
+	case models.EventDebtReminder:
+		var payload models.DebtReminderPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, notification.Message{}, err
+		}
+		if payload.Debt == nil {
+			return nil, notification.Message{}, fmt.Errorf("reminder without debt")
+		}
+
+		debt := payload.Debt
+		offset := time.Duration(payload.OffsetSeconds) * time.Second
+		msg := notification.Message{Subject: fmt.Sprintf("Debt reminder: %.2f", debt.Amount)}
+		if offset < 0 {
+			msg.Body = fmt.Sprintf("Your debt of %.2f to %s (%s) is due in %s.",
+				debt.Amount, s.userName(ctx, debt.ToUserID), debt.Description, -offset)
+		} else {
+			msg.Body = fmt.Sprintf("Your debt of %.2f to %s (%s) is overdue by %s.",
+				debt.Amount, s.userName(ctx, debt.ToUserID), debt.Description, offset)
+		}
+		return []int{debt.FromUserID}, msg, nil
+
+	case models.EventMemberAdded:
+		var payload models.MemberAddedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, notification.Message{}, err
+		}
+
+		msg := notification.Message{
+			Subject: fmt.Sprintf("You were added to %s", payload.GroupName),
+			Body:    fmt.Sprintf("You are now a member of the group %s.", payload.GroupName),
+		}
+		return []int{payload.UserID}, msg, nil
+	}
+
+	return nil, notification.Message{}, fmt.Errorf("unknown event type")
+}
+
+// deliver отправляет одно уведомление и сохраняет результат
+func (s *NotificationService) deliver(ctx context.Context, n *models.Notification) {
+	notifier, ok := s.notifiers[n.Channel]
+	if !ok {
+		if err := s.notificationRepo.MarkFailed(ctx, n.ID, fmt.Errorf("channel %s is not configured", n.Channel), 0); err != nil {
+			logging.FromContext(ctx).Error("Failed to update notification", "notification_id", n.ID, "error", err)
+		}
+		return
+	}
+
+	sendErr := notifier.Notify(ctx, n.User, notification.Message{Subject: n.Subject, Body: n.Body})
+	if sendErr == nil {
+		if err := s.notificationRepo.MarkSent(ctx, n.ID); err != nil {
+			logging.FromContext(ctx).Error("Failed to update notification", "notification_id", n.ID, "error", err)
+		}
+		return
+	}
+
+	var retryIn time.Duration
+	if n.Attempts < s.maxAttempts {
+		retryIn = retryBackoff(n.Attempts)
+	}
+	logging.FromContext(ctx).Warn("Failed to send notification", "notification_id", n.ID, "channel", n.Channel, "attempt", n.Attempts, "error", sendErr)
+
+	if err := s.notificationRepo.MarkFailed(ctx, n.ID, sendErr, retryIn); err != nil {
+		logging.FromContext(ctx).Error("Failed to update notification", "notification_id", n.ID, "error", err)
+	}
+}
+
+// loadPreferences возвращает функцию, которая отвечает, включен ли канал для типа события
+func (s *NotificationService) loadPreferences(ctx context.Context, userID int) (func(channel, eventType string) bool, error) {
+	saved, err := s.notificationRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	disabled := make(map[string]bool)
+	for _, p := range saved {
+		if !p.Enabled {
+			disabled[p.Channel+"/"+p.EventType] = true
+		}
+	}
+
+	return func(channel, eventType string) bool {
+		return !disabled[channel+"/"+eventType]
+	}, nil
+}
+
+func (s *NotificationService) userName(ctx context.Context, userID int) string {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Sprintf("user #%d", userID)
+	}
+	return user.Name
+}
+
+// retryBackoff возвращает паузу перед следующей попыткой: 30s, 1m, 2m, ... но не больше часа
+func retryBackoff(attempts int) time.Duration {
+	backoff := notificationBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= notificationMaxBackoff {
+			return notificationMaxBackoff
+		}
+	}
+	return backoff
+}
+
+func isValidEventType(eventType string) bool {
+	for _, t := range models.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/internal/models"
+	"balance/internal/recurrence"
+	"balance/internal/repository"
+	"balance/internal/tracing"
+)
+
+const (
+	// recurringBatchSize - сколько серий обрабатывается в одной транзакции
+	recurringBatchSize = 100
+	// recurringMaxBatches ограничивает работу за один запуск планировщика,
+	// чтобы серия с давно прошедшим началом не занимала его надолго
+	recurringMaxBatches = 50
+)
+
+type RecurringService struct {
+	recurringRepo  *repository.RecurringRepository
+	friendshipRepo *repository.FriendshipRepository
+	now            func() time.Time
+}
+
+func NewRecurringService(recurringRepo *repository.RecurringRepository, friendshipRepo *repository.FriendshipRepository) *RecurringService {
+	return &RecurringService{
+		recurringRepo:  recurringRepo,
+		friendshipRepo: friendshipRepo,
+		now:            time.Now,
+	}
+}
+
+func (s *RecurringService) CreateSeries(ctx context.Context, req *models.CreateRecurringSeriesRequest) (*models.RecurringSeries, error) {
+	ctx, span := tracing.Start(ctx, "RecurringService.CreateSeries")
+	defer span.End()
+
+	if req.Interval == 0 {
+		req.Interval = 1
+	}
+	req.StartAt = req.StartAt.UTC()
+	if req.Until != nil {
+		until := req.Until.UTC()
+		req.Until = &until
+	}
+
+	if err := s.validateCreateSeriesRequest(req); err != nil {
+		return nil, err
+	}
+
+	// Серия без группы создает прямые долги, поэтому каждый плательщик
+	// должен дружить с кредитором
+	if req.GroupID == 0 {
+		for _, share := range req.Shares {
+			friends, err := s.friendshipRepo.AreFriends(ctx, share.FromUserID, req.ToUserID)
+			if err != nil {
+				return nil, err
+			}
+			if !friends {
+				return nil, fmt.Errorf("only the friends can have direct debts")
+			}
+		}
+	}
+
+	rule := recurrence.Rule{
+		Frequency: req.Frequency,
+		Interval:  req.Interval,
+		MonthDay:  req.MonthDay,
+		Start:     req.StartAt,
+		Until:     req.Until,
+		Count:     req.Count,
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	first, ok := rule.First()
+	if !ok {
+		return nil, fmt.Errorf("rule has no occurrences")
+	}
+
+	return s.recurringRepo.Create(ctx, req, first)
+}
+
+func (s *RecurringService) GetSeries(ctx context.Context, id int) (*models.RecurringSeries, error) {
+	ctx, span := tracing.Start(ctx, "RecurringService.GetSeries")
+	defer span.End()
+
+	return s.recurringRepo.GetByID(ctx, id)
+}
+
+func (s *RecurringService) GetAllSeries(ctx context.Context, groupID, userID int) ([]*models.RecurringSeries, error) {
+	ctx, span := tracing.Start(ctx, "RecurringService.GetAllSeries")
+	defer span.End()
+
+	series, err := s.recurringRepo.GetAll(ctx, groupID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recurring series: %w", err)
+	}
+
+	return series, nil
+}
+
+// UpdateSeries изменяет описание, доли или правило повторения серии. Поля правила
+// применяются по отдельности, непереданные остаются прежними. Следующее повторение
+// пересчитывается от последнего созданного или от текущего момента
+func (s *RecurringService) UpdateSeries(ctx context.Context, id int, req *models.UpdateRecurringSeriesRequest) (*models.RecurringSeries, error) {
+	ctx, span := tracing.Start(ctx, "RecurringService.UpdateSeries")
+	defer span.End()
+
+	series, err := s.getOwnedSeries(ctx, id, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if series.Status == models.SeriesStatusEnded {
+		return nil, fmt.Errorf("cannot edit an ended series")
+	}
+
+	if req.Description != nil {
+		if len(*req.Description) > 500 {
+			return nil, fmt.Errorf("description must be no more than 500 characters long")
+		}
+		series.Description = *req.Description
+	}
+	if req.Shares != nil {
+		if err := validateShares(series.ToUserID, req.Shares); err != nil {
+			return nil, err
+		}
+		series.Shares = req.Shares
+	}
+	if err := applyRuleUpdate(series, req); err != nil {
+		return nil, err
+	}
+
+	if series.Status == models.SeriesStatusActive {
+		s.reschedule(series)
+	}
+
+	return s.recurringRepo.Update(ctx, series)
+}
+
+// applyRuleUpdate применяет к правилу серии переданные поля req и проверяет результат
+func applyRuleUpdate(series *models.RecurringSeries, req *models.UpdateRecurringSeriesRequest) error {
+	if req.Frequency != nil {
+		series.Frequency = *req.Frequency
+		// День месяца есть только у monthly: при смене частоты он сбрасывается
+		if series.Frequency != recurrence.Monthly && req.MonthDay == nil {
+			series.MonthDay = 0
+		}
+	}
+	if req.Interval != nil {
+		if *req.Interval <= 0 {
+			return fmt.Errorf("interval must be greater than 0")
+		}
+		series.Interval = *req.Interval
+	}
+	if req.MonthDay != nil {
+		series.MonthDay = *req.MonthDay
+	}
+	if req.Count != nil {
+		series.Count = *req.Count
+	}
+	if req.Until != nil {
+		until := req.Until.UTC()
+		series.Until = &until
+	}
+	if req.StartAt != nil {
+		series.StartAt = req.StartAt.UTC()
+	}
+
+	return seriesRule(series).Validate()
+}
+
+// SetSeriesStatus ставит серию на паузу (pause), возобновляет (resume) или завершает (end)
+func (s *RecurringService) SetSeriesStatus(ctx context.Context, id int, action string, req *models.SeriesActionRequest) (*models.RecurringSeries, error) {
+	ctx, span := tracing.Start(ctx, "RecurringService.SetSeriesStatus")
+	defer span.End()
+
+	series, err := s.getOwnedSeries(ctx, id, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch action {
+	case "pause":
+		if series.Status != models.SeriesStatusActive {
+			return nil, fmt.Errorf("cannot pause a series in status %s", series.Status)
+		}
+		series.Status = models.SeriesStatusPaused
+		series.NextRunAt = nil
+	case "resume":
+		if series.Status != models.SeriesStatusPaused {
+			return nil, fmt.Errorf("cannot resume a series in status %s", series.Status)
+		}
+		// Повторения, пропущенные во время паузы, не создаются
+		series.Status = models.SeriesStatusActive
+		s.reschedule(series)
+	case "end":
+		if series.Status == models.SeriesStatusEnded {
+			return nil, fmt.Errorf("cannot end a series in status %s", series.Status)
+		}
+		series.Status = models.SeriesStatusEnded
+		series.NextRunAt = nil
+	default:
+		return nil, fmt.Errorf("unknown series action")
+	}
+
+	return s.recurringRepo.Update(ctx, series)
+}
+
+// RunDueSeries создает долги для всех наступивших повторений. Вызывается планировщиком
+func (s *RecurringService) RunDueSeries(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "RecurringService.RunDueSeries")
+	defer span.End()
+
+	for i := 0; i < recurringMaxBatches; i++ {
+		processed, err := s.recurringRepo.RunDue(ctx, s.now().UTC(), recurringBatchSize, s.planRun)
+		if err != nil {
+			return err
+		}
+		if processed == 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+// planRun вычисляет повторение, которое нужно создать сейчас, и следующее за ним
+func (s *RecurringService) planRun(series *models.RecurringSeries) models.SeriesRun {
+	run := models.SeriesRun{OccurrenceAt: *series.NextRunAt}
+
+	if series.Count == 0 || series.Occurrences+1 < series.Count {
+		if next, ok := seriesRule(series).Next(run.OccurrenceAt); ok {
+			run.NextRunAt = &next
+		}
+	}
+
+	return run
+}
+
+// reschedule пересчитывает next_run_at активной серии. Если повторений больше нет, серия завершается
+func (s *RecurringService) reschedule(series *models.RecurringSeries) {
+	rule := seriesRule(series)
+
+	after := s.now().UTC()
+	if series.LastRunAt == nil && series.StartAt.After(after) {
+		after = series.StartAt.Add(-time.Nanosecond)
+	}
+	if series.LastRunAt != nil && series.LastRunAt.After(after) {
+		after = *series.LastRunAt
+	}
+
+	next, ok := rule.Next(after)
+	if !ok || (series.Count > 0 && series.Occurrences >= series.Count) {
+		series.Status = models.SeriesStatusEnded
+		series.NextRunAt = nil
+		return
+	}
+	series.NextRunAt = &next
+}
+
+// getOwnedSeries возвращает серию, если userID - ее автор или получатель платежей
+func (s *RecurringService) getOwnedSeries(ctx context.Context, id, userID int) (*models.RecurringSeries, error) {
+	if userID == 0 {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	series, err := s.recurringRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if userID != series.CreatedBy && userID != series.ToUserID {
+		return nil, fmt.Errorf("only the creator or the creditor can change this series")
+	}
+
+	return series, nil
+}
+
+func (s *RecurringService) validateCreateSeriesRequest(req *models.CreateRecurringSeriesRequest) error {
+	if req.ToUserID == 0 {
+		return fmt.Errorf("to_user_id is required")
+	}
+	if len(req.Description) > 500 {
+		return fmt.Errorf("description must be no more than 500 characters long")
+	}
+	if err := validateShares(req.ToUserID, req.Shares); err != nil {
+		return err
+	}
+
+	isParty := req.CreatedBy == req.ToUserID
+	for _, share := range req.Shares {
+		if share.FromUserID == req.CreatedBy {
+			isParty = true
+		}
+	}
+	if !isParty {
+		return fmt.Errorf("created_by must be one of the series parties")
+	}
+
+	return nil
+}
+
+func validateShares(toUserID int, shares []models.RecurringShare) error {
+	if len(shares) == 0 {
+		return fmt.Errorf("at least one share is required")
+	}
+
+	seen := make(map[int]bool, len(shares))
+	for _, share := range shares {
+		if share.FromUserID == 0 {
+			return fmt.Errorf("from_user_id is required in every share")
+		}
+		if share.FromUserID == toUserID {
+			return fmt.Errorf("share user must differ from to_user_id")
+		}
+		if seen[share.FromUserID] {
+			return fmt.Errorf("duplicate share for user %d", share.FromUserID)
+		}
+		if share.Amount <= 0 {
+			return fmt.Errorf("share amount must be greater than 0")
+		}
+		seen[share.FromUserID] = true
+	}
+
+	return nil
+}
+
+func seriesRule(series *models.RecurringSeries) recurrence.Rule {
+	return recurrence.Rule{
+		Frequency: series.Frequency,
+		Interval:  series.Interval,
+		MonthDay:  series.MonthDay,
+		Start:     series.StartAt,
+		Until:     series.Until,
+		Count:     series.Count,
+	}
+}
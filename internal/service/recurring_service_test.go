@@ -0,0 +1,116 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"balance/internal/models"
+	"balance/internal/recurrence"
+)
+
+func monthlySeries() *models.RecurringSeries {
+	until := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	return &models.RecurringSeries{
+		Frequency: recurrence.Monthly,
+		Interval:  2,
+		MonthDay:  15,
+		StartAt:   time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		Until:     &until,
+		Count:     5,
+	}
+}
+
+func TestApplyRuleUpdate(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	strPtr := func(v string) *string { return &v }
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	until := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		req   models.UpdateRecurringSeriesRequest
+		check func(*models.RecurringSeries) bool
+		err   string
+	}{
+		{"interval alone", models.UpdateRecurringSeriesRequest{Interval: intPtr(3)},
+			func(s *models.RecurringSeries) bool { return s.Interval == 3 && s.MonthDay == 15 && s.Count == 5 }, ""},
+		{"month day alone", models.UpdateRecurringSeriesRequest{MonthDay: intPtr(31)},
+			func(s *models.RecurringSeries) bool { return s.MonthDay == 31 && s.Interval == 2 }, ""},
+		{"count alone", models.UpdateRecurringSeriesRequest{Count: intPtr(10)},
+			func(s *models.RecurringSeries) bool { return s.Count == 10 && s.Until != nil }, ""},
+		{"count removed", models.UpdateRecurringSeriesRequest{Count: intPtr(0)},
+			func(s *models.RecurringSeries) bool { return s.Count == 0 }, ""},
+		{"until alone", models.UpdateRecurringSeriesRequest{Until: &until},
+			func(s *models.RecurringSeries) bool { return s.Until.Equal(until) && s.Count == 5 }, ""},
+		{"start in UTC", models.UpdateRecurringSeriesRequest{StartAt: &start},
+			func(s *models.RecurringSeries) bool {
+				return s.StartAt.Equal(start) && s.StartAt.Location() == time.UTC
+			}, ""},
+		{"frequency keeps the rest", models.UpdateRecurringSeriesRequest{Frequency: strPtr(recurrence.Weekly)},
+			func(s *models.RecurringSeries) bool { return s.MonthDay == 0 && s.Interval == 2 && s.Count == 5 }, ""},
+		{"zero interval", models.UpdateRecurringSeriesRequest{Interval: intPtr(0)}, nil, "interval"},
+		{"month day of a weekly series", models.UpdateRecurringSeriesRequest{Frequency: strPtr(recurrence.Weekly), MonthDay: intPtr(5)}, nil, "month_day"},
+		{"until before start", models.UpdateRecurringSeriesRequest{StartAt: &until, Until: &start}, nil, "until"},
+	}
+	for _, tt := range tests {
+		series := monthlySeries()
+		err := applyRuleUpdate(series, &tt.req)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got %v, want an error about %s", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !tt.check(series) {
+			t.Errorf("%s: unexpected series %+v", tt.name, series)
+		}
+	}
+}
+
+func TestPlanRunStopsAtCount(t *testing.T) {
+	s := &RecurringService{now: time.Now}
+	series := monthlySeries()
+	next := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	series.NextRunAt = &next
+
+	series.Occurrences = 3
+	if run := s.planRun(series); run.NextRunAt == nil || !run.NextRunAt.Equal(time.Date(2024, 3, 15, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("fourth of five occurrences: next run %v, want 2024-03-15", run.NextRunAt)
+	}
+	series.Occurrences = 4
+	if run := s.planRun(series); !run.OccurrenceAt.Equal(next) || run.NextRunAt != nil {
+		t.Errorf("last occurrence: got %+v, want %v without a next run", run, next)
+	}
+}
+
+func TestRescheduleEndsSeries(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	s := &RecurringService{now: func() time.Time { return now }}
+
+	series := monthlySeries()
+	series.Status = models.SeriesStatusActive
+	s.reschedule(series)
+	if series.NextRunAt == nil || !series.NextRunAt.Equal(time.Date(2024, 7, 15, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("next run %v, want 2024-07-15", series.NextRunAt)
+	}
+
+	// Все повторения уже созданы
+	series.Occurrences = series.Count
+	s.reschedule(series)
+	if series.Status != models.SeriesStatusEnded || series.NextRunAt != nil {
+		t.Errorf("series with all occurrences: status %s, next run %v; want ended", series.Status, series.NextRunAt)
+	}
+
+	// until прошел
+	series = monthlySeries()
+	now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.reschedule(series)
+	if series.Status != models.SeriesStatusEnded {
+		t.Errorf("series after until: status %s, want ended", series.Status)
+	}
+}
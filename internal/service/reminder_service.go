@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"balance/internal/logging"
+	"balance/internal/repository"
+	"balance/internal/tracing"
+)
+
+type ReminderService struct {
+	debtRepo *repository.DebtRepository
+	offsets  []time.Duration
+}
+
+// NewReminderService создает сервис напоминаний о сроках долгов. offsets - смещения
+// напоминаний от срока: отрицательное - до срока, положительное - после
+func NewReminderService(debtRepo *repository.DebtRepository, offsets []time.Duration) *ReminderService {
+	return &ReminderService{
+		debtRepo: debtRepo,
+		offsets:  offsets,
+	}
+}
+
+// SendDueReminders отправляет все напоминания, время которых наступило: каждое
+// уходит через outbox как событие debt.reminder. Вызывается планировщиком периодически
+func (s *ReminderService) SendDueReminders(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "ReminderService.SendDueReminders")
+	defer span.End()
+
+	for _, offset := range s.offsets {
+		debts, err := s.debtRepo.ClaimDueReminders(ctx, offset)
+		if err != nil {
+			return fmt.Errorf("failed to claim reminders: %w", err)
+		}
+		if len(debts) > 0 {
+			logging.FromContext(ctx).Debug("Reminders sent", "offset", offset, "count", len(debts))
+		}
+	}
+
+	return nil
+}
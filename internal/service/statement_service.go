@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"balance/internal/models"
+	"balance/internal/repository"
+	"balance/internal/tracing"
+)
+
+// StatementService собирает выписки по группам
+type StatementService struct {
+	debtRepo  *repository.DebtRepository
+	groupRepo *repository.GroupRepository
+}
+
+func NewStatementService(debtRepo *repository.DebtRepository, groupRepo *repository.GroupRepository) *StatementService {
+	return &StatementService{
+		debtRepo:  debtRepo,
+		groupRepo: groupRepo,
+	}
+}
+
+// GetStatement собирает выписку по группе за период [filter.From, filter.To).
+// Сводка, балансы и план расчета учитывают долги, созданные в этом периоде.
+// Если задан filter.UserID, балансы, план и список долгов ограничиваются
+// этим пользователем, а сводка остается общей по группе. Выписку получает
+// только участник группы actorID
+func (s *StatementService) GetStatement(ctx context.Context, groupID, actorID int, filter models.StatementFilter) (*models.Statement, error) {
+	ctx, span := tracing.Start(ctx, "StatementService.GetStatement")
+	defer span.End()
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.groupRepo.IsMember(ctx, groupID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, fmt.Errorf("only the group members can see its statement")
+	}
+
+	summary, err := s.groupRepo.GetSummary(ctx, groupID, filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.debtRepo.GetSummaries(ctx, groupID, filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.debtRepo.GetAll(ctx, models.DebtFilter{
+		GroupID: groupID,
+		UserID:  filter.UserID,
+		From:    filter.From,
+		To:      filter.To,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get debts: %w", err)
+	}
+
+	// Балансы включают всех сторон долгов за период, поэтому из них же берем имена
+	users := make(map[int]*models.User, len(members))
+	for _, m := range members {
+		users[m.UserID] = &models.User{ID: m.UserID, Name: m.UserName}
+	}
+	for _, d := range transactions {
+		d.FromUser = users[d.FromUserID]
+		d.ToUser = users[d.ToUserID]
+	}
+	// В выписке долги идут в хронологическом порядке
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].CreatedAt.Before(transactions[j].CreatedAt)
+	})
+
+	plan := settlePlan(members)
+
+	if filter.UserID != 0 {
+		if _, ok := users[filter.UserID]; !ok {
+			return nil, fmt.Errorf("user not found")
+		}
+
+		var own []models.DebtSummary
+		for _, m := range members {
+			if m.UserID == filter.UserID {
+				own = append(own, m)
+			}
+		}
+		members = own
+
+		var ownPlan []models.Settlement
+		for _, p := range plan {
+			if p.FromUserID == filter.UserID || p.ToUserID == filter.UserID {
+				ownPlan = append(ownPlan, p)
+			}
+		}
+		plan = ownPlan
+	}
+
+	return &models.Statement{
+		Group:        group,
+		UserID:       filter.UserID,
+		From:         filter.From,
+		To:           filter.To,
+		GeneratedAt:  time.Now().UTC(),
+		Summary:      summary,
+		Members:      members,
+		SettlePlan:   plan,
+		Transactions: transactions,
+	}, nil
+}
+
+// settlePlan строит план расчета по чистым балансам: каждый раз наибольший
+// должник платит наибольшему кредитору. Переводов получается не больше, чем
+// участников с ненулевым балансом, минус один. Суммы считаются в копейках
+func settlePlan(summaries []models.DebtSummary) []models.Settlement {
+	type party struct {
+		id     int
+		name   string
+		amount int64
+	}
+
+	var creditors, debtors []*party
+	for _, s := range summaries {
+		cents := int64(math.Round(s.NetBalance * 100))
+		switch {
+		case cents > 0:
+			creditors = append(creditors, &party{id: s.UserID, name: s.UserName, amount: cents})
+		case cents < 0:
+			debtors = append(debtors, &party{id: s.UserID, name: s.UserName, amount: -cents})
+		}
+	}
+
+	byAmount := func(parties []*party) {
+		sort.SliceStable(parties, func(i, j int) bool { return parties[i].amount > parties[j].amount })
+	}
+
+	var plan []models.Settlement
+	for len(creditors) > 0 && len(debtors) > 0 {
+		byAmount(creditors)
+		byAmount(debtors)
+		creditor, debtor := creditors[0], debtors[0]
+
+		amount := min(creditor.amount, debtor.amount)
+		plan = append(plan, models.Settlement{
+			FromUserID:   debtor.id,
+			FromUserName: debtor.name,
+			ToUserID:     creditor.id,
+			ToUserName:   creditor.name,
+			Amount:       float64(amount) / 100,
+		})
+
+		creditor.amount -= amount
+		debtor.amount -= amount
+		if creditor.amount == 0 {
+			creditors = creditors[1:]
+		}
+		if debtor.amount == 0 {
+			debtors = debtors[1:]
+		}
+	}
+
+	return plan
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"balance/internal/models"
+	"balance/internal/repository"
+	"balance/internal/totp"
+	"balance/internal/tracing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// recoveryCodeCount - сколько кодов восстановления выдается за раз
+	recoveryCodeCount = 10
+	// recoveryAlphabet - символы кодов восстановления без похожих друг на друга 0/O и 1/I
+	recoveryAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	// totpSkew - на сколько шагов по 30 секунд могут расходиться часы пользователя
+	totpSkew = 1
+)
+
+// TwoFactorService управляет двухфакторной аутентификацией по TOTP (RFC 6238)
+type TwoFactorService struct {
+	twoFactorRepo *repository.TwoFactorRepository
+	userRepo      *repository.UserRepository
+	issuer        string
+	now           func() time.Time
+}
+
+// NewTwoFactorService создает сервис. issuer - название приложения в аутентификаторе
+func NewTwoFactorService(
+	twoFactorRepo *repository.TwoFactorRepository,
+	userRepo *repository.UserRepository,
+	issuer string,
+) *TwoFactorService {
+	return &TwoFactorService{
+		twoFactorRepo: twoFactorRepo,
+		userRepo:      userRepo,
+		issuer:        issuer,
+		now:           time.Now,
+	}
+}
+
+// GetStatus сообщает, включена ли двухфакторная аутентификация и сколько осталось кодов восстановления
+func (s *TwoFactorService) GetStatus(ctx context.Context, userID int) (*models.TwoFactorStatus, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.GetStatus")
+	defer span.End()
+
+	_, enabled, err := s.twoFactorRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.TwoFactorStatus{Enabled: enabled}
+	if enabled {
+		codes, err := s.twoFactorRepo.GetRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		status.RecoveryCodesRemaining = len(codes)
+	}
+
+	return status, nil
+}
+
+// Enabled сообщает, что для входа пользователю нужен второй фактор
+func (s *TwoFactorService) Enabled(ctx context.Context, userID int) (bool, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Enabled")
+	defer span.End()
+
+	_, enabled, err := s.twoFactorRepo.Get(ctx, userID)
+	return enabled, err
+}
+
+// Enroll выпускает новый секрет. Двухфакторная аутентификация включится после Confirm
+func (s *TwoFactorService) Enroll(ctx context.Context, userID int) (*models.TwoFactorEnrollment, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Enroll")
+	defer span.End()
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Guest {
+		return nil, fmt.Errorf("cannot enable two-factor authentication for a guest")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.SetSecret(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Name
+	}
+
+	return &models.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, account, secret),
+	}, nil
+}
+
+// Confirm включает двухфакторную аутентификацию, если код из приложения подходит
+// к выпущенному секрету, и возвращает коды восстановления
+func (s *TwoFactorService) Confirm(ctx context.Context, userID int, req *models.TwoFactorCodeRequest) (*models.TwoFactorRecoveryCodes, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Confirm")
+	defer span.End()
+
+	secret, enabled, err := s.twoFactorRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, fmt.Errorf("cannot enroll while two-factor authentication is enabled")
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("two-factor enrollment not found")
+	}
+
+	step, ok := totp.Validate(secret, req.Code, s.now(), totpSkew)
+	if !ok {
+		return nil, fmt.Errorf("invalid two-factor code")
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.Enable(ctx, userID, secret, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// Disable выключает двухфакторную аутентификацию. Пользователь подтверждает себя
+// паролем, если он задан, и кодом из приложения или кодом восстановления
+func (s *TwoFactorService) Disable(ctx context.Context, userID int, req *models.DisableTwoFactorRequest) error {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Disable")
+	defer span.End()
+
+	enabled, err := s.Enabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return fmt.Errorf("cannot disable two-factor authentication that is not enabled")
+	}
+
+	passwordHash, err := s.userRepo.GetPasswordHash(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if passwordHash != "" && !checkPassword(passwordHash, req.Password) {
+		return fmt.Errorf("invalid password")
+	}
+
+	if err := s.Verify(ctx, userID, req.Code); err != nil {
+		return err
+	}
+
+	return s.twoFactorRepo.Disable(ctx, userID)
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления новыми. Нужен код из приложения
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, req *models.TwoFactorCodeRequest) (*models.TwoFactorRecoveryCodes, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.RegenerateRecoveryCodes")
+	defer span.End()
+
+	enabled, err := s.Enabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, fmt.Errorf("two-factor authentication is not enabled")
+	}
+
+	if err := s.verifyTOTP(ctx, userID, req.Code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// Verify проверяет второй фактор: шестизначный код из приложения или код
+// восстановления. Каждый код принимается один раз
+func (s *TwoFactorService) Verify(ctx context.Context, userID int, code string) error {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Verify")
+	defer span.End()
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return fmt.Errorf("code is required")
+	}
+
+	if len(strings.ReplaceAll(code, " ", "")) == totp.Digits {
+		return s.verifyTOTP(ctx, userID, code)
+	}
+	return s.verifyRecoveryCode(ctx, userID, code)
+}
+
+func (s *TwoFactorService) verifyTOTP(ctx context.Context, userID int, code string) error {
+	secret, enabled, err := s.twoFactorRepo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return fmt.Errorf("two-factor authentication is not enabled")
+	}
+
+	step, ok := totp.Validate(secret, code, s.now(), totpSkew)
+	if !ok {
+		return fmt.Errorf("invalid two-factor code")
+	}
+
+	// Код, который уже приняли, второй раз не подходит
+	fresh, err := s.twoFactorRepo.UseStep(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return fmt.Errorf("invalid two-factor code")
+	}
+
+	return nil
+}
+
+func (s *TwoFactorService) verifyRecoveryCode(ctx context.Context, userID int, code string) error {
+	code = normalizeRecoveryCode(code)
+
+	codes, err := s.twoFactorRepo.GetRecoveryCodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(c.Hash), []byte(code)) != nil {
+			continue
+		}
+		used, err := s.twoFactorRepo.UseRecoveryCode(ctx, c.ID)
+		if err != nil {
+			return err
+		}
+		if !used {
+			break
+		}
+		return nil
+	}
+
+	return fmt.Errorf("invalid two-factor code")
+}
+
+// newRecoveryCodes возвращает коды восстановления вида XXXXX-XXXXX и их bcrypt хеши.
+// Кодов мало, а энтропия каждого - 50 бит, поэтому хеш медленный, как у паролей
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		for j := range b {
+			b[j] = recoveryAlphabet[int(b[j])%len(recoveryAlphabet)]
+		}
+		code := string(b[:5]) + "-" + string(b[5:])
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(normalizeRecoveryCode(code)), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to hash recovery code: %w", err)
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, string(hash))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode убирает дефисы и пробелы и приводит код к верхнему регистру
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"balance/internal/database/dbtest"
+	"balance/internal/models"
+	"balance/internal/repository"
+	"balance/internal/totp"
+)
+
+func TestTwoFactorCodeIsAcceptedOnce(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+
+	user, err := repository.NewUserRepository(db).Create(ctx, &models.CreateUserRequest{Name: "TOTP", Email: dbtest.Email("totp")}, "")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	svc := NewTwoFactorService(repository.NewTwoFactorRepository(db), repository.NewUserRepository(db), "Balance")
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	enrollment, err := svc.Enroll(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Enroll: %v", err)
+	}
+	code := func(offset int) string {
+		c, err := totp.Code(enrollment.Secret, now.Add(time.Duration(offset)*totp.Period))
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		return c
+	}
+
+	if _, err := svc.Confirm(ctx, user.ID, &models.TwoFactorCodeRequest{Code: code(0)}); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+
+	// Код, которым включили 2FA, уже использован
+	if err := svc.Verify(ctx, user.ID, code(0)); err == nil {
+		t.Error("Verify accepted the code used to confirm enrollment")
+	}
+	// Код предыдущего шага попадает в skew, но он старше принятого
+	if err := svc.Verify(ctx, user.ID, code(-1)); err == nil {
+		t.Error("Verify accepted a code older than the last accepted one")
+	}
+
+	// Следующий шаг принимается один раз
+	if err := svc.Verify(ctx, user.ID, code(1)); err != nil {
+		t.Fatalf("Verify with the next code: %v", err)
+	}
+	if err := svc.Verify(ctx, user.ID, code(1)); err == nil {
+		t.Error("Verify accepted the same code twice")
+	}
+
+	// За пределами skew код не подходит
+	now = now.Add(5 * totp.Period)
+	if err := svc.Verify(ctx, user.ID, code(-5)); err == nil {
+		t.Error("Verify accepted a code outside the allowed skew")
+	}
+	if err := svc.Verify(ctx, user.ID, code(0)); err != nil {
+		t.Errorf("Verify with a current code: %v", err)
+	}
+}
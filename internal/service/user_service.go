@@ -3,22 +3,59 @@ package service
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
+	"balance/internal/logging"
 	"balance/internal/models"
+	"balance/internal/notification"
 	"balance/internal/repository"
+	"balance/internal/token"
+	"balance/internal/tracing"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
-	userRepo *repository.UserRepository
+	userRepo    *repository.UserRepository
+	tokenRepo   *repository.UserTokenRepository
+	authService *AuthService
+	mailer      notification.Mailer
+	publicURL   string
+	verifyTTL   time.Duration
+	resetTTL    time.Duration
+	now         func() time.Time
 }
 
-func NewUserService(userRepo *repository.UserRepository) *UserService {
+// NewUserService создает сервис пользователей. Письма для подтверждения email и
+// сброса пароля уходят через mailer, ссылки в них ведут на publicURL. Сессии после
+// входа по паролю выдает authService
+func NewUserService(
+	userRepo *repository.UserRepository,
+	tokenRepo *repository.UserTokenRepository,
+	authService *AuthService,
+	mailer notification.Mailer,
+	publicURL string,
+	verifyTTL, resetTTL time.Duration,
+) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		authService: authService,
+		mailer:      mailer,
+		publicURL:   publicURL,
+		verifyTTL:   verifyTTL,
+		resetTTL:    resetTTL,
+		now:         time.Now,
 	}
 }
 
 func (s *UserService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
 	// Валидация данных
 	if err := s.validateCreateUserRequest(req); err != nil {
 		return nil, err
@@ -30,16 +67,32 @@ func (s *UserService) CreateUser(ctx context.Context, req *models.CreateUserRequ
 		return nil, fmt.Errorf("user with this email already exists")
 	}
 
+	var passwordHash string
+	if req.Password != "" {
+		if passwordHash, err = hashPassword(req.Password); err != nil {
+			return nil, err
+		}
+	}
+
 	// Создаем пользователя
-	user, err := s.userRepo.Create(ctx, req)
+	user, err := s.userRepo.Create(ctx, req, passwordHash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	// Пользователь уже создан, поэтому ошибка отправки письма его не отменяет:
+	// ссылку можно запросить повторно
+	if err := s.sendVerification(ctx, user); err != nil {
+		logging.FromContext(ctx).Error("Failed to send verification email", "user_id", user.ID, "error", err)
+	}
+
 	return user, nil
 }
 
 func (s *UserService) GetUser(ctx context.Context, id int) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUser")
+	defer span.End()
+
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -49,6 +102,9 @@ func (s *UserService) GetUser(ctx context.Context, id int) (*models.User, error)
 }
 
 func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByEmail")
+	defer span.End()
+
 	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -57,8 +113,11 @@ func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models
 	return user, nil
 }
 
-func (s *UserService) GetAllUsers(ctx context.Context) ([]*models.User, error) {
-	users, err := s.userRepo.GetAll(ctx)
+func (s *UserService) GetAllUsers(ctx context.Context, page models.Page) ([]*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetAllUsers")
+	defer span.End()
+
+	users, err := s.userRepo.GetAll(ctx, page)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get users: %w", err)
 	}
@@ -67,6 +126,9 @@ func (s *UserService) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 }
 
 func (s *UserService) UpdateUser(ctx context.Context, id int, req *models.UpdateUserRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
+
 	// Проверяем, существует ли пользователь
 	existingUser, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
@@ -87,10 +149,183 @@ func (s *UserService) UpdateUser(ctx context.Context, id int, req *models.Update
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
+	// Новый адрес нужно подтвердить заново
+	if user.Email != existingUser.Email {
+		if err := s.sendVerification(ctx, user); err != nil {
+			logging.FromContext(ctx).Error("Failed to send verification email", "user_id", user.ID, "error", err)
+		}
+	}
+
 	return user, nil
 }
 
+// Login проверяет email и пароль и выдает сессию или, если включена двухфакторная
+// аутентификация, токен второго шага. Ошибка одна и та же для неизвестного email
+// и неверного пароля
+func (s *UserService) Login(ctx context.Context, req *models.LoginRequest) (*models.LoginResult, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Login")
+	defer span.End()
+
+	email := strings.TrimSpace(req.Email)
+	if email == "" || req.Password == "" {
+		return nil, fmt.Errorf("email and password are required")
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil && err.Error() != "user not found" {
+		return nil, err
+	}
+
+	var passwordHash string
+	if user != nil {
+		if passwordHash, err = s.userRepo.GetPasswordHash(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Хеш сравнивается и для неизвестного пользователя, чтобы по времени ответа
+	// нельзя было узнать, зарегистрирован ли email
+	if !checkPassword(passwordHash, req.Password) || user == nil {
+		return nil, fmt.Errorf("invalid email or password")
+	}
+
+	return s.authService.Login(ctx, user)
+}
+
+// SendVerification повторно отправляет пользователю письмо со ссылкой для подтверждения email
+func (s *UserService) SendVerification(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "UserService.SendVerification")
+	defer span.End()
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return fmt.Errorf("user has no email")
+	}
+	if user.EmailVerified {
+		return fmt.Errorf("cannot verify an email that is already verified")
+	}
+
+	return s.sendVerification(ctx, user)
+}
+
+// VerifyEmail подтверждает email по токену из письма
+func (s *UserService) VerifyEmail(ctx context.Context, req *models.TokenRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.VerifyEmail")
+	defer span.End()
+
+	if req.Token == "" {
+		return nil, fmt.Errorf("token is required")
+	}
+
+	return s.tokenRepo.VerifyEmail(ctx, token.Hash(req.Token))
+}
+
+// RequestPasswordReset отправляет письмо со ссылкой для смены пароля. Чтобы по
+// ответу нельзя было узнать, зарегистрирован ли адрес, для неизвестного email
+// ошибка не возвращается
+func (s *UserService) RequestPasswordReset(ctx context.Context, req *models.PasswordResetRequest) error {
+	ctx, span := tracing.Start(ctx, "UserService.RequestPasswordReset")
+	defer span.End()
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == "" {
+		return fmt.Errorf("email is required")
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil
+		}
+		return err
+	}
+
+	link, expiresAt, err := s.issueToken(ctx, user, models.TokenPurposeResetPassword, s.resetTTL, "/reset-password")
+	if err != nil {
+		return err
+	}
+
+	msg := notification.Message{
+		Subject: "Reset your Balance password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password for %s.\n\n"+
+				"Open the link to choose a new password:\n%s\n\n"+
+				"The link works once and expires on %s. If it wasn't you, ignore this email.",
+			user.Email, link, expiresAt.Format("2006-01-02 15:04 MST"),
+		),
+	}
+	// Ошибку отправки не возвращаем: по ней можно было бы понять, что адрес зарегистрирован
+	if err := s.mailer.Mail(ctx, user.Email, msg); err != nil {
+		logging.FromContext(ctx).Error("Failed to send password reset email", "user_id", user.ID, "error", err)
+	}
+
+	return nil
+}
+
+// ConfirmPasswordReset задает новый пароль по токену из письма
+func (s *UserService) ConfirmPasswordReset(ctx context.Context, req *models.PasswordResetConfirmRequest) error {
+	ctx, span := tracing.Start(ctx, "UserService.ConfirmPasswordReset")
+	defer span.End()
+
+	if req.Token == "" {
+		return fmt.Errorf("token is required")
+	}
+	if err := validatePassword(req.Password); err != nil {
+		return err
+	}
+
+	passwordHash, err := hashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	return s.tokenRepo.ResetPassword(ctx, token.Hash(req.Token), passwordHash)
+}
+
+// sendVerification выпускает токен подтверждения email и отправляет ссылку письмом
+func (s *UserService) sendVerification(ctx context.Context, user *models.User) error {
+	link, expiresAt, err := s.issueToken(ctx, user, models.TokenPurposeVerifyEmail, s.verifyTTL, "/verify-email")
+	if err != nil {
+		return err
+	}
+
+	msg := notification.Message{
+		Subject: "Confirm your email for Balance",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nOpen the link to confirm that %s is your address:\n%s\n\n"+
+				"The link expires on %s.",
+			user.Name, user.Email, link, expiresAt.Format("2006-01-02 15:04 MST"),
+		),
+	}
+	if err := s.mailer.Mail(ctx, user.Email, msg); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	return nil
+}
+
+// issueToken сохраняет новый одноразовый токен и возвращает ссылку с ним и срок ее действия
+func (s *UserService) issueToken(ctx context.Context, user *models.User, purpose string, ttl time.Duration, path string) (string, time.Time, error) {
+	secret, hash, err := token.New()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := s.now().UTC().Add(ttl)
+	if err := s.tokenRepo.Create(ctx, user.ID, purpose, user.Email, hash, expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return s.publicURL + path + "?token=" + url.QueryEscape(secret), expiresAt, nil
+}
+
 func (s *UserService) DeleteUser(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
 	// Проверяем, существует ли пользователь
 	_, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
@@ -125,9 +360,50 @@ func (s *UserService) validateCreateUserRequest(req *models.CreateUserRequest) e
 		return fmt.Errorf("invalid email format")
 	}
 
+	if req.Password != "" {
+		if err := validatePassword(req.Password); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// validatePassword проверяет длину пароля. bcrypt учитывает только первые 72 байта
+func validatePassword(password string) error {
+	if len(password) < 8 {
+		return fmt.Errorf("password must be at least 8 characters long")
+	}
+	if len(password) > 72 {
+		return fmt.Errorf("password must be no more than 72 bytes long")
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// dummyPasswordHash сравнивается с паролем, когда настоящего хеша нет
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// checkPassword сравнивает пароль с bcrypt хешем. Пустой хеш - пароль не задан,
+// такой пароль не подходит, но время проверки то же
+func checkPassword(passwordHash, password string) bool {
+	if passwordHash == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) == nil
+}
+
 func (s *UserService) isValidEmail(email string) bool {
 	// Простая проверка email - в реальном проекте лучше использовать библиотеку
 	if len(email) < 5 {
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"balance/internal/database/dbtest"
+	"balance/internal/models"
+	"balance/internal/notification"
+	"balance/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const publicURL = "https://balance.example.com"
+
+// recordingMailer запоминает письма вместо отправки
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []sentMail
+}
+
+type sentMail struct {
+	to  string
+	msg notification.Message
+}
+
+func (m *recordingMailer) Mail(ctx context.Context, to string, msg notification.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, sentMail{to: to, msg: msg})
+	return nil
+}
+
+var linkPattern = regexp.MustCompile(`https://\S+`)
+
+// lastToken возвращает токен из ссылки в последнем письме на адрес to
+func (m *recordingMailer) lastToken(t *testing.T, to, path string) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i].to != to {
+			continue
+		}
+		link, err := url.Parse(linkPattern.FindString(m.sent[i].msg.Body))
+		if err != nil || link.Path != path {
+			t.Fatalf("last email to %s has no %s link: %q", to, path, m.sent[i].msg.Body)
+		}
+		return link.Query().Get("token")
+	}
+	t.Fatalf("no email to %s", to)
+	return ""
+}
+
+func newUserService(t *testing.T, db *pgxpool.Pool) (*UserService, *recordingMailer) {
+	t.Helper()
+	mailer := &recordingMailer{}
+	svc := NewUserService(repository.NewUserRepository(db), repository.NewUserTokenRepository(db), nil,
+		mailer, publicURL, 24*time.Hour, time.Hour)
+	return svc, mailer
+}
+
+func TestVerifyEmail(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	svc, mailer := newUserService(t, db)
+
+	user, err := svc.CreateUser(ctx, &models.CreateUserRequest{Name: "Ivan", Email: dbtest.Email("verify")})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if user.EmailVerified {
+		t.Fatal("a new user has a verified email")
+	}
+	token := mailer.lastToken(t, user.Email, "/verify-email")
+
+	verified, err := svc.VerifyEmail(ctx, &models.TokenRequest{Token: token})
+	if err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	if !verified.EmailVerified {
+		t.Error("VerifyEmail did not verify the email")
+	}
+
+	if _, err := svc.VerifyEmail(ctx, &models.TokenRequest{Token: token}); err == nil || !strings.Contains(err.Error(), "already been used") {
+		t.Errorf("VerifyEmail with a used token: got %v, want an error", err)
+	}
+	if _, err := svc.VerifyEmail(ctx, &models.TokenRequest{Token: "unknown"}); err == nil || !strings.HasSuffix(err.Error(), "not found") {
+		t.Errorf("VerifyEmail with an unknown token: got %v, want not found", err)
+	}
+	if err := svc.SendVerification(ctx, user.ID); err == nil || !strings.HasPrefix(err.Error(), "cannot ") {
+		t.Errorf("SendVerification for a verified email: got %v, want a conflict", err)
+	}
+}
+
+func TestVerifyEmailTokenExpires(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	svc, mailer := newUserService(t, db)
+
+	// Токен выпущен двое суток назад и действует сутки
+	svc.now = func() time.Time { return time.Now().Add(-48 * time.Hour) }
+	user, err := svc.CreateUser(ctx, &models.CreateUserRequest{Name: "Ivan", Email: dbtest.Email("expired")})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	svc.now = time.Now
+
+	token := mailer.lastToken(t, user.Email, "/verify-email")
+	if _, err := svc.VerifyEmail(ctx, &models.TokenRequest{Token: token}); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("VerifyEmail with an expired token: got %v, want an expiry error", err)
+	}
+
+	// Новая ссылка действует
+	if err := svc.SendVerification(ctx, user.ID); err != nil {
+		t.Fatalf("SendVerification: %v", err)
+	}
+	if _, err := svc.VerifyEmail(ctx, &models.TokenRequest{Token: mailer.lastToken(t, user.Email, "/verify-email")}); err != nil {
+		t.Errorf("VerifyEmail with a new token: %v", err)
+	}
+}
+
+func TestEmailChangeInvalidatesVerification(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	svc, mailer := newUserService(t, db)
+
+	user, err := svc.CreateUser(ctx, &models.CreateUserRequest{Name: "Ivan", Email: dbtest.Email("old")})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	oldToken := mailer.lastToken(t, user.Email, "/verify-email")
+
+	newEmail := dbtest.Email("new")
+	if _, err := svc.UpdateUser(ctx, user.ID, &models.UpdateUserRequest{Name: user.Name, Email: newEmail}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+
+	// Ссылка на старый адрес больше не подтверждает аккаунт
+	if _, err := svc.VerifyEmail(ctx, &models.TokenRequest{Token: oldToken}); err == nil {
+		t.Error("VerifyEmail accepted a token sent to the previous email")
+	}
+
+	verified, err := svc.VerifyEmail(ctx, &models.TokenRequest{Token: mailer.lastToken(t, newEmail, "/verify-email")})
+	if err != nil {
+		t.Fatalf("VerifyEmail with the new token: %v", err)
+	}
+	if verified.Email != newEmail || !verified.EmailVerified {
+		t.Errorf("VerifyEmail returned %+v, want verified %s", verified, newEmail)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	svc, mailer := newUserService(t, db)
+	userRepo := repository.NewUserRepository(db)
+
+	user, err := svc.CreateUser(ctx, &models.CreateUserRequest{Name: "Ivan", Email: dbtest.Email("reset"), Password: "old password 1"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	// Для неизвестного адреса ответ тот же, но письмо не уходит
+	before := len(mailer.sent)
+	if err := svc.RequestPasswordReset(ctx, &models.PasswordResetRequest{Email: dbtest.Email("nobody")}); err != nil {
+		t.Fatalf("RequestPasswordReset for an unknown email: %v", err)
+	}
+	if len(mailer.sent) != before {
+		t.Error("a reset email was sent to an unknown address")
+	}
+
+	if err := svc.RequestPasswordReset(ctx, &models.PasswordResetRequest{Email: strings.ToUpper(user.Email)}); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	first := mailer.lastToken(t, user.Email, "/reset-password")
+
+	// Действует только последняя ссылка
+	if err := svc.RequestPasswordReset(ctx, &models.PasswordResetRequest{Email: user.Email}); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	token := mailer.lastToken(t, user.Email, "/reset-password")
+	if err := svc.ConfirmPasswordReset(ctx, &models.PasswordResetConfirmRequest{Token: first, Password: "new password 1"}); err == nil {
+		t.Error("ConfirmPasswordReset accepted a replaced token")
+	}
+
+	if err := svc.ConfirmPasswordReset(ctx, &models.PasswordResetConfirmRequest{Token: token, Password: "new password 1"}); err != nil {
+		t.Fatalf("ConfirmPasswordReset: %v", err)
+	}
+	hash, err := userRepo.GetPasswordHash(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetPasswordHash: %v", err)
+	}
+	if !checkPassword(hash, "new password 1") {
+		t.Error("the password was not changed")
+	}
+
+	if err := svc.ConfirmPasswordReset(ctx, &models.PasswordResetConfirmRequest{Token: token, Password: "other password 2"}); err == nil || !strings.Contains(err.Error(), "already been used") {
+		t.Errorf("ConfirmPasswordReset with a used token: got %v, want an error", err)
+	}
+
+	// Письмо дошло, значит адрес подтвержден
+	reset, err := userRepo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if !reset.EmailVerified {
+		t.Error("a password reset did not verify the email")
+	}
+}
+
+func TestPasswordResetTokenExpires(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	svc, mailer := newUserService(t, db)
+
+	user, err := svc.CreateUser(ctx, &models.CreateUserRequest{Name: "Ivan", Email: dbtest.Email("reset-expired"), Password: "old password 1"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	svc.now = func() time.Time { return time.Now().Add(-2 * time.Hour) }
+	if err := svc.RequestPasswordReset(ctx, &models.PasswordResetRequest{Email: user.Email}); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+
+	token := mailer.lastToken(t, user.Email, "/reset-password")
+	err = svc.ConfirmPasswordReset(ctx, &models.PasswordResetConfirmRequest{Token: token, Password: "new password 1"})
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("ConfirmPasswordReset with an expired token: got %v, want an expiry error", err)
+	}
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"balance/internal/logging"
+	"balance/internal/models"
+	"balance/internal/repository"
+	"balance/internal/tracing"
+	"balance/internal/webhook"
+)
+
+const (
+	// webhookBatchSize - сколько доставок отправляется за один запуск
+	webhookBatchSize = 50
+	// webhookLease - на сколько доставка резервируется за отправителем
+	webhookLease = 2 * time.Minute
+	// webhookDeliveriesLimit - сколько последних доставок показывает журнал
+	webhookDeliveriesLimit = 100
+)
+
+type WebhookService struct {
+	webhookRepo *repository.WebhookRepository
+	groupRepo   *repository.GroupRepository
+	sender      *webhook.Sender
+	maxAttempts int
+}
+
+func NewWebhookService(
+	webhookRepo *repository.WebhookRepository,
+	groupRepo *repository.GroupRepository,
+	sender *webhook.Sender,
+	maxAttempts int,
+) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		groupRepo:   groupRepo,
+		sender:      sender,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// CreateWebhook создает подписку и возвращает ее вместе с секретом подписи.
+// Секрет показывается только в этом ответе
+func (s *WebhookService) CreateWebhook(ctx context.Context, req *models.CreateWebhookRequest) (*models.Webhook, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.CreateWebhook")
+	defer span.End()
+
+	if err := s.validateCreateWebhookRequest(req); err != nil {
+		return nil, err
+	}
+
+	// Подписаться на события группы может ее участник, на события пользователя - только он сам
+	if req.GroupID != nil {
+		isMember, err := s.groupRepo.IsMember(ctx, *req.GroupID, req.CreatedBy)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return nil, fmt.Errorf("only the group members can subscribe to its events")
+		}
+	} else if *req.UserID != req.CreatedBy {
+		return nil, fmt.Errorf("only the user can subscribe to their own events")
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.webhookRepo.Create(ctx, req, secret)
+}
+
+// GetWebhook возвращает подписку без секрета. Увидеть ее может тот, кто может ею управлять
+func (s *WebhookService) GetWebhook(ctx context.Context, id, actorID int) (*models.Webhook, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetWebhook")
+	defer span.End()
+
+	if err := s.checkAccess(ctx, id, actorID); err != nil {
+		return nil, err
+	}
+
+	webhook, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+
+	return webhook, nil
+}
+
+// GetWebhooks возвращает подписки группы groupID, если actorID в ней состоит,
+// или подписки на события самого actorID. Без фильтров - подписки actorID
+func (s *WebhookService) GetWebhooks(ctx context.Context, groupID, userID, actorID int) ([]*models.Webhook, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetWebhooks")
+	defer span.End()
+
+	if userID != 0 && userID != actorID {
+		return nil, fmt.Errorf("only the user can see their own webhooks")
+	}
+	if groupID != 0 {
+		isMember, err := s.groupRepo.IsMember(ctx, groupID, actorID)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return nil, fmt.Errorf("only the group members can see its webhooks")
+		}
+	} else {
+		userID = actorID
+	}
+
+	webhooks, err := s.webhookRepo.GetAll(ctx, groupID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	for _, w := range webhooks {
+		w.Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook удаляет подписку. Удалить ее может тот, кто мог бы ее создать
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id, actorID int) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeleteWebhook")
+	defer span.End()
+
+	if err := s.checkAccess(ctx, id, actorID); err != nil {
+		return err
+	}
+
+	return s.webhookRepo.Delete(ctx, id)
+}
+
+// GetDeliveries возвращает журнал доставок. В нем тела событий и ответы получателя,
+// поэтому доступ такой же, как к управлению подпиской
+func (s *WebhookService) GetDeliveries(ctx context.Context, webhookID, actorID int) ([]*models.WebhookDelivery, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetDeliveries")
+	defer span.End()
+
+	if err := s.checkAccess(ctx, webhookID, actorID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.webhookRepo.GetDeliveries(ctx, webhookID, webhookDeliveriesLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver повторно отправляет событие доставки. В журнале появляется новая запись
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID, deliveryID, actorID int) (*models.WebhookDelivery, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.Redeliver")
+	defer span.End()
+
+	if err := s.checkAccess(ctx, webhookID, actorID); err != nil {
+		return nil, err
+	}
+
+	return s.webhookRepo.Redeliver(ctx, webhookID, deliveryID)
+}
+
+// checkAccess проверяет, что пользователь actorID может управлять подпиской: он ее
+// создал, состоит в ее группе или подписан на свои события
+func (s *WebhookService) checkAccess(ctx context.Context, webhookID, actorID int) error {
+	webhook, err := s.webhookRepo.GetByID(ctx, webhookID)
+	if err != nil {
+		return err
+	}
+	if webhook.CreatedBy == actorID {
+		return nil
+	}
+
+	if webhook.GroupID != nil {
+		isMember, err := s.groupRepo.IsMember(ctx, *webhook.GroupID, actorID)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			return fmt.Errorf("only the group members can manage its webhooks")
+		}
+		return nil
+	}
+	if webhook.UserID == nil || *webhook.UserID != actorID {
+		return fmt.Errorf("only the user can manage their own webhooks")
+	}
+	return nil
+}
+
+// Deliver отправляет доставки, которым пришло время. Вызывается планировщиком периодически
+func (s *WebhookService) Deliver(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.Deliver")
+	defer span.End()
+
+	deliveries, err := s.webhookRepo.ClaimDue(ctx, webhookBatchSize, webhookLease)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deliveries {
+		s.deliver(ctx, d)
+	}
+
+	return nil
+}
+
+func (s *WebhookService) deliver(ctx context.Context, d *models.WebhookDelivery) {
+	body, err := json.Marshal(models.WebhookPayload{
+		DeliveryID: d.ID,
+		EventID:    d.EventID,
+		Type:       d.EventType,
+		CreatedAt:  d.Event.CreatedAt,
+		Data:       d.Event.Payload,
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to encode webhook delivery", "delivery_id", d.ID, "error", err)
+		return
+	}
+
+	result, sendErr := s.sender.Send(ctx, webhook.Request{
+		URL:        d.Webhook.URL,
+		Secret:     d.Webhook.Secret,
+		Event:      d.EventType,
+		DeliveryID: d.ID,
+		Body:       body,
+	})
+
+	d.ResponseStatus, d.ResponseBody, d.DurationMs = nil, "", nil
+	if result != nil {
+		durationMs := int(result.Duration.Milliseconds())
+		d.ResponseStatus = &result.StatusCode
+		d.ResponseBody = result.Body
+		d.DurationMs = &durationMs
+	}
+
+	var retryIn time.Duration
+	switch {
+	case sendErr == nil:
+		d.Status = models.DeliveryStatusDelivered
+		d.LastError = ""
+	case d.Attempts < s.maxAttempts:
+		d.Status = models.DeliveryStatusPending
+		d.LastError = sendErr.Error()
+		retryIn = retryBackoff(d.Attempts)
+	default:
+		d.Status = models.DeliveryStatusFailed
+		d.LastError = sendErr.Error()
+	}
+
+	if sendErr != nil {
+		logging.FromContext(ctx).Warn("Webhook delivery failed", "delivery_id", d.ID, "url", d.Webhook.URL, "attempt", d.Attempts, "error", sendErr)
+	}
+
+	if err := s.webhookRepo.SaveAttempt(ctx, d, retryIn); err != nil {
+		logging.FromContext(ctx).Error("Failed to update webhook delivery", "delivery_id", d.ID, "error", err)
+	}
+}
+
+func (s *WebhookService) validateCreateWebhookRequest(req *models.CreateWebhookRequest) error {
+	if (req.GroupID == nil) == (req.UserID == nil) {
+		return fmt.Errorf("exactly one of group_id and user_id is required")
+	}
+	if req.CreatedBy == 0 {
+		return fmt.Errorf("created_by is required")
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	// Адреса, которые указаны явно, проверяем сразу. Имена проверяет отправитель
+	// при подключении, после разрешения имени
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("url must not point to a private address")
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !webhook.IsPublic(ip) {
+		return fmt.Errorf("url must not point to a private address")
+	}
+
+	if len(req.EventTypes) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+	for _, eventType := range req.EventTypes {
+		if !isValidEventType(eventType) {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+
+	return nil
+}
+
+// generateSecret возвращает случайный секрет для подписи вебхуков
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"balance/internal/database/dbtest"
+	"balance/internal/models"
+	"balance/internal/repository"
+	"balance/internal/webhook"
+)
+
+// hookHost - имя получателя в адресе подписки. Тестовый клиент соединяет его с
+// httptest сервером: адреса 127.0.0.1 подписка не принимает
+const hookHost = "hooks.example.test"
+
+func receiverClient(receiver *httptest.Server) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, _, _ := net.SplitHostPort(addr); host != hookHost {
+			return nil, errors.New("unexpected webhook host " + host)
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, receiver.Listener.Addr().String())
+	}
+	return &http.Client{Transport: transport}
+}
+
+func TestWebhookDeliveryRetriesAndLogs(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+
+	user, err := userRepo.Create(ctx, &models.CreateUserRequest{Name: "Webhook", Email: dbtest.Email("webhook")}, "")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	// Первая попытка получает 500, вторая - 200
+	var mu sync.Mutex
+	var secret string
+	var calls int
+	var signatureErrors []error
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err := webhook.Verify(secret, r.Header, body, time.Minute); err != nil {
+			signatureErrors = append(signatureErrors, err)
+		}
+		calls++
+		if calls == 1 {
+			http.Error(w, "try later", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("accepted"))
+	}))
+	defer receiver.Close()
+
+	svc := NewWebhookService(webhookRepo, repository.NewGroupRepository(db),
+		webhook.NewSender(receiverClient(receiver)), 5)
+
+	hook, err := svc.CreateWebhook(ctx, &models.CreateWebhookRequest{
+		UserID:     &user.ID,
+		URL:        "http://" + hookHost + "/balance",
+		EventTypes: []string{models.EventDebtReminder},
+		CreatedBy:  user.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	mu.Lock()
+	secret = hook.Secret
+	mu.Unlock()
+
+	err = outboxRepo.Enqueue(ctx, models.EventDebtReminder, map[string]int{"debt_id": 1},
+		models.EventScope{UserIDs: []int{user.ID}})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	delivery := func() *models.WebhookDelivery {
+		t.Helper()
+		deliveries, err := svc.GetDeliveries(ctx, hook.ID, user.ID)
+		if err != nil {
+			t.Fatalf("GetDeliveries: %v", err)
+		}
+		if len(deliveries) != 1 {
+			t.Fatalf("got %d deliveries, want 1", len(deliveries))
+		}
+		return deliveries[0]
+	}
+
+	if err := svc.Deliver(ctx); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	d := delivery()
+	if d.Status != models.DeliveryStatusPending || d.Attempts != 1 {
+		t.Fatalf("after a failed attempt: status %s, attempts %d, want pending, 1", d.Status, d.Attempts)
+	}
+	if d.ResponseStatus == nil || *d.ResponseStatus != http.StatusInternalServerError {
+		t.Errorf("response status = %v, want 500", d.ResponseStatus)
+	}
+	if !strings.Contains(d.LastError, "500") {
+		t.Errorf("last error = %q, want the status", d.LastError)
+	}
+	if !d.NextAttemptAt.After(time.Now()) {
+		t.Errorf("next attempt at %v is not in the future", d.NextAttemptAt)
+	}
+
+	// Пауза перед повтором уже проверена, повторяем сразу
+	if _, err := db.Exec(ctx, `UPDATE webhook_deliveries SET next_attempt_at = CURRENT_TIMESTAMP WHERE id = $1`, d.ID); err != nil {
+		t.Fatalf("failed to reschedule delivery: %v", err)
+	}
+	if err := svc.Deliver(ctx); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	d = delivery()
+	if d.Status != models.DeliveryStatusDelivered || d.Attempts != 2 {
+		t.Fatalf("after a retry: status %s, attempts %d, want delivered, 2", d.Status, d.Attempts)
+	}
+	if d.ResponseStatus == nil || *d.ResponseStatus != http.StatusOK || d.ResponseBody != "accepted" {
+		t.Errorf("response = %v %q, want 200 \"accepted\"", d.ResponseStatus, d.ResponseBody)
+	}
+	if d.LastError != "" || d.DeliveredAt == nil || d.DurationMs == nil {
+		t.Errorf("delivered row: last error %q, delivered at %v, duration %v", d.LastError, d.DeliveredAt, d.DurationMs)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Errorf("receiver got %d requests, want 2", calls)
+	}
+	for _, err := range signatureErrors {
+		t.Errorf("invalid signature: %v", err)
+	}
+}
+
+func TestWebhookAccess(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(db)
+	owner, err := userRepo.Create(ctx, &models.CreateUserRequest{Name: "Owner", Email: dbtest.Email("owner")}, "")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	other, err := userRepo.Create(ctx, &models.CreateUserRequest{Name: "Other", Email: dbtest.Email("other")}, "")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	svc := NewWebhookService(repository.NewWebhookRepository(db), repository.NewGroupRepository(db), webhook.NewSender(nil), 5)
+	hook, err := svc.CreateWebhook(ctx, &models.CreateWebhookRequest{
+		UserID: &owner.ID, URL: "https://" + hookHost + "/balance",
+		EventTypes: []string{models.EventDebtCreated}, CreatedBy: owner.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+
+	if _, err := svc.GetDeliveries(ctx, hook.ID, other.ID); err == nil || !strings.HasPrefix(err.Error(), "only the ") {
+		t.Errorf("GetDeliveries by another user: got %v, want a permission error", err)
+	}
+	if err := svc.DeleteWebhook(ctx, hook.ID, other.ID); err == nil || !strings.HasPrefix(err.Error(), "only the ") {
+		t.Errorf("DeleteWebhook by another user: got %v, want a permission error", err)
+	}
+	if err := svc.DeleteWebhook(ctx, hook.ID, owner.ID); err != nil {
+		t.Errorf("DeleteWebhook by the owner: %v", err)
+	}
+}
+
+func TestWebhookURLValidation(t *testing.T) {
+	svc := &WebhookService{}
+	userID := 1
+
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://hooks.example.com/balance", true},
+		{"http://203.0.113.10:8080/hook", true},
+		{"ftp://hooks.example.com/", false},
+		{"http://127.0.0.1:8080/", false},
+		{"http://localhost/", false},
+		{"http://api.localhost/", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://10.0.0.5/", false},
+		{"http://[::1]/", false},
+	}
+	for _, tt := range tests {
+		err := svc.validateCreateWebhookRequest(&models.CreateWebhookRequest{
+			UserID: &userID, URL: tt.url, EventTypes: []string{models.EventDebtCreated}, CreatedBy: userID,
+		})
+		if (err == nil) != tt.ok {
+			t.Errorf("validate %s: got %v, want ok=%v", tt.url, err, tt.ok)
+		}
+	}
+}
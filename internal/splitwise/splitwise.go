@@ -0,0 +1,234 @@
+// Package splitwise разбирает CSV-экспорт группы из Splitwise.
+//
+// Файл начинается с колонок Date, Description, Category, Cost, Currency, за которыми
+// идет по колонке на каждого участника. В колонке участника - его чистый результат
+// по строке: положительное число - он заплатил больше своей доли, отрицательное -
+// он должен. Сумма по строке равна нулю. Последняя строка "Total balance" содержит
+// итоговые балансы участников
+package splitwise
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"balance/internal/models"
+)
+
+// CategoryPayment - категория строк, которыми в Splitwise записываются выплаты между участниками
+const CategoryPayment = "Payment"
+
+// totalBalance - описание итоговой строки
+const totalBalance = "total balance"
+
+// fixedColumns - колонки перед колонками участников
+var fixedColumns = []string{"date", "description", "category", "cost", "currency"}
+
+// Export - разобранный файл. Суммы хранятся в центах
+type Export struct {
+	Participants []string
+	Rows         []*Row
+	Totals       []int64 // Итоговые балансы из строки "Total balance", nil если ее нет
+}
+
+// Row - одна строка расхода или выплаты
+type Row struct {
+	Line        int
+	Date        time.Time
+	Description string
+	Category    string
+	Cost        int64
+	Currency    string
+	Shares      []int64 // Результат строки для каждого участника, в порядке Export.Participants
+}
+
+// IsPayment сообщает, что строка - выплата, а не расход
+func (r *Row) IsPayment() bool {
+	return strings.EqualFold(r.Category, CategoryPayment)
+}
+
+// Transfer - долг участника From участнику To, индексы из Export.Participants
+type Transfer struct {
+	From   int
+	To     int
+	Amount int64
+}
+
+// Read разбирает файл. Ошибки в отдельных строках возвращаются списком rowErrors,
+// такие строки в Export.Rows не попадают. err возвращается, если файл нельзя
+// прочитать или его заголовок не похож на экспорт Splitwise
+func Read(r io.Reader) (export *Export, rowErrors []models.ImportRowError, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, fmt.Errorf("file is empty")
+		}
+		return nil, nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+
+	if len(header) <= len(fixedColumns) {
+		return nil, nil, fmt.Errorf("file has no participant columns")
+	}
+	for i, name := range fixedColumns {
+		if !strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff")), name) {
+			return nil, nil, fmt.Errorf("not a Splitwise export: column %d must be %s", i+1, name)
+		}
+	}
+
+	export = &Export{}
+	seen := make(map[string]bool)
+	for _, name := range header[len(fixedColumns):] {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, nil, fmt.Errorf("participant name is empty")
+		}
+		if seen[name] {
+			return nil, nil, fmt.Errorf("participant %s appears twice", name)
+		}
+		seen[name] = true
+		export.Participants = append(export.Participants, name)
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(record) != len(header) {
+			rowErrors = append(rowErrors, models.ImportRowError{
+				Row:   line,
+				Error: fmt.Sprintf("expected %d columns, got %d", len(header), len(record)),
+			})
+			continue
+		}
+
+		if strings.EqualFold(strings.TrimSpace(record[1]), totalBalance) {
+			totals, err := parseShares(record[len(fixedColumns):])
+			if err != nil {
+				rowErrors = append(rowErrors, models.ImportRowError{Row: line, Error: err.Error()})
+				continue
+			}
+			export.Totals = totals
+			continue
+		}
+
+		row, err := parseRow(line, record)
+		if err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: line, Error: err.Error()})
+			continue
+		}
+		export.Rows = append(export.Rows, row)
+	}
+
+	return export, rowErrors, nil
+}
+
+func parseRow(line int, record []string) (*Row, error) {
+	row := &Row{
+		Line:        line,
+		Description: strings.TrimSpace(record[1]),
+		Category:    strings.TrimSpace(record[2]),
+		Currency:    strings.ToUpper(strings.TrimSpace(record[4])),
+	}
+
+	var err error
+	if row.Date, err = time.Parse("2006-01-02", strings.TrimSpace(record[0])); err != nil {
+		return nil, fmt.Errorf("invalid date %q", record[0])
+	}
+	if row.Cost, err = ParseCents(record[3]); err != nil {
+		return nil, fmt.Errorf("invalid cost %q", record[3])
+	}
+	if row.Shares, err = parseShares(record[len(fixedColumns):]); err != nil {
+		return nil, err
+	}
+
+	var sum int64
+	for _, share := range row.Shares {
+		sum += share
+	}
+	if sum != 0 {
+		return nil, fmt.Errorf("participant shares add up to %s instead of 0", FormatCents(sum))
+	}
+
+	return row, nil
+}
+
+func parseShares(values []string) ([]int64, error) {
+	shares := make([]int64, len(values))
+	for i, value := range values {
+		share, err := ParseCents(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount %q", value)
+		}
+		shares[i] = share
+	}
+	return shares, nil
+}
+
+// Transfers превращает результаты участников по строке в долги: каждый, у кого
+// результат отрицательный, должен тем, у кого он положительный. Участники
+// сопоставляются по порядку колонок, поэтому расход с одним плательщиком дает
+// ровно по одному долгу на каждого должника
+func Transfers(shares []int64) []Transfer {
+	var creditors, debtors []int
+	remaining := make([]int64, len(shares))
+	for i, share := range shares {
+		switch {
+		case share > 0:
+			creditors = append(creditors, i)
+			remaining[i] = share
+		case share < 0:
+			debtors = append(debtors, i)
+			remaining[i] = -share
+		}
+	}
+
+	var transfers []Transfer
+	for c, d := 0, 0; c < len(creditors) && d < len(debtors); {
+		to, from := creditors[c], debtors[d]
+		amount := min(remaining[to], remaining[from])
+		transfers = append(transfers, Transfer{From: from, To: to, Amount: amount})
+
+		remaining[to] -= amount
+		remaining[from] -= amount
+		if remaining[to] == 0 {
+			c++
+		}
+		if remaining[from] == 0 {
+			d++
+		}
+	}
+
+	return transfers
+}
+
+// ParseCents разбирает денежную сумму в центы. Пустая строка - ноль
+func ParseCents(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid amount")
+	}
+	return int64(math.Round(f * 100)), nil
+}
+
+// FormatCents форматирует сумму в центах как "12.34"
+func FormatCents(cents int64) string {
+	return strconv.FormatFloat(float64(cents)/100, 'f', 2, 64)
+}
@@ -0,0 +1,124 @@
+// Package statement отрисовывает выписку по группе (models.Statement) в HTML и PDF
+package statement
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"balance/internal/models"
+)
+
+var funcs = template.FuncMap{
+	"money":  money,
+	"date":   formatDate,
+	"period": period,
+	"name":   userName,
+}
+
+var htmlTemplate = template.Must(template.New("statement").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Statement: {{.Group.Name}}</title>
+<style>
+	body { font-family: Helvetica, Arial, sans-serif; color: #222; margin: 2em auto; max-width: 960px; }
+	h1 { margin-bottom: 0; }
+	.muted { color: #777; }
+	table { border-collapse: collapse; width: 100%; margin: 0.5em 0 1.5em; }
+	th, td { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; }
+	th { background: #f2f2f2; }
+	td.num, th.num { text-align: right; white-space: nowrap; }
+	.negative { color: #b00; }
+	@media print { body { margin: 0; max-width: none; } }
+</style>
+</head>
+<body>
+<h1>{{.Group.Name}}</h1>
+<p class="muted">Statement for {{period .}}. Generated {{date .GeneratedAt}}.</p>
+
+<h2>Summary</h2>
+<table>
+	<tr><th>Members</th><th class="num">Outstanding</th><th class="num">Active debts</th><th class="num">Settled debts</th></tr>
+	<tr>
+		<td>{{.Summary.MemberCount}}</td>
+		<td class="num">{{money .Summary.TotalDebts}}</td>
+		<td class="num">{{.Summary.ActiveDebts}}</td>
+		<td class="num">{{.Summary.SettledDebts}}</td>
+	</tr>
+</table>
+
+<h2>Balances</h2>
+<table>
+	<tr><th>Member</th><th class="num">Owes</th><th class="num">Is owed</th><th class="num">Net</th></tr>
+	{{range .Members}}
+	<tr>
+		<td>{{.UserName}}</td>
+		<td class="num">{{money .TotalOwed}}</td>
+		<td class="num">{{money .TotalOwedTo}}</td>
+		<td class="num{{if lt .NetBalance 0.0}} negative{{end}}">{{money .NetBalance}}</td>
+	</tr>
+	{{end}}
+</table>
+
+<h2>Settle plan</h2>
+{{if .SettlePlan}}
+<table>
+	<tr><th>From</th><th>To</th><th class="num">Amount</th></tr>
+	{{range .SettlePlan}}
+	<tr><td>{{.FromUserName}}</td><td>{{.ToUserName}}</td><td class="num">{{money .Amount}}</td></tr>
+	{{end}}
+</table>
+{{else}}
+<p class="muted">Everyone is settled up.</p>
+{{end}}
+
+<h2>Transactions</h2>
+{{if .Transactions}}
+<table>
+	<tr><th>Date</th><th>Description</th><th>From</th><th>To</th><th>Status</th><th class="num">Amount</th></tr>
+	{{range .Transactions}}
+	<tr>
+		<td>{{date .CreatedAt}}</td>
+		<td>{{.Description}}</td>
+		<td>{{name .FromUser .FromUserID}}</td>
+		<td>{{name .ToUser .ToUserID}}</td>
+		<td>{{.Status}}</td>
+		<td class="num">{{money .Amount}}</td>
+	</tr>
+	{{end}}
+</table>
+{{else}}
+<p class="muted">No transactions in this period.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// RenderHTML пишет выписку в w как HTML-страницу для печати
+func RenderHTML(w io.Writer, s *models.Statement) error {
+	return htmlTemplate.Execute(w, s)
+}
+
+func money(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// period описывает период выписки. To хранится как исключающая граница,
+// поэтому показывается предыдущий день
+func period(s *models.Statement) string {
+	switch {
+	case s.From != nil && s.To != nil:
+		return formatDate(*s.From) + " – " + formatDate(s.To.Add(-time.Nanosecond))
+	case s.From != nil:
+		return "since " + formatDate(*s.From)
+	case s.To != nil:
+		return "until " + formatDate(s.To.Add(-time.Nanosecond))
+	}
+	return "all time"
+}
@@ -0,0 +1,196 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+
+	"balance/internal/models"
+	"balance/internal/pdf"
+)
+
+const (
+	margin     = 40.0
+	bodySize   = 9.0
+	headSize   = 12.0
+	titleSize  = 18.0
+	lineHeight = 14.0
+)
+
+// column - колонка таблицы: ширина в пунктах и выравнивание
+type column struct {
+	title string
+	width float64
+	right bool
+}
+
+// layout ведет курсор сверху вниз и переносит таблицы на новые страницы
+type layout struct {
+	doc *pdf.Document
+	y   float64
+}
+
+// RenderPDF пишет выписку в w как PDF формата A4
+func RenderPDF(w io.Writer, s *models.Statement) error {
+	l := &layout{doc: pdf.New("Statement: " + s.Group.Name)}
+	l.newPage()
+
+	l.text(margin, pdf.HelveticaBold, titleSize, s.Group.Name)
+	l.y -= 8
+	l.text(margin, pdf.Helvetica, bodySize,
+		fmt.Sprintf("Statement for %s. Generated %s.", period(s), formatDate(s.GeneratedAt)))
+
+	l.heading("Summary")
+	l.table([]column{
+		{title: "Members", width: 130},
+		{title: "Outstanding", width: 130, right: true},
+		{title: "Active debts", width: 127, right: true},
+		{title: "Settled debts", width: 128, right: true},
+	}, [][]string{{
+		fmt.Sprint(s.Summary.MemberCount),
+		money(s.Summary.TotalDebts),
+		fmt.Sprint(s.Summary.ActiveDebts),
+		fmt.Sprint(s.Summary.SettledDebts),
+	}})
+
+	l.heading("Balances")
+	var balances [][]string
+	for _, m := range s.Members {
+		balances = append(balances, []string{m.UserName, money(m.TotalOwed), money(m.TotalOwedTo), money(m.NetBalance)})
+	}
+	l.table([]column{
+		{title: "Member", width: 215},
+		{title: "Owes", width: 100, right: true},
+		{title: "Is owed", width: 100, right: true},
+		{title: "Net", width: 100, right: true},
+	}, balances)
+
+	l.heading("Settle plan")
+	if len(s.SettlePlan) == 0 {
+		l.text(margin, pdf.Helvetica, bodySize, "Everyone is settled up.")
+	} else {
+		var plan [][]string
+		for _, p := range s.SettlePlan {
+			plan = append(plan, []string{p.FromUserName, p.ToUserName, money(p.Amount)})
+		}
+		l.table([]column{
+			{title: "From", width: 207},
+			{title: "To", width: 208},
+			{title: "Amount", width: 100, right: true},
+		}, plan)
+	}
+
+	l.heading("Transactions")
+	if len(s.Transactions) == 0 {
+		l.text(margin, pdf.Helvetica, bodySize, "No transactions in this period.")
+	} else {
+		var transactions [][]string
+		for _, d := range s.Transactions {
+			transactions = append(transactions, []string{
+				formatDate(d.CreatedAt),
+				d.Description,
+				userName(d.FromUser, d.FromUserID),
+				userName(d.ToUser, d.ToUserID),
+				d.Status,
+				money(d.Amount),
+			})
+		}
+		l.table([]column{
+			{title: "Date", width: 60},
+			{title: "Description", width: 155},
+			{title: "From", width: 90},
+			{title: "To", width: 90},
+			{title: "Status", width: 55},
+			{title: "Amount", width: 65, right: true},
+		}, transactions)
+	}
+
+	_, err := l.doc.WriteTo(w)
+	return err
+}
+
+func (l *layout) newPage() {
+	l.doc.AddPage()
+	l.y = pdf.PageHeight - margin
+	if n := l.doc.PageCount(); n > 1 {
+		footer := fmt.Sprintf("Page %d", n)
+		l.doc.Text(pdf.PageWidth-margin-pdf.TextWidth(pdf.Helvetica, 8, footer), margin/2, pdf.Helvetica, 8, footer)
+	}
+}
+
+// ensure начинает новую страницу, если до нижнего поля осталось меньше height
+func (l *layout) ensure(height float64) bool {
+	if l.y-height < margin {
+		l.newPage()
+		return true
+	}
+	return false
+}
+
+func (l *layout) text(x float64, font pdf.Font, size float64, s string) {
+	l.ensure(size + 4)
+	l.y -= size + 4
+	l.doc.Text(x, l.y, font, size, pdf.Truncate(font, size, s, pdf.PageWidth-margin-x))
+}
+
+func (l *layout) heading(title string) {
+	l.y -= 12
+	l.ensure(headSize + 3*lineHeight)
+	l.text(margin, pdf.HelveticaBold, headSize, title)
+	l.y -= 4
+}
+
+// table рисует таблицу; на каждой новой странице заголовок повторяется
+func (l *layout) table(columns []column, rows [][]string) {
+	l.tableHeader(columns)
+	for i, row := range rows {
+		if l.ensure(lineHeight) {
+			l.tableHeader(columns)
+		}
+		if i%2 == 1 {
+			l.doc.FillRect(margin, l.y-lineHeight, tableWidth(columns), lineHeight, 0.96)
+		}
+		l.row(columns, row, pdf.Helvetica)
+	}
+	l.doc.Line(margin, l.y, margin+tableWidth(columns), l.y, 0.5, 0.6)
+}
+
+func (l *layout) tableHeader(columns []column) {
+	l.ensure(2 * lineHeight)
+	l.doc.FillRect(margin, l.y-lineHeight, tableWidth(columns), lineHeight, 0.9)
+	titles := make([]string, len(columns))
+	for i, c := range columns {
+		titles[i] = c.title
+	}
+	l.row(columns, titles, pdf.HelveticaBold)
+}
+
+func (l *layout) row(columns []column, cells []string, font pdf.Font) {
+	const padding = 4.0
+	baseline := l.y - lineHeight + 4
+	x := margin
+	for i, c := range columns {
+		text := pdf.Truncate(font, bodySize, cells[i], c.width-2*padding)
+		tx := x + padding
+		if c.right {
+			tx = x + c.width - padding - pdf.TextWidth(font, bodySize, text)
+		}
+		l.doc.Text(tx, baseline, font, bodySize, text)
+		x += c.width
+	}
+	l.y -= lineHeight
+}
+
+func tableWidth(columns []column) float64 {
+	var width float64
+	for _, c := range columns {
+		width += c.width
+	}
+	return width
+}
+
+func userName(u *models.User, id int) string {
+	if u != nil {
+		return u.Name
+	}
+	return fmt.Sprintf("user #%d", id)
+}
@@ -0,0 +1,31 @@
+// Package token выпускает одноразовые секретные токены для ссылок и приглашений.
+// Пользователю отдается сам токен, в базе хранится только его хеш
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// size - длина токена в байтах до кодирования
+const size = 32
+
+// New возвращает новый токен и его хеш для хранения
+func New() (token, hash string, err error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, Hash(token), nil
+}
+
+// Hash возвращает хеш токена: hex(SHA-256). Токены случайные и длинные,
+// поэтому медленный хеш, как для паролей, не нужен
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,142 @@
+// Package totp реализует одноразовые пароли по времени (RFC 6238) поверх HOTP (RFC 4226).
+// Приложения-аутентификаторы по умолчанию используют SHA-1, 6 цифр и шаг 30 секунд
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Параметры кодов, которые понимают все распространенные приложения
+const (
+	Digits = 6
+	Period = 30 * time.Second
+)
+
+// secretSize - длина секрета в байтах, как рекомендует RFC 4226 (160 бит)
+const secretSize = 20
+
+// Algorithm - хеш-функция HMAC
+type Algorithm string
+
+const (
+	SHA1   Algorithm = "SHA1"
+	SHA256 Algorithm = "SHA256"
+	SHA512 Algorithm = "SHA512"
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret возвращает новый случайный секрет в base32 без выравнивания
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// DecodeSecret разбирает секрет в base32. Пробелы, дефисы и регистр не важны
+func DecodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(secret))
+	key, err := encoding.DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("invalid secret")
+	}
+	return key, nil
+}
+
+// HOTP возвращает код RFC 4226 для счетчика counter
+func HOTP(key []byte, counter uint64, digits int, algorithm Algorithm) string {
+	var newHash func() hash.Hash
+	switch algorithm {
+	case SHA256:
+		newHash = sha256.New
+	case SHA512:
+		newHash = sha512.New
+	default:
+		newHash = sha1.New
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(newHash, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Динамическое усечение: 31 бит начиная со смещения из последнего полубайта
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// Step возвращает номер временного шага для момента t
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code возвращает код для момента t с параметрами по умолчанию
+func Code(secret string, t time.Time) (string, error) {
+	key, err := DecodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return HOTP(key, uint64(Step(t)), Digits, SHA1), nil
+}
+
+// Validate проверяет код для момента t, допуская расхождение часов на skew шагов
+// в обе стороны. Возвращает шаг, которому соответствует код: чтобы код нельзя
+// было использовать повторно, вызывающий запоминает последний принятый шаг
+func Validate(secret, code string, t time.Time, skew int) (int64, bool) {
+	key, err := DecodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	current := Step(t)
+	for i := -skew; i <= skew; i++ {
+		step := current + int64(i)
+		if step < 0 {
+			continue
+		}
+		expected := HOTP(key, uint64(step), Digits, SHA1)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// URI возвращает ссылку otpauth:// для QR-кода в приложении-аутентификаторе
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	query := url.Values{
+		"secret":    {secret},
+		"issuer":    {issuer},
+		"algorithm": {string(SHA1)},
+		"digits":    {fmt.Sprint(Digits)},
+		"period":    {fmt.Sprint(int(Period / time.Second))},
+	}
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
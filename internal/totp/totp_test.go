@@ -0,0 +1,138 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Ключи из RFC 6238, приложение B: ASCII "1234567890", повторенное до длины хеша
+var (
+	keySHA1   = []byte("12345678901234567890")
+	keySHA256 = []byte("12345678901234567890123456789012")
+	keySHA512 = []byte("1234567890123456789012345678901234567890123456789012345678901234")
+)
+
+func TestHOTPRFC4226(t *testing.T) {
+	// RFC 4226, приложение D
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range want {
+		if got := HOTP(keySHA1, uint64(counter), 6, SHA1); got != code {
+			t.Errorf("HOTP(counter %d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestTOTPRFC6238(t *testing.T) {
+	tests := []struct {
+		unix                 int64
+		sha1, sha256, sha512 string
+	}{
+		{59, "94287082", "46119246", "90693936"},
+		{1111111109, "07081804", "68084774", "25091201"},
+		{1111111111, "14050471", "67062674", "99943326"},
+		{1234567890, "89005924", "91819424", "93441116"},
+		{2000000000, "69279037", "90698825", "38618901"},
+		{20000000000, "65353130", "77737706", "47863826"},
+	}
+	for _, tt := range tests {
+		step := uint64(Step(time.Unix(tt.unix, 0)))
+		for _, c := range []struct {
+			algorithm Algorithm
+			key       []byte
+			want      string
+		}{
+			{SHA1, keySHA1, tt.sha1},
+			{SHA256, keySHA256, tt.sha256},
+			{SHA512, keySHA512, tt.sha512},
+		} {
+			if got := HOTP(c.key, step, 8, c.algorithm); got != c.want {
+				t.Errorf("TOTP(%d, %s) = %s, want %s", tt.unix, c.algorithm, got, c.want)
+			}
+		}
+	}
+}
+
+func TestCode(t *testing.T) {
+	secret := encoding.EncodeToString(keySHA1)
+	code, err := Code(secret, time.Unix(59, 0))
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	// Шесть младших цифр восьмизначного кода из RFC 6238
+	if code != "287082" {
+		t.Errorf("Code = %s, want 287082", code)
+	}
+
+	// Приложения показывают секрет группами и строчными буквами
+	spaced := strings.ToLower(secret[:4] + " " + secret[4:8] + "-" + secret[8:])
+	if code2, err := Code(spaced, time.Unix(59, 0)); err != nil || code2 != code {
+		t.Errorf("Code with a formatted secret = %s, %v, want %s", code2, err, code)
+	}
+
+	if _, err := Code("not base32!", time.Now()); err == nil {
+		t.Error("Code accepted an invalid secret")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	current := Step(now)
+
+	tests := []struct {
+		offset int64 // Шаг кода относительно текущего
+		skew   int
+		ok     bool
+	}{
+		{0, 0, true},
+		{-1, 0, false},
+		{1, 0, false},
+		{-1, 1, true},
+		{1, 1, true},
+		{-2, 1, false},
+		{2, 1, false},
+		{-2, 2, true},
+	}
+	for _, tt := range tests {
+		code, _ := Code(secret, now.Add(time.Duration(tt.offset)*Period))
+		step, ok := Validate(secret, code, now, tt.skew)
+		if ok != tt.ok {
+			t.Errorf("code from step %+d with skew %d: ok = %v, want %v", tt.offset, tt.skew, ok, tt.ok)
+			continue
+		}
+		// По возвращенному шагу вызывающий отклоняет повторное использование кода
+		if ok && step != current+tt.offset {
+			t.Errorf("code from step %+d: got step %d, want %d", tt.offset, step, current+tt.offset)
+		}
+	}
+}
+
+func TestValidateRejectsMalformedCodes(t *testing.T) {
+	secret, _ := GenerateSecret()
+	now := time.Now()
+	code, _ := Code(secret, now)
+
+	if _, ok := Validate(secret, code[:3]+" "+code[3:], now, 0); !ok {
+		t.Error("Validate rejected a code with a space")
+	}
+	for _, bad := range []string{"", code[:5], code + "0", "abcdef"} {
+		if _, ok := Validate(secret, bad, now, 1); ok {
+			t.Errorf("Validate accepted %q", bad)
+		}
+	}
+	if _, ok := Validate("invalid secret!", code, now, 1); ok {
+		t.Error("Validate accepted a code for an invalid secret")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Balance", "ivan@example.com", "JBSWY3DPEHPK3PXP")
+	want := "otpauth://totp/Balance:ivan@example.com?algorithm=SHA1&digits=6&issuer=Balance&period=30&secret=JBSWY3DPEHPK3PXP"
+	if uri != want {
+		t.Errorf("URI = %s, want %s", uri, want)
+	}
+}
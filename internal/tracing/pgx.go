@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryTracer открывает клиентский спан на каждый запрос pgx. Реализует pgx.QueryTracer.
+// Параметры запроса в спан не попадают: в них бывают email и хеши токенов
+type QueryTracer struct{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := queryOperation(data.SQL)
+	ctx, _ = Start(ctx, operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(data.SQL),
+	))
+	return ctx
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil && data.Err != pgx.ErrNoRows {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+	span.End()
+}
+
+// queryOperation - первое слово запроса в верхнем регистре: SELECT, INSERT и т.д.
+func queryOperation(sql string) string {
+	sql = strings.TrimLeftFunc(sql, unicode.IsSpace)
+	if i := strings.IndexFunc(sql, unicode.IsSpace); i >= 0 {
+		sql = sql[:i]
+	}
+	if sql == "" {
+		return "QUERY"
+	}
+	return strings.ToUpper(sql)
+}
@@ -0,0 +1,72 @@
+// Package tracing пишет трассировку OpenTelemetry: спаны HTTP запросов, методов
+// сервисов и запросов к базе. Спаны отправляются по OTLP, контекст трассировки
+// принимается из заголовков traceparent и baggage входящих запросов
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation - имя, под которым сервер создает спаны
+const instrumentation = "balance"
+
+// Config - настройки трассировки
+type Config struct {
+	// Endpoint - адрес OTLP/HTTP коллектора, например http://localhost:4318.
+	// Если пустой, спаны не отправляются
+	Endpoint    string
+	ServiceName string
+	// SampleRatio - доля записываемых трасс без родителя, от 0 до 1
+	SampleRatio float64
+}
+
+// Setup настраивает глобальные TracerProvider и propagator. Возвращает функцию,
+// которая отправляет оставшиеся спаны и останавливает экспорт
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	// Остальные параметры (заголовки, таймаут) exporter читает из OTEL_EXPORTER_OTLP_*
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := NewProvider(sdktrace.NewBatchSpanProcessor(exporter), cfg)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider создает TracerProvider, который отдает спаны processor. В тестах
+// подходит sdktrace.NewSimpleSpanProcessor(tracetest.NewInMemoryExporter())
+func NewProvider(processor sdktrace.SpanProcessor, cfg Config) *sdktrace.TracerProvider {
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = instrumentation
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+}
+
+// Start начинает спан name в глобальном TracerProvider
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, opts...)
+}
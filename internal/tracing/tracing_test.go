@@ -0,0 +1,182 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"balance/internal/metrics"
+	"balance/internal/tracing"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentSpanID  = "00f067aa0ba902b7"
+)
+
+// setup подключает глобальный TracerProvider к exporter в памяти
+func setup(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	if _, err := tracing.Setup(context.Background(), tracing.Config{}); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), tracing.Config{SampleRatio: 1})
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func findSpan(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	t.Fatalf("span %q not found, got %q", name, names)
+	return tracetest.SpanStub{}
+}
+
+func attr(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// Запрос с traceparent дает серверный спан с именем маршрута, под ним спан
+// метода сервиса, а под ним - спан запроса к базе
+func TestRequestSpans(t *testing.T) {
+	exporter := setup(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/debts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "DebtService.GetDebt")
+		defer span.End()
+
+		var tracer tracing.QueryTracer
+		ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "\n\t\tselect id, amount FROM debts WHERE id = $1"})
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := metrics.NewHTTPMetrics(metrics.NewRegistry()).Middleware(
+		tracing.Middleware(metrics.Route, metrics.Routes("", mux)))
+
+	r := httptest.NewRequest("GET", "/api/v1/debts/42", nil)
+	r.Header.Set("traceparent", "00-"+parentTraceID+"-"+parentSpanID+"-01")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+
+	server := findSpan(t, spans, "GET /api/v1/debts/{id}")
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("server span kind = %v", server.SpanKind)
+	}
+	if got := server.SpanContext.TraceID().String(); got != parentTraceID {
+		t.Errorf("server span trace = %s, want %s from traceparent", got, parentTraceID)
+	}
+	if got := server.Parent.SpanID().String(); got != parentSpanID || !server.Parent.IsRemote() {
+		t.Errorf("server span parent = %s (remote %v), want remote %s", got, server.Parent.IsRemote(), parentSpanID)
+	}
+	if got := attr(server, "http.route").AsString(); got != "/api/v1/debts/{id}" {
+		t.Errorf("http.route = %q", got)
+	}
+	if got := attr(server, "http.response.status_code").AsInt64(); got != http.StatusNoContent {
+		t.Errorf("http.response.status_code = %d", got)
+	}
+
+	service := findSpan(t, spans, "DebtService.GetDebt")
+	if service.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("service span is not a child of the server span")
+	}
+
+	query := findSpan(t, spans, "SELECT")
+	if query.Parent.SpanID() != service.SpanContext.SpanID() {
+		t.Error("query span is not a child of the service span")
+	}
+	if query.SpanKind != trace.SpanKindClient {
+		t.Errorf("query span kind = %v", query.SpanKind)
+	}
+	if got := attr(query, "db.system").AsString(); got != "postgresql" {
+		t.Errorf("db.system = %q", got)
+	}
+	if got := attr(query, "db.operation.name").AsString(); got != "SELECT" {
+		t.Errorf("db.operation.name = %q", got)
+	}
+	for _, span := range spans {
+		if span.SpanContext.TraceID() != server.SpanContext.TraceID() {
+			t.Errorf("span %s is in another trace", span.Name)
+		}
+	}
+}
+
+func TestRequestSpanWithoutRoute(t *testing.T) {
+	exporter := setup(t)
+
+	handler := metrics.NewHTTPMetrics(metrics.NewRegistry()).Middleware(
+		tracing.Middleware(metrics.Route, metrics.Routes("", http.NewServeMux())))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/unknown", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	// Путь без маршрута в имя не попадает, иначе имен спанов было бы столько же, сколько путей
+	if spans[0].Name != "POST" {
+		t.Errorf("span name = %q, want POST", spans[0].Name)
+	}
+	if spans[0].Parent.IsValid() {
+		t.Error("span without traceparent has a parent")
+	}
+}
+
+func TestQuerySpanRecordsErrors(t *testing.T) {
+	exporter := setup(t)
+
+	var tracer tracing.QueryTracer
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "UPDATE debts SET amount = $1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("deadlock detected")})
+
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: pgx.ErrNoRows})
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if update := findSpan(t, spans, "UPDATE"); update.Status.Code != codes.Error || len(update.Events) == 0 {
+		t.Errorf("failed query: status %v, %d events, want an error", update.Status, len(update.Events))
+	}
+	// Пустой результат - не ошибка
+	if sel := findSpan(t, spans, "SELECT"); sel.Status.Code == codes.Error {
+		t.Errorf("query without rows has status %v", sel.Status)
+	}
+}
@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Заголовки запроса доставки вебхука
+const (
+	HeaderEvent     = "X-Balance-Event"
+	HeaderDelivery  = "X-Balance-Delivery"
+	HeaderTimestamp = "X-Balance-Timestamp"
+	HeaderSignature = "X-Balance-Signature"
+)
+
+// maxResponseBody - сколько байт ответа получателя сохраняется в журнал доставок
+const maxResponseBody = 1024
+
+// Sign возвращает подпись тела запроса: "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+// Метка времени входит в подпись, чтобы перехваченный запрос нельзя было повторить позже
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись входящего вебхука на стороне получателя.
+// tolerance ограничивает возраст метки времени, 0 - не проверять
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp header")
+	}
+
+	if tolerance > 0 {
+		age := time.Since(time.Unix(timestamp, 0))
+		if age > tolerance || age < -tolerance {
+			return fmt.Errorf("timestamp is outside the tolerance")
+		}
+	}
+
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(header.Get(HeaderSignature))) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// Request - одна доставка вебхука
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID int
+	Body       []byte
+}
+
+// Result - ответ получателя
+type Result struct {
+	StatusCode int
+	Body       string
+	Duration   time.Duration
+}
+
+// Sender отправляет подписанные вебхуки
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewSender создает отправителя. Если client равен nil, используется клиент с таймаутом
+// 10 секунд, который не подключается к внутренним адресам и не следует перенаправлениям
+func NewSender(client *http.Client) *Sender {
+	if client == nil {
+		client = newClient(denyInternal)
+	}
+	return &Sender{client: client, now: time.Now}
+}
+
+// newClient возвращает клиент, который проверяет адрес каждого подключения функцией control.
+// Адрес проверяется после разрешения имени, поэтому имя, которое указывает на
+// внутренний адрес, не обходит проверку. Перенаправления не выполняются: ответ 3xx
+// считается ответом получателя. Прокси из окружения не используется, иначе
+// проверялся бы адрес прокси
+func newClient(control func(network, address string, c syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: control}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// denyInternal запрещает подключения к адресам, которые не являются публичными
+func denyInternal(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q", address)
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("invalid address %q", address)
+	}
+	if !IsPublic(ip) {
+		return fmt.Errorf("address %s is not allowed", ip)
+	}
+	return nil
+}
+
+// reservedPrefixes - диапазоны, которые netip.Addr не относит к частным, но которые
+// не ведут в интернет: текущая сеть, CGNAT, служебные, тестовые и зарезервированные
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// IsPublic сообщает, можно ли отправлять вебхуки на адрес ip. Запрещены loopback,
+// частные сети, link-local (в том числе адреса метаданных облаков 169.254.169.254),
+// multicast и неуказанный адрес
+func IsPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Send выполняет POST запрос с подписью. Ошибка возвращается, если запрос не удался
+// или получатель ответил не 2xx; Result заполняется, если ответ был получен
+func (s *Sender) Send(ctx context.Context, req Request) (*Result, error) {
+	timestamp := s.now().Unix()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "balance-webhooks/1.0")
+	httpReq.Header.Set(HeaderEvent, req.Event)
+	httpReq.Header.Set(HeaderDelivery, strconv.Itoa(req.DeliveryID))
+	httpReq.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	httpReq.Header.Set(HeaderSignature, Sign(req.Secret, timestamp, req.Body))
+
+	start := s.now()
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	result := &Result{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		Duration:   s.now().Sub(start),
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return result, nil
+}
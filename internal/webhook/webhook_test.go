@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testSender отправляет на любые адреса: получатели в тестах слушают на 127.0.0.1
+func testSender() *Sender {
+	return NewSender(newClient(nil))
+}
+
+func TestSendSignsRequest(t *testing.T) {
+	const secret = "s3cret"
+	body := []byte(`{"delivery_id":7,"type":"debt.created"}`)
+
+	var got http.Header
+	var gotBody []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		gotBody, _ = io.ReadAll(r.Body)
+		if err := Verify(secret, r.Header, gotBody, time.Minute); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer receiver.Close()
+
+	result, err := testSender().Send(context.Background(), Request{
+		URL: receiver.URL, Secret: secret, Event: "debt.created", DeliveryID: 7, Body: body,
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if result.StatusCode != http.StatusOK || result.Body != "ok" {
+		t.Errorf("Send result = %d %q, want 200 \"ok\"", result.StatusCode, result.Body)
+	}
+
+	if string(gotBody) != string(body) {
+		t.Errorf("receiver got body %s, want %s", gotBody, body)
+	}
+	if got.Get(HeaderEvent) != "debt.created" || got.Get(HeaderDelivery) != "7" {
+		t.Errorf("event headers = %q %q", got.Get(HeaderEvent), got.Get(HeaderDelivery))
+	}
+	timestamp, err := strconv.ParseInt(got.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid timestamp header %q", got.Get(HeaderTimestamp))
+	}
+	if want := Sign(secret, timestamp, body); got.Get(HeaderSignature) != want {
+		t.Errorf("signature = %q, want %q", got.Get(HeaderSignature), want)
+	}
+	if !strings.HasPrefix(got.Get(HeaderSignature), "sha256=") {
+		t.Errorf("signature %q has no sha256= prefix", got.Get(HeaderSignature))
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	body := []byte(`{"amount":10}`)
+	now := time.Now().Unix()
+
+	header := http.Header{}
+	header.Set(HeaderTimestamp, strconv.FormatInt(now, 10))
+	header.Set(HeaderSignature, Sign("secret", now, body))
+
+	if err := Verify("secret", header, body, time.Minute); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err := Verify("other", header, body, time.Minute); err == nil {
+		t.Error("Verify accepted a signature made with another secret")
+	}
+	if err := Verify("secret", header, []byte(`{"amount":1000}`), time.Minute); err == nil {
+		t.Error("Verify accepted a changed body")
+	}
+
+	old := now - 3600
+	header.Set(HeaderTimestamp, strconv.FormatInt(old, 10))
+	header.Set(HeaderSignature, Sign("secret", old, body))
+	if err := Verify("secret", header, body, time.Minute); err == nil {
+		t.Error("Verify accepted an old timestamp")
+	}
+}
+
+func TestSendReportsNon2xx(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(strings.Repeat("x", 2*maxResponseBody)))
+	}))
+	defer receiver.Close()
+
+	result, err := testSender().Send(context.Background(), Request{URL: receiver.URL, Secret: "s", Body: []byte("{}")})
+	if err == nil {
+		t.Fatal("Send succeeded on status 500")
+	}
+	if result == nil || result.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Send result = %+v, want status 500", result)
+	}
+	if len(result.Body) != maxResponseBody {
+		t.Errorf("saved %d bytes of the response, want %d", len(result.Body), maxResponseBody)
+	}
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	var hits atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer target.Close()
+
+	receiver := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer receiver.Close()
+
+	result, err := testSender().Send(context.Background(), Request{URL: receiver.URL, Secret: "s", Body: []byte("{}")})
+	if err == nil {
+		t.Fatal("Send succeeded on a redirect")
+	}
+	if result == nil || result.StatusCode != http.StatusTemporaryRedirect {
+		t.Errorf("Send result = %+v, want status 307", result)
+	}
+	if hits.Load() != 0 {
+		t.Error("Send followed the redirect")
+	}
+}
+
+func TestSendBlocksInternalAddresses(t *testing.T) {
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer receiver.Close()
+
+	// localhost разрешается в 127.0.0.1: проверка выполняется после разрешения имени
+	urls := []string{receiver.URL, strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)}
+	for _, url := range urls {
+		result, err := NewSender(nil).Send(context.Background(), Request{URL: url, Secret: "s", Body: []byte("{}")})
+		if err == nil || result != nil {
+			t.Errorf("Send to %s: got %+v, %v, want an error", url, result, err)
+		}
+	}
+	if hits.Load() != 0 {
+		t.Error("the receiver on a loopback address was reached")
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+	for _, tt := range tests {
+		if got := IsPublic(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("IsPublic(%s) = %v, want %v", tt.addr, got, tt.public)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"balance/internal/models"
+)
+
+// Login входит по email и паролю. Если сессия выдана, клиент дальше работает с ней.
+// Если у пользователя включена двухфакторная аутентификация, TwoFactorRequired равно
+// true и вход завершается CompleteTwoFactorLogin
+func (c *Client) Login(ctx context.Context, email, password string) (*LoginResult, error) {
+	var result LoginResult
+	req := &models.LoginRequest{Email: email, Password: password}
+	if err := c.do(ctx, http.MethodPost, "/auth/login", nil, req, &result); err != nil {
+		return nil, err
+	}
+	c.useSession(result.Session)
+	return &result, nil
+}
+
+// CompleteTwoFactorLogin завершает вход кодом из приложения или кодом восстановления
+func (c *Client) CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string) (*LoginResult, error) {
+	var result LoginResult
+	req := &models.TwoFactorLoginRequest{ChallengeToken: challengeToken, Code: code}
+	if err := c.do(ctx, http.MethodPost, "/auth/login/2fa", nil, req, &result); err != nil {
+		return nil, err
+	}
+	c.useSession(result.Session)
+	return &result, nil
+}
+
+// Me возвращает пользователя текущей сессии или API ключа
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodGet, "/auth/me", nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Logout завершает текущую сессию и убирает токен из клиента
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.do(ctx, http.MethodPost, "/auth/logout", nil, nil, nil); err != nil {
+		return err
+	}
+	c.SetToken("")
+	return nil
+}
+
+func (c *Client) useSession(session *Session) {
+	if session != nil {
+		c.SetToken(session.Token)
+	}
+}
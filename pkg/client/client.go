@@ -0,0 +1,223 @@
+// Package client - клиент HTTP API сервера balance. Клиент передает токен сессии
+// или API ключ, повторяет запросы, которые можно безопасно повторить, и возвращает
+// ошибки API как *Error
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// apiPrefix - префикс маршрутов API относительно адреса сервера
+	apiPrefix = "/api/v1"
+	// defaultPageSize - сколько записей запрашивают итераторы за раз
+	defaultPageSize = 100
+	// maxErrorBody - сколько байт ответа с ошибкой читается, если это не JSON
+	maxErrorBody = 4096
+)
+
+// Client обращается к API одного сервера. Методы можно вызывать из нескольких горутин
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	userAgent  string
+	retry      RetryPolicy
+	pageSize   int
+
+	mu    sync.RWMutex
+	token string
+}
+
+// Option настраивает клиента
+type Option func(*Client)
+
+// WithHTTPClient задает HTTP клиент, например с таймаутом или своим транспортом
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithToken задает токен сессии или персональный API ключ (bal_...)
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithRetry задает политику повторов. RetryPolicy{MaxAttempts: 1} отключает повторы
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// WithPageSize задает, сколько записей итераторы запрашивают за раз (от 1 до 1000)
+func WithPageSize(size int) Option {
+	return func(c *Client) { c.pageSize = size }
+}
+
+// WithUserAgent задает заголовок User-Agent
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// New создает клиента для сервера по адресу baseURL, например https://balance.example.com
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL: scheme must be http or https")
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		userAgent:  "balance-go-client",
+		retry:      DefaultRetryPolicy,
+		pageSize:   defaultPageSize,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.pageSize < 1 || c.pageSize > 1000 {
+		return nil, fmt.Errorf("page size must be between 1 and 1000")
+	}
+
+	return c, nil
+}
+
+// SetToken заменяет токен, с которым отправляются запросы. Пустой токен - запросы без авторизации
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// Token возвращает текущий токен
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// response - ответ API в формате utils.Response. Data декодируется позже в тип метода
+type response struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// do отправляет запрос к маршруту API path и декодирует поле data ответа в out.
+// body кодируется в JSON один раз и отправляется заново при каждой попытке
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	u := *c.baseURL
+	u.Path += apiPrefix + path
+	u.RawQuery = query.Encode()
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", c.userAgent)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token := c.Token(); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		var retryAfter time.Duration
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if attempt >= c.retry.MaxAttempts || !retryableError(method, err) {
+				return fmt.Errorf("%s %s: %w", method, path, err)
+			}
+		} else {
+			err = decodeResponse(resp, out)
+			if err == nil {
+				return nil
+			}
+			apiErr, ok := err.(*Error)
+			if !ok || attempt >= c.retry.MaxAttempts || !retryableStatus(method, apiErr.StatusCode) {
+				return err
+			}
+			retryAfter = apiErr.RetryAfter
+		}
+
+		timer := time.NewTimer(c.retry.delay(attempt, retryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// decodeResponse читает ответ. Успешный ответ декодируется в out, ответ с ошибкой
+// возвращается как *Error
+func decodeResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return decodeError(resp)
+	}
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if out != nil && len(r.Data) > 0 {
+		if err := json.Unmarshal(r.Data, out); err != nil {
+			return fmt.Errorf("failed to decode response data: %w", err)
+		}
+	}
+	return nil
+}
+
+func decodeError(resp *http.Response) *Error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var r response
+	if err := json.Unmarshal(raw, &r); err == nil && r.Error != "" {
+		apiErr.Message = r.Error
+		apiErr.Data = r.Data
+		return apiErr
+	}
+
+	// Ответ не в формате API, например от прокси
+	if len(raw) > maxErrorBody {
+		raw = raw[:maxErrorBody]
+	}
+	apiErr.Message = strings.TrimSpace(string(raw))
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
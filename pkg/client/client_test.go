@@ -0,0 +1,230 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"balance/api"
+	"balance/internal/config"
+	"balance/internal/database/dbtest"
+	"balance/internal/health"
+	"balance/internal/logging"
+	"balance/internal/metrics"
+	"balance/pkg/client"
+)
+
+const password = "correct horse battery"
+
+// newServer запускает сервер API на тестовой базе. configure меняет настройки до запуска
+func newServer(t *testing.T, configure func(*config.Config)) *httptest.Server {
+	t.Helper()
+
+	db := dbtest.Open(t)
+	cfg := config.New()
+	cfg.SMTP.Host = ""
+	if configure != nil {
+		configure(cfg)
+	}
+
+	srv := httptest.NewServer(api.NewRouter(db, cfg, logging.New(io.Discard, "text", "error"),
+		metrics.NewRegistry(), health.New(time.Second)))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newClient(t *testing.T, srv *httptest.Server, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	opts = append([]client.Option{client.WithHTTPClient(srv.Client())}, opts...)
+	c, err := client.New(srv.URL, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+// register создает пользователя с паролем и возвращает клиента, вошедшего от его имени
+func register(t *testing.T, srv *httptest.Server, name string) (*client.Client, *client.User) {
+	t.Helper()
+	ctx := context.Background()
+
+	c := newClient(t, srv)
+	email := dbtest.Email(name)
+	user, err := c.CreateUser(ctx, &client.CreateUserRequest{Name: name, Email: email, Password: password})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := c.Login(ctx, email, password); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return c, user
+}
+
+func TestClientAuth(t *testing.T) {
+	srv := newServer(t, nil)
+	ctx := context.Background()
+
+	c, user := register(t, srv, "alice")
+	if c.Token() == "" {
+		t.Fatal("Login did not store the session token")
+	}
+
+	me, err := c.Me(ctx)
+	if err != nil {
+		t.Fatalf("Me: %v", err)
+	}
+	if me.ID != user.ID {
+		t.Errorf("Me returned user %d, want %d", me.ID, user.ID)
+	}
+
+	if err := c.Logout(ctx); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if c.Token() != "" {
+		t.Error("Logout did not clear the token")
+	}
+	if _, err := c.Me(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("Me after logout: got %v, want ErrUnauthorized", err)
+	}
+
+	anon := newClient(t, srv)
+	if _, err := anon.Login(ctx, user.Email, "wrong password"); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("Login with a wrong password: got %v, want ErrUnauthorized", err)
+	}
+
+	stale := newClient(t, srv, client.WithToken("bal_0000000000000000"))
+	if _, err := stale.Me(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("Me with an unknown API key: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestClientPagination(t *testing.T) {
+	srv := newServer(t, nil)
+	ctx := context.Background()
+
+	alice, a := register(t, srv, "alice")
+	_, b := register(t, srv, "bob")
+
+	group, err := alice.CreateGroup(ctx, &client.CreateGroupRequest{Name: "Trip", CreatedBy: a.ID})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if _, err := alice.AddMember(ctx, group.ID, b.ID); err != nil {
+		t.Fatalf("AddMember: %v", err)
+	}
+
+	created := make(map[int]bool)
+	for range 5 {
+		debt, err := alice.CreateDebt(ctx, &client.CreateDebtRequest{
+			GroupID: group.ID, FromUserID: a.ID, ToUserID: b.ID, Amount: 10, CreatedBy: a.ID,
+		})
+		if err != nil {
+			t.Fatalf("CreateDebt: %v", err)
+		}
+		created[debt.ID] = true
+	}
+
+	paged := newClient(t, srv, client.WithToken(alice.Token()), client.WithPageSize(2))
+	seen := make(map[int]bool)
+	for debt, err := range paged.Debts(ctx, client.DebtFilter{GroupID: group.ID}) {
+		if err != nil {
+			t.Fatalf("Debts: %v", err)
+		}
+		if seen[debt.ID] {
+			t.Errorf("debt %d returned twice", debt.ID)
+		}
+		seen[debt.ID] = true
+	}
+	if len(seen) != len(created) {
+		t.Fatalf("Debts returned %d debts, want %d", len(seen), len(created))
+	}
+	for id := range created {
+		if !seen[id] {
+			t.Errorf("debt %d is missing", id)
+		}
+	}
+
+	page, err := paged.ListDebts(ctx, client.DebtFilter{GroupID: group.ID}, client.Page{Limit: 2, Offset: 4})
+	if err != nil {
+		t.Fatalf("ListDebts: %v", err)
+	}
+	if len(page) != 1 {
+		t.Errorf("last page has %d debts, want 1", len(page))
+	}
+}
+
+func TestClientRetriesAfterRateLimit(t *testing.T) {
+	srv := newServer(t, func(cfg *config.Config) {
+		cfg.RateLimit.Store = "memory"
+		cfg.RateLimit.Read = config.RateLimit{Requests: 1, Period: time.Second}
+	})
+	ctx := context.Background()
+
+	c, user := register(t, srv, "carol")
+	if _, err := c.GetUser(ctx, user.ID); err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+
+	// Без повторов второй запрос в той же секунде получает 429 с Retry-After
+	once := newClient(t, srv, client.WithToken(c.Token()), client.WithRetry(client.RetryPolicy{MaxAttempts: 1}))
+	_, err := once.GetUser(ctx, user.ID)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("GetUser: got %v, want *client.Error", err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests || !errors.Is(err, client.ErrRateLimited) {
+		t.Errorf("GetUser: got status %d, want 429", apiErr.StatusCode)
+	}
+	if apiErr.RetryAfter <= 0 {
+		t.Errorf("RetryAfter = %v, want > 0", apiErr.RetryAfter)
+	}
+
+	// С повторами клиент ждет Retry-After и получает ответ
+	start := time.Now()
+	if _, err := c.GetUser(ctx, user.ID); err != nil {
+		t.Fatalf("GetUser with retries: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("GetUser returned after %v, the client did not wait for Retry-After", elapsed)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	srv := newServer(t, nil)
+	ctx := context.Background()
+
+	c, user := register(t, srv, "dave")
+
+	_, err := c.GetUser(ctx, 1<<30)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("GetUser: got %v, want *client.Error", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("GetUser: got status %d, want 404", apiErr.StatusCode)
+	}
+	if apiErr.Message != "user not found" {
+		t.Errorf("Message = %q, want %q", apiErr.Message, "user not found")
+	}
+	if apiErr.RequestID == "" {
+		t.Error("RequestID is empty")
+	}
+
+	_, err = c.CreateUser(ctx, &client.CreateUserRequest{Name: "Dave", Email: user.Email, Password: password})
+	if !errors.As(err, &apiErr) || apiErr.Message == "" {
+		t.Fatalf("CreateUser with a taken email: got %v, want *client.Error", err)
+	}
+	if errors.Is(err, client.ErrNotFound) {
+		t.Errorf("CreateUser error %v matches ErrNotFound", err)
+	}
+
+	_, err = c.CreateGroup(ctx, &client.CreateGroupRequest{Name: "x", CreatedBy: user.ID})
+	if !errors.Is(err, client.ErrBadRequest) {
+		t.Errorf("CreateGroup with a short name: got %v, want ErrBadRequest", err)
+	}
+}
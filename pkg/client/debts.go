@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CreateDebt создает долг. Без GroupID долг прямой, стороны должны быть друзьями
+func (c *Client) CreateDebt(ctx context.Context, req *CreateDebtRequest) (*Debt, error) {
+	var debt Debt
+	if err := c.do(ctx, http.MethodPost, "/debts", nil, req, &debt); err != nil {
+		return nil, err
+	}
+	return &debt, nil
+}
+
+// GetDebt возвращает долг
+func (c *Client) GetDebt(ctx context.Context, id int) (*Debt, error) {
+	var debt Debt
+	if err := c.do(ctx, http.MethodGet, "/debts/"+strconv.Itoa(id), nil, nil, &debt); err != nil {
+		return nil, err
+	}
+	return &debt, nil
+}
+
+// ListDebts возвращает страницу долгов по фильтру, новые первыми
+func (c *Client) ListDebts(ctx context.Context, filter DebtFilter, page Page) ([]*Debt, error) {
+	query := url.Values{}
+	if filter.GroupID != 0 {
+		query.Set("group_id", strconv.Itoa(filter.GroupID))
+	}
+	if filter.UserID != 0 {
+		query.Set("user_id", strconv.Itoa(filter.UserID))
+	}
+	if filter.Status != "" {
+		query.Set("status", filter.Status)
+	}
+	setPage(query, page)
+
+	var debts []*Debt
+	if err := c.do(ctx, http.MethodGet, "/debts", query, nil, &debts); err != nil {
+		return nil, err
+	}
+	return debts, nil
+}
+
+// Debts обходит долги по фильтру страницами
+func (c *Client) Debts(ctx context.Context, filter DebtFilter) iter.Seq2[*Debt, error] {
+	return paginate(ctx, c.pageSize, func(ctx context.Context, page Page) ([]*Debt, error) {
+		return c.ListDebts(ctx, filter, page)
+	})
+}
+
+// OverdueDebts возвращает просроченные долги пользователя
+func (c *Client) OverdueDebts(ctx context.Context, userID int) ([]*Debt, error) {
+	var debts []*Debt
+	path := "/users/" + strconv.Itoa(userID) + "/debts/overdue"
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &debts); err != nil {
+		return nil, err
+	}
+	return debts, nil
+}
+
+// ApplyDebtAction переводит долг в другой статус: DebtActionConfirm, DebtActionSettle и т.д.
+func (c *Client) ApplyDebtAction(ctx context.Context, id int, action string, req *DebtActionRequest) (*Debt, error) {
+	var debt Debt
+	path := "/debts/" + strconv.Itoa(id) + "/" + url.PathEscape(action)
+	if err := c.do(ctx, http.MethodPost, path, nil, req, &debt); err != nil {
+		return nil, err
+	}
+	return &debt, nil
+}
+
+// Balances возвращает балансы пользователя со всеми, с кем у него есть активные долги
+func (c *Client) Balances(ctx context.Context, userID int) ([]*UserBalance, error) {
+	var balances []*UserBalance
+	path := "/users/" + strconv.Itoa(userID) + "/balances"
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &balances); err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// Balance возвращает баланс пользователя с другим пользователем. Положительный
+// баланс - пользователю должны
+func (c *Client) Balance(ctx context.Context, userID, otherID int) (*UserBalance, error) {
+	var balance UserBalance
+	path := "/users/" + strconv.Itoa(userID) + "/balances/" + strconv.Itoa(otherID)
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &balance); err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Ошибки для сравнения через errors.Is с *Error по коду ответа
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:      ErrBadRequest,
+	http.StatusUnauthorized:    ErrUnauthorized,
+	http.StatusForbidden:       ErrForbidden,
+	http.StatusNotFound:        ErrNotFound,
+	http.StatusConflict:        ErrConflict,
+	http.StatusTooManyRequests: ErrRateLimited,
+}
+
+// Error - ответ API с ошибкой: {"status": "error", "error": "..."}
+type Error struct {
+	StatusCode int
+	Message    string          // Поле error ответа
+	Data       json.RawMessage // Подробности, например отчет импорта с ошибками по строкам
+	RequestID  string          // X-Request-ID ответа, по нему запрос ищется в логах сервера
+	RetryAfter time.Duration   // Через сколько можно повторить запрос, если сервер это сообщил
+}
+
+func (e *Error) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("balance API error %d: %s (request %s)", e.StatusCode, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("balance API error %d: %s", e.StatusCode, e.Message)
+}
+
+// Is сопоставляет ошибку с ErrNotFound, ErrConflict и другими по коду ответа
+func (e *Error) Is(target error) bool {
+	return statusErrors[e.StatusCode] == target
+}
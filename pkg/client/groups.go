@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"balance/internal/models"
+)
+
+// CreateGroup создает группу
+func (c *Client) CreateGroup(ctx context.Context, req *CreateGroupRequest) (*Group, error) {
+	var group Group
+	if err := c.do(ctx, http.MethodPost, "/groups", nil, req, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetGroup возвращает группу с участниками
+func (c *Client) GetGroup(ctx context.Context, id int) (*Group, error) {
+	var group Group
+	if err := c.do(ctx, http.MethodGet, "/groups/"+strconv.Itoa(id), nil, nil, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ListGroups возвращает страницу всех групп или, если userID не ноль, групп пользователя
+func (c *Client) ListGroups(ctx context.Context, userID int, page Page) ([]*Group, error) {
+	query := url.Values{}
+	if userID != 0 {
+		query.Set("user_id", strconv.Itoa(userID))
+	}
+	setPage(query, page)
+
+	var groups []*Group
+	if err := c.do(ctx, http.MethodGet, "/groups", query, nil, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// Groups обходит группы страницами. Если userID не ноль - только группы пользователя
+func (c *Client) Groups(ctx context.Context, userID int) iter.Seq2[*Group, error] {
+	return paginate(ctx, c.pageSize, func(ctx context.Context, page Page) ([]*Group, error) {
+		return c.ListGroups(ctx, userID, page)
+	})
+}
+
+// AddMember добавляет пользователя в группу
+func (c *Client) AddMember(ctx context.Context, groupID, userID int) (*GroupMember, error) {
+	var member GroupMember
+	path := "/groups/" + strconv.Itoa(groupID) + "/members"
+	if err := c.do(ctx, http.MethodPost, path, nil, &models.AddMemberRequest{UserID: userID}, &member); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// RemoveMember удаляет пользователя из группы
+func (c *Client) RemoveMember(ctx context.Context, groupID, userID int) error {
+	path := "/groups/" + strconv.Itoa(groupID) + "/members/" + strconv.Itoa(userID)
+	return c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
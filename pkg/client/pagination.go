@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/url"
+	"strconv"
+)
+
+// paginate обходит список страницами по size записей, пока не придет неполная страница.
+// Ошибка передается последним элементом. Записи, созданные во время обхода, сдвигают
+// страницы, поэтому запись может встретиться дважды
+func paginate[T any](ctx context.Context, size int, list func(ctx context.Context, page Page) ([]T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		page := Page{Limit: size}
+		for {
+			items, err := list(ctx, page)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(items) < size {
+				return
+			}
+			page.Offset += len(items)
+		}
+	}
+}
+
+// setPage добавляет страницу в параметры запроса. Нулевая страница - весь список
+func setPage(query url.Values, page Page) {
+	if page.Limit > 0 {
+		query.Set("limit", strconv.Itoa(page.Limit))
+	}
+	if page.Offset > 0 {
+		query.Set("offset", strconv.Itoa(page.Offset))
+	}
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy задает повторы запросов с экспоненциальной задержкой
+type RetryPolicy struct {
+	MaxAttempts int           // Всего попыток, включая первую
+	MinDelay    time.Duration // Задержка перед первым повтором
+	MaxDelay    time.Duration // Наибольшая задержка
+}
+
+// DefaultRetryPolicy - три попытки с задержкой от 200 мс до 5 секунд
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinDelay:    200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// delay возвращает задержку перед повтором после попытки attempt. Если сервер
+// прислал Retry-After, ждем столько, сколько он просит
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	d := p.MinDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	// Случайная часть разводит повторы клиентов, которые получили ошибку одновременно
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryableStatus сообщает, можно ли повторить запрос после ответа с кодом status.
+// 429 сервер отдает до обработки запроса, поэтому повторяется любой метод.
+// Ошибки прокси повторяются только для идемпотентных методов: POST мог быть выполнен
+func retryableStatus(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent(method)
+	}
+	return false
+}
+
+// retryableError сообщает, можно ли повторить запрос после сетевой ошибки. POST
+// повторяется, только если соединение не удалось открыть и сервер запрос не получил
+func retryableError(method string, err error) bool {
+	if idempotent(method) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter разбирает Retry-After в секундах или в виде даты
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,43 @@
+package client
+
+import "balance/internal/models"
+
+// Модели API. Это те же типы, которые кодирует сервер, поэтому клиент не расходится с ним
+type (
+	User              = models.User
+	CreateUserRequest = models.CreateUserRequest
+	UpdateUserRequest = models.UpdateUserRequest
+
+	Session     = models.Session
+	LoginResult = models.LoginResult
+
+	Group              = models.Group
+	GroupMember        = models.GroupMember
+	CreateGroupRequest = models.CreateGroupRequest
+
+	Debt              = models.Debt
+	CreateDebtRequest = models.CreateDebtRequest
+	DebtActionRequest = models.DebtActionRequest
+
+	UserBalance  = models.UserBalance
+	GroupBalance = models.GroupBalance
+
+	// Page - страница списка: Limit записей после первых Offset
+	Page = models.Page
+)
+
+// Переходы статуса долга для ApplyDebtAction
+const (
+	DebtActionConfirm = "confirm"
+	DebtActionReject  = "reject"
+	DebtActionDispute = "dispute"
+	DebtActionSettle  = "settle"
+	DebtActionCancel  = "cancel"
+)
+
+// DebtFilter - необязательные фильтры списка долгов
+type DebtFilter struct {
+	GroupID int
+	UserID  int    // Долги, где пользователь должник или кредитор
+	Status  string // proposed, active, settled, cancelled или disputed
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CreateUser создает пользователя
+func (c *Client) CreateUser(ctx context.Context, req *CreateUserRequest) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodPost, "/users", nil, req, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUser возвращает пользователя по ID
+func (c *Client) GetUser(ctx context.Context, id int) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodGet, "/users/", userQuery(id), nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateUser изменяет пользователя
+func (c *Client) UpdateUser(ctx context.Context, id int, req *UpdateUserRequest) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodPut, "/users/", userQuery(id), req, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// DeleteUser удаляет пользователя
+func (c *Client) DeleteUser(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, "/users/", userQuery(id), nil, nil)
+}
+
+// ListUsers возвращает страницу пользователей, новые первыми
+func (c *Client) ListUsers(ctx context.Context, page Page) ([]*User, error) {
+	query := url.Values{}
+	setPage(query, page)
+
+	var users []*User
+	if err := c.do(ctx, http.MethodGet, "/users", query, nil, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Users обходит всех пользователей страницами
+func (c *Client) Users(ctx context.Context) iter.Seq2[*User, error] {
+	return paginate(ctx, c.pageSize, c.ListUsers)
+}
+
+// Маршруты /users/ принимают ID в параметре id
+func userQuery(id int) url.Values {
+	return url.Values{"id": {strconv.Itoa(id)}}
+}